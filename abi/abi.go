@@ -3,33 +3,56 @@ package abi
 import (
 	"encoding/json"
 	"fmt"
-	"math/big"
-	"reflect"
-	"strconv"
 	"strings"
 
-	"github.com/bytejedi/tron-sdk-go/keystore"
 	ethabi "github.com/ethereum/go-ethereum/accounts/abi"
-	ethcmn "github.com/ethereum/go-ethereum/common"
 	"golang.org/x/crypto/sha3"
 )
 
 // Param list
 type Param map[string]interface{}
 
-// loadFromJSON string into ABI data
-func loadFromJSON(jString string) ([]Param, error) {
-	if len(jString) == 0 {
+// loadFromJSON decodes the params JSON, keeping numbers as json.Number
+func loadFromJSON(jString string) (interface{}, error) {
+	if len(strings.TrimSpace(jString)) == 0 {
 		return nil, nil
 	}
-	var data []Param
-	err := json.Unmarshal([]byte(jString), &data)
-	if err != nil {
+	var data interface{}
+	dec := json.NewDecoder(strings.NewReader(jString))
+	// Keep numbers exact, large integers don't fit in a float64
+	dec.UseNumber()
+	if err := dec.Decode(&data); err != nil {
 		return nil, err
 	}
 	return data, nil
 }
 
+// toTypedParams returns the list as []Param if it is in the {type: value}
+// format, where every element is an object with a single abi type key.
+func toTypedParams(method *ethabi.Method, list []interface{}) ([]Param, bool) {
+	if len(list) == 0 {
+		return nil, false
+	}
+	params := make([]Param, 0, len(list))
+	for i, item := range list {
+		p, ok := item.(map[string]interface{})
+		if !ok || len(p) != 1 {
+			return nil, false
+		}
+		for k := range p {
+			k = normalizeType(k)
+			if i < len(method.Inputs) && method.Inputs[i].Type.String() == k {
+				continue
+			}
+			if _, err := ethabi.NewType(k, "", nil); err != nil {
+				return nil, false
+			}
+		}
+		params = append(params, Param(p))
+	}
+	return params, true
+}
+
 // Signature of a method
 func Signature(method string) []byte {
 	// hash method
@@ -39,169 +62,129 @@ func Signature(method string) []byte {
 	return b[:4]
 }
 
-func convetToAddress(v interface{}) (ethcmn.Address, error) {
-	switch v.(type) {
-	case string:
-		addr, err := keystore.Base58ToAddress(v.(string))
-		if err != nil {
-			return ethcmn.Address{}, fmt.Errorf("invalid address %s: %+v", v.(string), err)
-		}
-		return ethcmn.BytesToAddress(addr.Bytes()[len(addr.Bytes())-20:]), nil
-	}
-	return ethcmn.Address{}, fmt.Errorf("invalid address %v", v)
-}
-
-func convertToInt(ty ethabi.Type, v interface{}) interface{} {
-	if ty.T == ethabi.IntTy && ty.Size <= 64 {
-		tmp, _ := strconv.ParseInt(v.(string), 10, ty.Size)
-		switch ty.Size {
-		case 8:
-			v = int8(tmp)
-		case 16:
-			v = int16(tmp)
-		case 32:
-			v = int32(tmp)
-		case 64:
-			v = tmp
-		}
-	} else if ty.T == ethabi.UintTy && ty.Size <= 64 {
-		tmp, _ := strconv.ParseUint(v.(string), 10, ty.Size)
-		switch ty.Size {
-		case 8:
-			v = uint8(tmp)
-		case 16:
-			v = uint16(tmp)
-		case 32:
-			v = uint32(tmp)
-		case 64:
-			v = tmp
-		}
-	} else {
-		v, _ = new(big.Int).SetString(v.(string), 10)
-	}
-	return v
-}
-
 // getPaddedParam return padded params bytes
 func getPaddedParam(method *ethabi.Method, param []Param) ([]byte, error) {
-	values := make([]interface{}, 0)
+	values := make([]interface{}, 0, len(param))
 
-	for _, p := range param {
+	for i, p := range param {
 		if len(p) != 1 {
-			return nil, fmt.Errorf("invalid param %+v", p)
+			return nil, fmt.Errorf("invalid param %d %+v: want a single {type: value} pair", i, p)
 		}
 		for k, v := range p {
-			if k == "uint" {
-				k = "uint256"
-			} else if strings.HasPrefix(k, "uint[") {
-				k = strings.Replace(k, "uint[", "uint256[", 1)
-			}
-			ty, err := ethabi.NewType(k, "", nil)
-			if err != nil {
-				return nil, fmt.Errorf("invalid param %+v: %+v", p, err)
-			}
-
-			if ty.T == ethabi.SliceTy || ty.T == ethabi.ArrayTy {
-				if ty.Elem.T == ethabi.AddressTy {
-					tmp := v.([]interface{})
-					v = make([]ethcmn.Address, 0)
-					for i := range tmp {
-						addr, err := convetToAddress(tmp[i])
-						if err != nil {
-							return nil, err
-						}
-						v = append(v.([]ethcmn.Address), addr)
-					}
-				}
-
-				if (ty.Elem.T == ethabi.IntTy || ty.Elem.T == ethabi.UintTy) && reflect.TypeOf(v).Elem().Kind() == reflect.Interface {
-					if ty.Elem.Size > 64 {
-						tmp := make([]*big.Int, 0)
-						for _, i := range v.([]interface{}) {
-							if s, ok := i.(string); ok {
-								value, _ := new(big.Int).SetString(s, 10)
-								tmp = append(tmp, value)
-							} else {
-								return nil, fmt.Errorf("abi: cannot use %T as type string as argument", i)
-							}
-						}
-						v = tmp
-					} else {
-						tmpI := make([]interface{}, 0)
-						for _, i := range v.([]interface{}) {
-							if s, ok := i.(string); ok {
-								value, err := strconv.ParseUint(s, 10, ty.Elem.Size)
-								if err != nil {
-									return nil, err
-								}
-								tmpI = append(tmpI, value)
-							} else {
-								return nil, fmt.Errorf("abi: cannot use %T as type string as argument", i)
-							}
-						}
-						switch ty.Elem.Size {
-						case 8:
-							tmp := make([]uint8, len(tmpI))
-							for i, sv := range tmpI {
-								tmp[i] = uint8(sv.(uint64))
-							}
-							v = tmp
-						case 16:
-							tmp := make([]uint16, len(tmpI))
-							for i, sv := range tmpI {
-								tmp[i] = uint16(sv.(uint64))
-							}
-							v = tmp
-						case 32:
-							tmp := make([]uint32, len(tmpI))
-							for i, sv := range tmpI {
-								tmp[i] = uint32(sv.(uint64))
-							}
-							v = tmp
-						case 64:
-							tmp := make([]uint64, len(tmpI))
-							for i, sv := range tmpI {
-								tmp[i] = sv.(uint64)
-							}
-							v = tmp
-						}
-					}
+			k = normalizeType(k)
+
+			var ty ethabi.Type
+			if i < len(method.Inputs) && method.Inputs[i].Type.String() == k {
+				// Reuse the method's type so tuple components are known
+				ty = method.Inputs[i].Type
+			} else {
+				var err error
+				if ty, err = ethabi.NewType(k, "", nil); err != nil {
+					return nil, fmt.Errorf("invalid param %d (%s): %v", i, k, err)
 				}
 			}
 
-			if ty.T == ethabi.AddressTy {
-				if v, err = convetToAddress(v); err != nil {
-					return nil, err
-				}
+			value, err := convertToType(ty, v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid param %d (%s): %v", i, k, err)
 			}
+			values = append(values, value)
+		}
+	}
 
-			if (ty.T == ethabi.IntTy || ty.T == ethabi.UintTy) && reflect.TypeOf(v).Kind() == reflect.String {
-				v = convertToInt(ty, v)
-			}
+	// convert params to bytes
+	return method.Inputs.PackValues(values)
+}
 
-			values = append(values, v)
+// getPositionalParam return padded params bytes from a list of values
+// given in the order of the method inputs
+func getPositionalParam(method *ethabi.Method, list []interface{}) ([]byte, error) {
+	if len(list) != len(method.Inputs) {
+		return nil, fmt.Errorf("argument count mismatch: got %d for %d", len(list), len(method.Inputs))
+	}
+	values := make([]interface{}, len(list))
+	for i, input := range method.Inputs {
+		value, err := convertToType(input.Type, list[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid param %d (%s): %v", i, input.Type.String(), err)
 		}
+		values[i] = value
 	}
+	return method.Inputs.PackValues(values)
+}
 
-	// convert params to bytes
+// getNamedParam return padded params bytes from values keyed by input name.
+// A leading underscore of the solidity name may be omitted, so "_to" can be
+// given as "to".
+func getNamedParam(method *ethabi.Method, named map[string]interface{}) ([]byte, error) {
+	values := make([]interface{}, len(method.Inputs))
+	used := make(map[string]bool, len(named))
+	for i, input := range method.Inputs {
+		if input.Name == "" {
+			return nil, fmt.Errorf("param %d (%s) has no name, use positional params", i, input.Type.String())
+		}
+		key := input.Name
+		v, ok := named[key]
+		if !ok {
+			key = strings.TrimLeft(input.Name, "_")
+			v, ok = named[key]
+		}
+		if !ok {
+			return nil, fmt.Errorf("missing param %d (%s %s)", i, input.Type.String(), input.Name)
+		}
+		used[key] = true
+
+		value, err := convertToType(input.Type, v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid param %d (%s %s): %v", i, input.Type.String(), input.Name, err)
+		}
+		values[i] = value
+	}
+	for k := range named {
+		if !used[k] {
+			return nil, fmt.Errorf("unknown param %q for method %s", k, method.Sig)
+		}
+	}
 	return method.Inputs.PackValues(values)
 }
 
-// Pack data into bytes
+// Pack data into bytes. The params JSON can be given in three forms:
+//
+//	[{"address":"T..."},{"uint256":"1000"}]  typed list, one {type: value} per input
+//	["T...","1000"]                          plain list of values in input order
+//	{"to":"T...","amount":"1000"}            values keyed by input name
+//
+// Only the typed list carries its own types, the other forms take them from
+// method.Inputs.
 func Pack(method *ethabi.Method, paramsJson string) ([]byte, error) {
-	params, err := loadFromJSON(paramsJson)
+	data, err := loadFromJSON(paramsJson)
 	if err != nil {
 		return nil, err
 	}
 
-	bz, err := getPaddedParam(method, params)
+	var bz []byte
+	switch params := data.(type) {
+	case nil:
+		bz, err = getPaddedParam(method, nil)
+	case map[string]interface{}:
+		bz, err = getNamedParam(method, params)
+	case []interface{}:
+		if typed, ok := toTypedParams(method, params); ok {
+			bz, err = getPaddedParam(method, typed)
+		} else {
+			bz, err = getPositionalParam(method, params)
+		}
+	default:
+		return nil, fmt.Errorf("invalid params %s: want a JSON list or object", paramsJson)
+	}
 	if err != nil {
 		return nil, err
 	}
 	return append(method.ID, bz...), nil
 }
 
-// DecodeOutputs unpack outputs data
+// DecodeOutputs unpack outputs data. If the data cannot be unpacked the raw
+// outputs are returned as a string, use DecodeOutputsMap to get the error.
 func DecodeOutputs(method *ethabi.Method, outputs []byte) (interface{}, error) {
 	res, err := method.Outputs.UnpackValues(outputs)
 	if err != nil {
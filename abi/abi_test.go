@@ -1,11 +1,17 @@
 package abi
 
 import (
+	"math/big"
 	"strings"
 	"testing"
 
+	"github.com/bytejedi/tron-sdk-go/keystore"
+	"github.com/bytejedi/tron-sdk-go/proto/api"
+	"github.com/bytejedi/tron-sdk-go/proto/core"
+	"github.com/bytejedi/tron-sdk-go/proto/core/contract"
 	"github.com/bytejedi/tron-sdk-go/utils"
 	ethabi "github.com/ethereum/go-ethereum/accounts/abi"
+	ethcmn "github.com/ethereum/go-ethereum/common"
 )
 
 var abiJson = `
@@ -1242,3 +1248,425 @@ func TestPack(t *testing.T) {
 		t.Error("getPaddedParam failed")
 	}
 }
+
+func TestPackStrictTypes(t *testing.T) {
+	newArg := func(ty string) ethabi.Argument {
+		typ, err := ethabi.NewType(ty, "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ethabi.Argument{Type: typ}
+	}
+	method := ethabi.NewMethod("f", "f", ethabi.Function, "nonpayable", false, false,
+		ethabi.Arguments{newArg("uint24"), newArg("bool"), newArg("bytes4"), newArg("int40")}, nil)
+
+	bz, err := Pack(&method, `[{"uint24":"0x10"},{"bool":"true"},{"bytes4":"0xdeadbeef"},{"int40":-1}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := utils.Bytes2Hex(method.ID) +
+		"0000000000000000000000000000000000000000000000000000000000000010" +
+		"0000000000000000000000000000000000000000000000000000000000000001" +
+		"deadbeef00000000000000000000000000000000000000000000000000000000" +
+		"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"
+	if s := utils.Bytes2Hex(bz); s != exp {
+		t.Errorf("unexpected encoding\nhave %s\nwant %s", s, exp)
+	}
+
+	bad := []string{
+		`[{"uint24":"16777216"},{"bool":true},{"bytes4":"0xdeadbeef"},{"int40":"1"}]`,
+		`[{"uint24":"1"},{"bool":"yes"},{"bytes4":"0xdeadbeef"},{"int40":"1"}]`,
+		`[{"uint24":"1"},{"bool":true},{"bytes4":"0xdead"},{"int40":"1"}]`,
+		`[{"uint24":"abc"},{"bool":true},{"bytes4":"0xdeadbeef"},{"int40":"1"}]`,
+		`[{"uint24":"-1"},{"bool":true},{"bytes4":"0xdeadbeef"},{"int40":"1"}]`,
+	}
+	for i, params := range bad {
+		if _, err := Pack(&method, params); err == nil {
+			t.Errorf("case %d: expected error for %s", i, params)
+		}
+	}
+}
+
+func TestPackNamedAndPositional(t *testing.T) {
+	abi, err := ethabi.JSON(strings.NewReader(abiJson))
+	if err != nil {
+		t.Fatal(err)
+	}
+	method := abi.Methods["transfer"]
+
+	exp, err := Pack(&method, `[{"address":"TCQRkmYMbb8bzrZfrtcokox8hwVmY3DCVP"},{"uint256":"1000"}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, params := range []string{
+		`{"_to":"TCQRkmYMbb8bzrZfrtcokox8hwVmY3DCVP","_value":"1000"}`,
+		`{"to":"TCQRkmYMbb8bzrZfrtcokox8hwVmY3DCVP","value":1000}`,
+		`["TCQRkmYMbb8bzrZfrtcokox8hwVmY3DCVP","0x3e8"]`,
+	} {
+		bz, err := Pack(&method, params)
+		if err != nil {
+			t.Fatalf("%s: %v", params, err)
+		}
+		if utils.Bytes2Hex(bz) != utils.Bytes2Hex(exp) {
+			t.Errorf("%s: encoding mismatch", params)
+		}
+	}
+
+	for _, params := range []string{
+		`{"to":"TCQRkmYMbb8bzrZfrtcokox8hwVmY3DCVP"}`,
+		`{"to":"TCQRkmYMbb8bzrZfrtcokox8hwVmY3DCVP","value":"1","memo":"x"}`,
+		`["TCQRkmYMbb8bzrZfrtcokox8hwVmY3DCVP"]`,
+	} {
+		if _, err := Pack(&method, params); err == nil {
+			t.Errorf("%s: expected error", params)
+		}
+	}
+}
+
+func TestDecodeOutputsMap(t *testing.T) {
+	abi, err := ethabi.JSON(strings.NewReader(abiJson))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	distributed := abi.Methods["distributed"]
+	data, err := distributed.Outputs.Pack(big.NewInt(1600000000), new(big.Int).Lsh(big.NewInt(1), 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := DecodeOutputsMap(&distributed, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res["timestamp"] != "1600000000" || res["amount"] != "1267650600228229401496703205376" {
+		t.Errorf("unexpected result %v", res)
+	}
+
+	owner := abi.Methods["owner"]
+	addr, _ := keystore.Base58ToAddress("TCQRkmYMbb8bzrZfrtcokox8hwVmY3DCVP")
+	data, err = owner.Outputs.Pack(ethcmn.BytesToAddress(addr[1:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err = DecodeOutputsMap(&owner, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res["0"] != "TCQRkmYMbb8bzrZfrtcokox8hwVmY3DCVP" {
+		t.Errorf("unexpected result %v", res)
+	}
+
+	if _, err := DecodeOutputsMap(&owner, data[:16]); err == nil {
+		t.Error("expected error for truncated output")
+	}
+}
+
+func TestDecoder(t *testing.T) {
+	abi, err := ethabi.JSON(strings.NewReader(abiJson))
+	if err != nil {
+		t.Fatal(err)
+	}
+	method := abi.Methods["transfer"]
+	data, err := Pack(&method, `{"to":"TCQRkmYMbb8bzrZfrtcokox8hwVmY3DCVP","value":"1000"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	call, err := NewDecoder(abi).Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if call.Signature != "transfer(address,uint256)" || call.Selector != "0xa9059cbb" {
+		t.Errorf("unexpected call %+v", call)
+	}
+	if call.Args["_to"] != "TCQRkmYMbb8bzrZfrtcokox8hwVmY3DCVP" || call.Args["_value"] != "1000" {
+		t.Errorf("unexpected args %v", call.Args)
+	}
+
+	d := NewDecoder()
+	if _, err := d.Decode(data); err == nil {
+		t.Error("expected unknown selector error")
+	} else if _, ok := err.(*UnknownSelectorError); !ok {
+		t.Errorf("unexpected error %v", err)
+	}
+	if err := d.LoadSignatures(strings.NewReader(`{"a9059cbb":"transfer(address,uint256)"}`)); err != nil {
+		t.Fatal(err)
+	}
+	call, err = d.Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if call.Args["0"] != "TCQRkmYMbb8bzrZfrtcokox8hwVmY3DCVP" || call.Args["1"] != "1000" {
+		t.Errorf("unexpected args %v", call.Args)
+	}
+
+	// Bad entries are skipped, colliding signatures are all kept
+	err = d.LoadSignatures(strings.NewReader(`{
+		"deadbeef": "transfer(address,uint256)",
+		"zz": "foo()",
+		"42966c68": ["burn(uint256)", "collate_propagate_storage(bytes16)"]
+	}`))
+	if errs, ok := err.(SignatureErrors); !ok || len(errs) != 2 {
+		t.Errorf("expected two skipped entries, have %v", err)
+	}
+	burn, _ := utils.Hex2Bytes("42966c68" + "0000000000000000000000000000000000000000000000000000000000000001")
+	candidates, err := d.candidates(burn)
+	if err != nil || len(candidates) != 2 {
+		t.Errorf("colliding selector: %d methods, %v", len(candidates), err)
+	}
+}
+
+func TestDecodeRevert(t *testing.T) {
+	// Error("insufficient balance")
+	data, _ := utils.Hex2Bytes("08c379a0" +
+		"0000000000000000000000000000000000000000000000000000000000000020" +
+		"0000000000000000000000000000000000000000000000000000000000000014" +
+		"696e73756666696369656e742062616c616e6365000000000000000000000000")
+	if err := DecodeRevert(data); err.Error() != "execution reverted: insufficient balance" {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	// Panic(0x11)
+	data, _ = utils.Hex2Bytes("4e487b71" + "0000000000000000000000000000000000000000000000000000000000000011")
+	if err := DecodeRevert(data); err.PanicCode == nil || err.PanicCode.Uint64() != 0x11 ||
+		err.Error() != "execution reverted: panic 0x11 (arithmetic underflow or overflow)" {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	// InsufficientBalance(uint256 available, uint256 required)
+	d := NewDecoder()
+	err := d.AddErrors(strings.NewReader(`[{"type":"error","name":"InsufficientBalance","inputs":[{"name":"available","type":"uint256"},{"name":"required","type":"uint256"}]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ = utils.Hex2Bytes(utils.Bytes2Hex(Signature("InsufficientBalance(uint256,uint256)")) +
+		"0000000000000000000000000000000000000000000000000000000000000001" +
+		"0000000000000000000000000000000000000000000000000000000000000002")
+	if err := d.DecodeRevert(data); err.Name != "InsufficientBalance" ||
+		err.Error() != "execution reverted: InsufficientBalance(available=1, required=2)" {
+		t.Errorf("unexpected error %v", err)
+	}
+	if err := DecodeRevert(data); err.Name != "" {
+		t.Errorf("unexpected custom error decoding without registration: %v", err)
+	}
+}
+
+func TestTransactionErrors(t *testing.T) {
+	d := NewDecoder()
+	if err := d.AddErrorSignature("InsufficientBalance(uint256 available, uint256 required)"); err != nil {
+		t.Fatal(err)
+	}
+	custom, _ := utils.Hex2Bytes(utils.Bytes2Hex(Signature("InsufficientBalance(uint256,uint256)")) +
+		"0000000000000000000000000000000000000000000000000000000000000001" +
+		"0000000000000000000000000000000000000000000000000000000000000002")
+	word := make([]byte, 32)
+	reverted := &core.Transaction{Ret: []*core.Transaction_Result{{ContractRet: core.Transaction_Result_REVERT}}}
+	succeeded := &core.Transaction{Ret: []*core.Transaction_Result{{ContractRet: core.Transaction_Result_SUCCESS}}}
+
+	for _, tt := range []struct {
+		name string
+		ext  *api.TransactionExtention
+		want string // Error message, empty if the call succeeded
+	}{
+		{"success", &api.TransactionExtention{Result: &api.Return{Result: true}, Transaction: succeeded, ConstantResult: [][]byte{word}}, ""},
+		{"node error", &api.TransactionExtention{Result: &api.Return{Message: []byte("contract validate error")}}, "execution reverted: contract validate error"},
+		{"revert with result true", &api.TransactionExtention{Result: &api.Return{Result: true}, Transaction: reverted, ConstantResult: [][]byte{custom}}, "execution reverted: InsufficientBalance(available=1, required=2)"},
+		{"revert without data", &api.TransactionExtention{Result: &api.Return{Result: true}, Transaction: reverted}, "execution reverted: REVERT"},
+		{"registered error without ret", &api.TransactionExtention{Result: &api.Return{Result: true}, ConstantResult: [][]byte{custom}}, "execution reverted: InsufficientBalance(available=1, required=2)"},
+		{"return data with error selector", &api.TransactionExtention{Result: &api.Return{Result: true}, ConstantResult: [][]byte{append(utils.CopyBytes(custom[:4]), word...)[:32]}}, ""},
+	} {
+		err := d.TransactionExtentionError(tt.ext)
+		if (err == nil) != (tt.want == "") || err != nil && err.Error() != tt.want {
+			t.Errorf("%s: have %v, want %q", tt.name, err, tt.want)
+		}
+	}
+
+	if err := d.TransactionInfoError(&core.TransactionInfo{}); err != nil {
+		t.Errorf("successful transaction: %v", err)
+	}
+	info := &core.TransactionInfo{Result: core.TransactionInfo_FAILED, ContractResult: [][]byte{custom}}
+	if err := d.TransactionInfoError(info); err == nil || err.(*RevertError).Name != "InsufficientBalance" {
+		t.Errorf("reverted transaction: %v", err)
+	}
+	info = &core.TransactionInfo{Result: core.TransactionInfo_FAILED, ResMessage: []byte("Not enough energy")}
+	if err := d.TransactionInfoError(info); err == nil || err.Error() != "execution reverted: Not enough energy" {
+		t.Errorf("failed transaction: %v", err)
+	}
+
+	// Messages are text or raw revert data
+	if err := d.messageError(custom); err.Name != "InsufficientBalance" {
+		t.Errorf("message with revert data: %v", err)
+	}
+	if err := d.messageError([]byte("REVERT opcode executed")); err.Reason != "REVERT opcode executed" {
+		t.Errorf("text message: %v", err)
+	}
+}
+
+func TestProtoABIRoundTrip(t *testing.T) {
+	a, err := ethabi.JSON(strings.NewReader(abiJson))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tuple, err := parseType("(address to,uint256[] amounts)[]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.Methods["batch"] = ethabi.NewMethod("batch", "batch", ethabi.Function, "payable", false, true,
+		ethabi.Arguments{{Name: "orders", Type: tuple}}, nil)
+
+	p := ABIToProto(a)
+	for _, e := range p.GetEntrys() {
+		if e.GetName() == "batch" && e.GetInputs()[0].GetType() != "(address,uint256[])[]" {
+			t.Errorf("tuple stored as %s, want (address,uint256[])[]", e.GetInputs()[0].GetType())
+		}
+	}
+	b, skipped, err := ProtoToABI(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(skipped) != 0 {
+		t.Errorf("skipped %d entries", len(skipped))
+	}
+	if len(b.Methods) != len(a.Methods) || len(b.Events) != len(a.Events) {
+		t.Fatalf("entry count mismatch: have %d/%d, want %d/%d", len(b.Methods), len(b.Events), len(a.Methods), len(a.Events))
+	}
+	for name, m := range a.Methods {
+		if b.Methods[name].String() != m.String() || b.Methods[name].StateMutability != m.StateMutability {
+			t.Errorf("method %s: have %s, want %s", name, b.Methods[name].String(), m.String())
+		}
+	}
+	for name, e := range a.Events {
+		if b.Events[name].String() != e.String() {
+			t.Errorf("event %s: have %s, want %s", name, b.Events[name].String(), e.String())
+		}
+	}
+	if b.Constructor.String() != a.Constructor.String() || !b.HasFallback() {
+		t.Errorf("constructor or fallback lost")
+	}
+
+	// Tuples stored without components, as java-tron does, are reported
+	batch := &contract.SmartContract_ABI_Entry{
+		Name:   "batch",
+		Type:   contract.SmartContract_ABI_Entry_Function,
+		Inputs: []*contract.SmartContract_ABI_Entry_Param{{Name: "orders", Type: "tuple[]"}},
+	}
+	p.Entrys = append(p.Entrys, batch)
+	if _, skipped, err := ProtoToABI(p); err != nil || len(skipped) != 1 || skipped[0] != batch {
+		t.Errorf("have skipped %v, %v, want the batch method", skipped, err)
+	}
+}
+
+func TestDecodeEvent(t *testing.T) {
+	a, err := ethabi.JSON(strings.NewReader(abiJson))
+	if err != nil {
+		t.Fatal(err)
+	}
+	from, _ := keystore.Base58ToAddress("TCQRkmYMbb8bzrZfrtcokox8hwVmY3DCVP")
+	to, _ := keystore.Base58ToAddress("TCudRMFJDPChH2FNjVb82cvbREMPNUm1pj")
+	data, _ := a.Events["Transfer"].Inputs.NonIndexed().Pack(big.NewInt(1000))
+
+	ev, err := DecodeEvent(&a, &core.TransactionInfo_Log{
+		Address: from[1:],
+		Topics:  [][]byte{a.Events["Transfer"].ID.Bytes(), ethcmn.LeftPadBytes(from[1:], 32), ethcmn.LeftPadBytes(to[1:], 32)},
+		Data:    data,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev.Event != "Transfer" || ev.Address != "TCQRkmYMbb8bzrZfrtcokox8hwVmY3DCVP" ||
+		ev.Args["from"] != "TCQRkmYMbb8bzrZfrtcokox8hwVmY3DCVP" || ev.Args["to"] != "TCudRMFJDPChH2FNjVb82cvbREMPNUm1pj" || ev.Args["value"] != "1000" {
+		t.Errorf("unexpected event %+v", ev)
+	}
+
+	// Unnamed arguments are keyed by their position in the event
+	unnamed, err := ethabi.JSON(strings.NewReader(`[{"type":"event","name":"Transfer","inputs":[` +
+		`{"name":"","type":"address","indexed":true},{"name":"","type":"address","indexed":true},{"name":"","type":"uint256","indexed":false}]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range unnamed.Events["Transfer"].Inputs {
+		unnamed.Events["Transfer"].Inputs[i].Name = ""
+	}
+	ev, err = DecodeEvent(&unnamed, &core.TransactionInfo_Log{
+		Address: from[1:],
+		Topics:  [][]byte{unnamed.Events["Transfer"].ID.Bytes(), ethcmn.LeftPadBytes(from[1:], 32), ethcmn.LeftPadBytes(to[1:], 32)},
+		Data:    data,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ev.Args) != 3 || ev.Args["0"] != "TCQRkmYMbb8bzrZfrtcokox8hwVmY3DCVP" || ev.Args["1"] != "TCudRMFJDPChH2FNjVb82cvbREMPNUm1pj" || ev.Args["2"] != "1000" {
+		t.Errorf("unexpected unnamed event args %v", ev.Args)
+	}
+}
+
+func TestPackedEncode(t *testing.T) {
+	packed, err := PackedEncode(
+		[]string{"address", "uint8", "int16", "bool", "string", "bytes2", "uint16[]"},
+		[]interface{}{"TCQRkmYMbb8bzrZfrtcokox8hwVmY3DCVP", "255", "-2", true, "tron", "0xabcd", []interface{}{"1", "2"}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := "1ab54bfac5a64d4e34468ae87b1bf46b59949111" + "ff" + "fffe" + "01" + "74726f6e" + "abcd" +
+		"0000000000000000000000000000000000000000000000000000000000000001" +
+		"0000000000000000000000000000000000000000000000000000000000000002"
+	if s := utils.Bytes2Hex(packed); s != exp {
+		t.Errorf("unexpected encoding\nhave %s\nwant %s", s, exp)
+	}
+
+	// keccak256(abi.encodePacked("hello")) == keccak256("hello")
+	hash, err := SolidityKeccak([]string{"string"}, []interface{}{"hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := utils.Bytes2Hex(hash); s != "1c8aff950685c2ed4bc3174f3472287b56d9517b9c948127319a09a7a36deac8" {
+		t.Errorf("unexpected hash %s", s)
+	}
+
+	if _, err := PackedEncode([]string{"string[]"}, []interface{}{[]interface{}{"a"}}); err == nil {
+		t.Error("expected error for string array")
+	}
+}
+
+func TestParseMethod(t *testing.T) {
+	tests := []struct {
+		sig, str, id string
+	}{
+		{"transfer(address,uint256)", "function transfer(address , uint256 ) returns()", "a9059cbb"},
+		{"transfer(address,uint) returns (bool)", "function transfer(address , uint256 ) returns(bool)", "a9059cbb"},
+		{"function transfer(address to, uint256 amount) external returns (bool success)", "function transfer(address to, uint256 amount) returns(bool success)", "a9059cbb"},
+		{"function balanceOf(address) view returns (uint256)", "function balanceOf(address ) view returns(uint256)", "70a08231"},
+		{"function swap(tuple(address token, uint256 amount)[] calldata path) payable", "function swap((address,uint256)[] path) payable returns()", ""},
+	}
+	for _, tt := range tests {
+		m, err := ParseMethod(tt.sig)
+		if err != nil {
+			t.Errorf("%s: %v", tt.sig, err)
+			continue
+		}
+		if m.String() != tt.str {
+			t.Errorf("%s: have %q, want %q", tt.sig, m.String(), tt.str)
+		}
+		if tt.id != "" && utils.Bytes2Hex(m.ID) != tt.id {
+			t.Errorf("%s: have id %x, want %s", tt.sig, m.ID, tt.id)
+		}
+	}
+
+	m, err := ParseMethod("function swap((address token, uint256 amount)[] path) payable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.IsPayable() || m.Sig != "swap((address,uint256)[])" {
+		t.Errorf("unexpected method %s", m.Sig)
+	}
+	if _, err := Pack(m, `{"path":[{"token":"TCQRkmYMbb8bzrZfrtcokox8hwVmY3DCVP","amount":"1"}]}`); err != nil {
+		t.Error(err)
+	}
+
+	for _, sig := range []string{"transfer", "(address)", "f(address) bogus", "f(address) view pure", "f(address"} {
+		if _, err := ParseMethod(sig); err == nil {
+			t.Errorf("%s: expected error", sig)
+		}
+	}
+}
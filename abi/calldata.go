@@ -0,0 +1,224 @@
+package abi
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bytejedi/tron-sdk-go/utils"
+	ethabi "github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+// UnknownSelectorError is returned when no registered method matches the
+// 4-byte selector of the calldata.
+type UnknownSelectorError struct {
+	Selector []byte
+}
+
+func (err *UnknownSelectorError) Error() string {
+	return fmt.Sprintf("unknown method selector %s", utils.BytesToHexString(err.Selector))
+}
+
+// DecodedCall is a contract call decoded from its calldata.
+type DecodedCall struct {
+	Method    string                 `json:"method"`    // Method name
+	Signature string                 `json:"signature"` // Canonical signature, e.g. transfer(address,uint256)
+	Selector  string                 `json:"selector"`  // 0x-prefixed 4-byte selector
+	Args      map[string]interface{} `json:"args"`      // Arguments keyed by name, or by index if unnamed
+}
+
+// Decoder resolves calldata, e.g. TriggerSmartContract.data, back into the
+// method call it encodes. Methods are registered from contract ABIs or from a
+// 4byte-style signature database.
+type Decoder struct {
+	mu         sync.RWMutex
+	methods    map[string][]ethabi.Method // Methods from contract ABIs by selector
+	signatures map[string][]ethabi.Method // Methods from bare signatures by selector
+	errors     map[string][]ethabi.Method // Custom errors by selector
+}
+
+// NewDecoder creates a decoder that knows the methods of the given ABIs.
+func NewDecoder(abis ...ethabi.ABI) *Decoder {
+	d := &Decoder{
+		methods:    make(map[string][]ethabi.Method),
+		signatures: make(map[string][]ethabi.Method),
+		errors:     make(map[string][]ethabi.Method),
+	}
+	for _, a := range abis {
+		d.AddABI(a)
+	}
+	return d
+}
+
+// AddABI registers all methods of the given ABI.
+func (d *Decoder) AddABI(a ethabi.ABI) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, m := range a.Methods {
+		d.methods[string(m.ID)] = appendMethod(d.methods[string(m.ID)], m)
+	}
+}
+
+// AddSignature registers a method by its canonical text signature, e.g.
+// "transfer(address,uint256)". Arguments of such methods are unnamed.
+func (d *Decoder) AddSignature(sig string) error {
+	m, err := parseFunction(sig, "function")
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.signatures[string(m.ID)] = appendMethod(d.signatures[string(m.ID)], m)
+	return nil
+}
+
+// SignatureErrors lists the entries of a signature database that
+// LoadSignatures skipped.
+type SignatureErrors []error
+
+func (errs SignatureErrors) Error() string {
+	msg := fmt.Sprintf("%d invalid signature entries", len(errs))
+	if len(errs) > 0 {
+		msg += ", first: " + errs[0].Error()
+	}
+	return msg
+}
+
+// LoadSignatures reads a 4byte-style signature database, a JSON object that
+// maps hex selectors to a canonical signature or, for colliding selectors, a
+// list of them:
+//
+//	{"a9059cbb": "transfer(address,uint256)", "42966c68": ["burn(uint256)", ...], ...}
+//
+// Entries that cannot be parsed or whose selector does not match the hash of
+// the signature are skipped and returned as SignatureErrors, all other
+// entries are registered.
+func (d *Decoder) LoadSignatures(r io.Reader) error {
+	var db map[string]json.RawMessage
+	if err := json.NewDecoder(r).Decode(&db); err != nil {
+		return err
+	}
+	selectors := make([]string, 0, len(db))
+	for selector := range db {
+		selectors = append(selectors, selector)
+	}
+	sort.Strings(selectors)
+
+	var errs SignatureErrors
+	for _, selector := range selectors {
+		want, err := hex.DecodeString(strings.TrimPrefix(strings.ToLower(selector), "0x"))
+		if err != nil || len(want) != 4 {
+			errs = append(errs, fmt.Errorf("invalid selector %q", selector))
+			continue
+		}
+		var sigs []string
+		if err := json.Unmarshal(db[selector], &sigs); err != nil {
+			var sig string
+			if err := json.Unmarshal(db[selector], &sig); err != nil {
+				errs = append(errs, fmt.Errorf("selector %s: want a signature or a list of them", selector))
+				continue
+			}
+			sigs = []string{sig}
+		}
+		for _, sig := range sigs {
+			m, err := parseFunction(sig, "function")
+			if err != nil {
+				errs = append(errs, fmt.Errorf("selector %s: %v", selector, err))
+				continue
+			}
+			if !bytes.Equal(m.ID, want) {
+				errs = append(errs, fmt.Errorf("selector %s does not match signature %q", selector, sig))
+				continue
+			}
+			d.mu.Lock()
+			d.signatures[string(m.ID)] = appendMethod(d.signatures[string(m.ID)], m)
+			d.mu.Unlock()
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// LoadSignatureFile reads a 4byte-style signature database from a file.
+func (d *Decoder) LoadSignatureFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return d.LoadSignatures(f)
+}
+
+// Method returns the method registered for the selector at the start of data.
+// Methods from ABIs take precedence over bare signatures.
+func (d *Decoder) Method(data []byte) (*ethabi.Method, error) {
+	candidates, err := d.candidates(data)
+	if err != nil {
+		return nil, err
+	}
+	return &candidates[0], nil
+}
+
+// Decode finds the method for the selector at the start of data and decodes
+// its arguments. If several methods share the selector, the first one whose
+// arguments decode is used.
+func (d *Decoder) Decode(data []byte) (*DecodedCall, error) {
+	candidates, err := d.candidates(data)
+	if err != nil {
+		return nil, err
+	}
+	var firstErr error
+	for i := range candidates {
+		m := &candidates[i]
+		args, err := decodeArguments(m.Inputs, data[4:])
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("decode %s: %v", m.Sig, err)
+			}
+			continue
+		}
+		return &DecodedCall{
+			Method:    m.RawName,
+			Signature: m.Sig,
+			Selector:  utils.BytesToHexString(m.ID),
+			Args:      args,
+		}, nil
+	}
+	return nil, firstErr
+}
+
+func (d *Decoder) candidates(data []byte) ([]ethabi.Method, error) {
+	if len(data) < 4 {
+		return nil, fmt.Errorf("calldata too short (%d bytes) for method lookup", len(data))
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	selector := string(data[:4])
+	candidates := append(append([]ethabi.Method{}, d.methods[selector]...), d.signatures[selector]...)
+	if len(candidates) == 0 {
+		return nil, &UnknownSelectorError{Selector: utils.CopyBytes(data[:4])}
+	}
+	return candidates, nil
+}
+
+// appendMethod adds m to list unless a method with the same signature is
+// already present.
+func appendMethod(list []ethabi.Method, m ethabi.Method) []ethabi.Method {
+	for _, have := range list {
+		if have.Sig == m.Sig && have.RawName == m.RawName {
+			return list
+		}
+	}
+	return append(list, m)
+}
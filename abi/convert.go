@@ -0,0 +1,288 @@
+package abi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/bytejedi/tron-sdk-go/keystore"
+	"github.com/bytejedi/tron-sdk-go/utils"
+	ethabi "github.com/ethereum/go-ethereum/accounts/abi"
+	ethcmn "github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	bigOne     = big.NewInt(1)
+	bigIntPtTy = reflect.TypeOf(&big.Int{})
+)
+
+// normalizeType expands the solidity aliases "uint" and "int" into their
+// canonical 256 bit forms, keeping any array suffix intact.
+func normalizeType(t string) string {
+	base, suffix := t, ""
+	if i := strings.Index(t, "["); i >= 0 {
+		base, suffix = t[:i], t[i:]
+	}
+	switch base {
+	case "uint", "int":
+		base += "256"
+	}
+	return base + suffix
+}
+
+// convertToType converts a JSON-decoded value into the go value expected by
+// go-ethereum when packing the given abi type.
+func convertToType(ty ethabi.Type, v interface{}) (interface{}, error) {
+	rv, err := convertToValue(ty, v)
+	if err != nil {
+		return nil, err
+	}
+	return rv.Interface(), nil
+}
+
+func convertToValue(ty ethabi.Type, v interface{}) (reflect.Value, error) {
+	if v == nil {
+		return reflect.Value{}, fmt.Errorf("missing value for %s", ty.String())
+	}
+	// Values that already have the exact go type are passed through
+	if reflect.TypeOf(v) == ty.GetType() {
+		return reflect.ValueOf(v), nil
+	}
+
+	switch ty.T {
+	case ethabi.IntTy, ethabi.UintTy:
+		n, err := convertToBigInt(v)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return intValue(ty, n)
+
+	case ethabi.BoolTy:
+		switch b := v.(type) {
+		case bool:
+			return reflect.ValueOf(b), nil
+		case string:
+			parsed, err := strconv.ParseBool(b)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("invalid bool %q", b)
+			}
+			return reflect.ValueOf(parsed), nil
+		}
+		return reflect.Value{}, fmt.Errorf("cannot use %T as bool", v)
+
+	case ethabi.StringTy:
+		s, ok := v.(string)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("cannot use %T as string", v)
+		}
+		return reflect.ValueOf(s), nil
+
+	case ethabi.AddressTy:
+		addr, err := convetToAddress(v)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(addr), nil
+
+	case ethabi.BytesTy:
+		b, err := convertToBytes(v)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(b), nil
+
+	case ethabi.FixedBytesTy, ethabi.FunctionTy:
+		b, err := convertToBytes(v)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if len(b) != ty.Size {
+			return reflect.Value{}, fmt.Errorf("invalid length for %s: have %d bytes, want %d", ty.String(), len(b), ty.Size)
+		}
+		arr := reflect.New(ty.GetType()).Elem()
+		reflect.Copy(arr, reflect.ValueOf(b))
+		return arr, nil
+
+	case ethabi.SliceTy, ethabi.ArrayTy:
+		list, ok := v.([]interface{})
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("cannot use %T as %s", v, ty.String())
+		}
+		if ty.T == ethabi.ArrayTy && len(list) != ty.Size {
+			return reflect.Value{}, fmt.Errorf("invalid length for %s: have %d elements, want %d", ty.String(), len(list), ty.Size)
+		}
+		var out reflect.Value
+		if ty.T == ethabi.ArrayTy {
+			out = reflect.New(ty.GetType()).Elem()
+		} else {
+			out = reflect.MakeSlice(ty.GetType(), len(list), len(list))
+		}
+		for i, item := range list {
+			elem, err := convertToValue(*ty.Elem, item)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("element %d: %v", i, err)
+			}
+			out.Index(i).Set(elem)
+		}
+		return out, nil
+
+	case ethabi.TupleTy:
+		out := reflect.New(ty.TupleType).Elem()
+		switch fields := v.(type) {
+		case []interface{}:
+			if len(fields) != len(ty.TupleElems) {
+				return reflect.Value{}, fmt.Errorf("invalid length for %s: have %d fields, want %d", ty.String(), len(fields), len(ty.TupleElems))
+			}
+			for i, elem := range ty.TupleElems {
+				fv, err := convertToValue(*elem, fields[i])
+				if err != nil {
+					return reflect.Value{}, fmt.Errorf("field %d: %v", i, err)
+				}
+				out.Field(i).Set(fv)
+			}
+		case map[string]interface{}:
+			if len(fields) != len(ty.TupleElems) {
+				return reflect.Value{}, fmt.Errorf("invalid field count for %s: have %d, want %d", ty.String(), len(fields), len(ty.TupleElems))
+			}
+			for i, elem := range ty.TupleElems {
+				name := ty.TupleRawNames[i]
+				raw, ok := fields[name]
+				if !ok {
+					return reflect.Value{}, fmt.Errorf("missing field %q", name)
+				}
+				fv, err := convertToValue(*elem, raw)
+				if err != nil {
+					return reflect.Value{}, fmt.Errorf("field %q: %v", name, err)
+				}
+				out.Field(i).Set(fv)
+			}
+		default:
+			return reflect.Value{}, fmt.Errorf("cannot use %T as %s", v, ty.String())
+		}
+		return out, nil
+	}
+	return reflect.Value{}, fmt.Errorf("unsupported type %s", ty.String())
+}
+
+// convertToBigInt parses decimal strings, 0x-prefixed hex strings and JSON
+// numbers into a big integer.
+func convertToBigInt(v interface{}) (*big.Int, error) {
+	switch n := v.(type) {
+	case string:
+		return parseBigInt(n)
+	case json.Number:
+		return parseBigInt(n.String())
+	case float64:
+		if n != math.Trunc(n) || math.Abs(n) > 1<<53 {
+			return nil, fmt.Errorf("number %v is not an exact integer, pass it as a string", n)
+		}
+		return big.NewInt(int64(n)), nil
+	case *big.Int:
+		return new(big.Int).Set(n), nil
+	case int:
+		return big.NewInt(int64(n)), nil
+	case int64:
+		return big.NewInt(n), nil
+	case uint64:
+		return new(big.Int).SetUint64(n), nil
+	}
+	return nil, fmt.Errorf("cannot use %T as integer", v)
+}
+
+func parseBigInt(s string) (*big.Int, error) {
+	str := strings.TrimSpace(s)
+	neg := strings.HasPrefix(str, "-")
+	if neg {
+		str = str[1:]
+	}
+	base := 10
+	if utils.Has0xPrefix(str) {
+		str, base = str[2:], 16
+	}
+	if str == "" || strings.HasPrefix(str, "+") || strings.HasPrefix(str, "-") {
+		return nil, fmt.Errorf("invalid integer %q", s)
+	}
+	n, ok := new(big.Int).SetString(str, base)
+	if !ok {
+		return nil, fmt.Errorf("invalid integer %q", s)
+	}
+	if neg {
+		n.Neg(n)
+	}
+	return n, nil
+}
+
+// intValue range checks n against ty and returns it in the go representation
+// go-ethereum uses for that integer size.
+func intValue(ty ethabi.Type, n *big.Int) (reflect.Value, error) {
+	var min, max *big.Int
+	if ty.T == ethabi.UintTy {
+		min = new(big.Int)
+		max = new(big.Int).Sub(new(big.Int).Lsh(bigOne, uint(ty.Size)), bigOne)
+	} else {
+		max = new(big.Int).Sub(new(big.Int).Lsh(bigOne, uint(ty.Size-1)), bigOne)
+		min = new(big.Int).Neg(new(big.Int).Lsh(bigOne, uint(ty.Size-1)))
+	}
+	if n.Cmp(min) < 0 || n.Cmp(max) > 0 {
+		return reflect.Value{}, fmt.Errorf("value %s out of range for %s", n.String(), ty.String())
+	}
+
+	goTy := ty.GetType()
+	if goTy == bigIntPtTy {
+		return reflect.ValueOf(n), nil
+	}
+	out := reflect.New(goTy).Elem()
+	if ty.T == ethabi.UintTy {
+		out.SetUint(n.Uint64())
+	} else {
+		out.SetInt(n.Int64())
+	}
+	return out, nil
+}
+
+// convertToBytes decodes a hex string, with or without the 0x prefix.
+func convertToBytes(v interface{}) ([]byte, error) {
+	switch b := v.(type) {
+	case []byte:
+		return b, nil
+	case string:
+		s := b
+		if utils.Has0xPrefix(s) {
+			s = s[2:]
+		}
+		if len(s)%2 != 0 {
+			return nil, fmt.Errorf("invalid hex string %q: odd length", b)
+		}
+		decoded, err := utils.Hex2Bytes(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex string %q: %v", b, err)
+		}
+		return decoded, nil
+	}
+	return nil, fmt.Errorf("cannot use %T as bytes", v)
+}
+
+// convetToAddress accepts any address form understood by
+// keystore.ParseAddress, as well as keystore.Address and ethcmn.Address values.
+func convetToAddress(v interface{}) (ethcmn.Address, error) {
+	switch addr := v.(type) {
+	case string:
+		parsed, err := keystore.ParseAddress(addr)
+		if err != nil {
+			return ethcmn.Address{}, err
+		}
+		return parsed.EVM(), nil
+	case keystore.Address:
+		if !addr.IsValid() {
+			return ethcmn.Address{}, fmt.Errorf("%w: %x", keystore.ErrInvalidAddress, addr[:])
+		}
+		return addr.EVM(), nil
+	case ethcmn.Address:
+		return addr, nil
+	}
+	return ethcmn.Address{}, fmt.Errorf("invalid address %v", v)
+}
@@ -0,0 +1,179 @@
+package abi
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+
+	"github.com/bytejedi/tron-sdk-go/keystore"
+	"github.com/bytejedi/tron-sdk-go/proto/core"
+	"github.com/bytejedi/tron-sdk-go/utils"
+	ethabi "github.com/ethereum/go-ethereum/accounts/abi"
+	ethcmn "github.com/ethereum/go-ethereum/common"
+)
+
+// DecodeOutputsMap unpacks the outputs data of method into a map keyed by
+// output name. Unnamed outputs are keyed by their index ("0", "1", ...).
+//
+// Values are rendered so they can be marshaled to JSON as is: integers as
+// decimal strings, addresses as base58 tron addresses, bytes as 0x-prefixed
+// hex, arrays as lists and tuples as maps keyed by component name.
+func DecodeOutputsMap(method *ethabi.Method, outputs []byte) (map[string]interface{}, error) {
+	return decodeArguments(method.Outputs, outputs)
+}
+
+// decodeArguments unpacks the non-indexed arguments from data. Unnamed
+// arguments are keyed by their index in args, so they do not collide with
+// indexed arguments decoded from topics.
+func decodeArguments(args ethabi.Arguments, data []byte) (map[string]interface{}, error) {
+	values, err := args.UnpackValues(data)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]interface{}, len(args))
+	j := 0
+	for i, arg := range args {
+		if arg.Indexed {
+			continue
+		}
+		fv, err := formatValue(arg.Type, values[j])
+		if err != nil {
+			return nil, fmt.Errorf("param %d (%s): %v", i, arg.Type.String(), err)
+		}
+		res[argumentKey(arg.Name, i)] = fv
+		j++
+	}
+	return res, nil
+}
+
+// argumentKey returns the map key for an argument, its name or, for unnamed
+// arguments, its index.
+func argumentKey(name string, index int) string {
+	if name == "" {
+		return strconv.Itoa(index)
+	}
+	return name
+}
+
+// formatValue converts a value unpacked by go-ethereum into its JSON-friendly
+// tron representation.
+func formatValue(ty ethabi.Type, v interface{}) (interface{}, error) {
+	rv := reflect.ValueOf(v)
+	switch ty.T {
+	case ethabi.IntTy, ethabi.UintTy:
+		switch n := v.(type) {
+		case *big.Int:
+			return n.String(), nil
+		}
+		switch rv.Kind() {
+		case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return strconv.FormatInt(rv.Int(), 10), nil
+		case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return strconv.FormatUint(rv.Uint(), 10), nil
+		}
+
+	case ethabi.BoolTy, ethabi.StringTy:
+		return v, nil
+
+	case ethabi.AddressTy:
+		if addr, ok := v.(ethcmn.Address); ok {
+			return keystore.EVMToAddress(addr).String(), nil
+		}
+
+	case ethabi.BytesTy, ethabi.FixedBytesTy, ethabi.FunctionTy, ethabi.HashTy:
+		switch rv.Kind() {
+		case reflect.Slice:
+			return utils.BytesToHexString(rv.Bytes()), nil
+		case reflect.Array:
+			b := make([]byte, rv.Len())
+			reflect.Copy(reflect.ValueOf(b), rv)
+			return utils.BytesToHexString(b), nil
+		}
+
+	case ethabi.SliceTy, ethabi.ArrayTy:
+		if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+			list := make([]interface{}, rv.Len())
+			for i := range list {
+				item, err := formatValue(*ty.Elem, rv.Index(i).Interface())
+				if err != nil {
+					return nil, fmt.Errorf("element %d: %v", i, err)
+				}
+				list[i] = item
+			}
+			return list, nil
+		}
+
+	case ethabi.TupleTy:
+		if rv.Kind() == reflect.Struct {
+			fields := make(map[string]interface{}, len(ty.TupleElems))
+			for i, elem := range ty.TupleElems {
+				item, err := formatValue(*elem, rv.Field(i).Interface())
+				if err != nil {
+					return nil, fmt.Errorf("field %d: %v", i, err)
+				}
+				fields[argumentKey(ty.TupleRawNames[i], i)] = item
+			}
+			return fields, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected value %T for %s", v, ty.String())
+}
+
+// DecodedEvent is a contract event decoded from a transaction log.
+type DecodedEvent struct {
+	Event     string                 `json:"event"`     // Event name
+	Signature string                 `json:"signature"` // Canonical signature, e.g. Transfer(address,address,uint256)
+	Address   string                 `json:"address"`   // Base58 address of the emitting contract
+	Args      map[string]interface{} `json:"args"`      // Indexed and non-indexed arguments by name
+}
+
+// DecodeEvent decodes a TransactionInfo log with the events of a. Indexed
+// arguments of dynamic types are only available as their keccak256 hash and
+// are returned as hex.
+func DecodeEvent(a *ethabi.ABI, log *core.TransactionInfo_Log) (*DecodedEvent, error) {
+	topics := log.GetTopics()
+	if len(topics) == 0 {
+		return nil, fmt.Errorf("log has no topics, anonymous events cannot be matched")
+	}
+	event, err := a.EventByID(ethcmn.BytesToHash(topics[0]))
+	if err != nil {
+		return nil, err
+	}
+
+	args, err := decodeArguments(event.Inputs, log.GetData())
+	if err != nil {
+		return nil, fmt.Errorf("event %s: %v", event.Sig, err)
+	}
+	t := 1
+	for i, input := range event.Inputs {
+		if !input.Indexed {
+			continue
+		}
+		if t >= len(topics) {
+			return nil, fmt.Errorf("event %s: missing topic for param %d", event.Sig, i)
+		}
+		topic := topics[t]
+		t++
+
+		key := argumentKey(input.Name, i)
+		switch input.Type.T {
+		case ethabi.StringTy, ethabi.BytesTy, ethabi.SliceTy, ethabi.ArrayTy, ethabi.TupleTy:
+			args[key] = utils.BytesToHexString(topic)
+			continue
+		}
+		values, err := ethabi.Arguments{{Type: input.Type}}.UnpackValues(topic)
+		if err != nil {
+			return nil, fmt.Errorf("event %s: param %d: %v", event.Sig, i, err)
+		}
+		if args[key], err = formatValue(input.Type, values[0]); err != nil {
+			return nil, fmt.Errorf("event %s: param %d: %v", event.Sig, i, err)
+		}
+	}
+
+	res := &DecodedEvent{Event: event.RawName, Signature: event.Sig, Args: args}
+	if addr, err := keystore.BytesToAddress(log.GetAddress()); err == nil {
+		res.Address = addr.String()
+	}
+	return res, nil
+}
@@ -0,0 +1,118 @@
+package abi
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+
+	"github.com/bytejedi/tron-sdk-go/utils"
+	ethabi "github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// PackedEncode encodes values like solidity's abi.encodePacked. Types are
+// solidity type strings and values take the same forms as the params of
+// Pack, so addresses may be given as base58 tron addresses. Addresses are
+// encoded in their 20 byte form, as the TVM does.
+//
+// Static types use as many bytes as they need, string and bytes are not
+// padded and array elements are padded to 32 bytes. Tuples and arrays of
+// dynamic types cannot be packed.
+func PackedEncode(types []string, values []interface{}) ([]byte, error) {
+	if len(types) != len(values) {
+		return nil, fmt.Errorf("argument count mismatch: got %d values for %d types", len(values), len(types))
+	}
+	var out []byte
+	for i, t := range types {
+		t = normalizeType(t)
+		ty, err := ethabi.NewType(t, "", nil)
+		if err != nil {
+			return nil, fmt.Errorf("invalid param %d (%s): %v", i, t, err)
+		}
+		rv, err := convertToValue(ty, values[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid param %d (%s): %v", i, t, err)
+		}
+		packed, err := packedElement(ty, rv, false)
+		if err != nil {
+			return nil, fmt.Errorf("invalid param %d (%s): %v", i, t, err)
+		}
+		out = append(out, packed...)
+	}
+	return out, nil
+}
+
+// SolidityKeccak returns keccak256(abi.encodePacked(values...)), see
+// PackedEncode for the accepted types and values.
+func SolidityKeccak(types []string, values []interface{}) ([]byte, error) {
+	packed, err := PackedEncode(types, values)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.Keccak256(packed), nil
+}
+
+// packedElement encodes a single value in packed mode. Inside arrays every
+// element takes a full 32 byte word.
+func packedElement(ty ethabi.Type, rv reflect.Value, inArray bool) ([]byte, error) {
+	switch ty.T {
+	case ethabi.IntTy, ethabi.UintTy:
+		var n *big.Int
+		switch rv.Kind() {
+		case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n = big.NewInt(rv.Int())
+		case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			n = new(big.Int).SetUint64(rv.Uint())
+		default:
+			n = new(big.Int).Set(rv.Interface().(*big.Int))
+		}
+		word := math.U256Bytes(n)
+		if inArray {
+			return word, nil
+		}
+		return word[32-ty.Size/8:], nil
+
+	case ethabi.BoolTy:
+		b := []byte{0}
+		if rv.Bool() {
+			b[0] = 1
+		}
+		if inArray {
+			return utils.LeftPadBytes(b, 32), nil
+		}
+		return b, nil
+
+	case ethabi.AddressTy, ethabi.FixedBytesTy:
+		b := make([]byte, rv.Len())
+		reflect.Copy(reflect.ValueOf(b), rv)
+		if !inArray {
+			return b, nil
+		}
+		if ty.T == ethabi.AddressTy {
+			return utils.LeftPadBytes(b, 32), nil
+		}
+		return utils.RightPadBytes(b, 32), nil
+
+	case ethabi.StringTy, ethabi.BytesTy:
+		if inArray {
+			return nil, fmt.Errorf("arrays of %s cannot be packed", ty.String())
+		}
+		if ty.T == ethabi.StringTy {
+			return []byte(rv.String()), nil
+		}
+		return rv.Bytes(), nil
+
+	case ethabi.SliceTy, ethabi.ArrayTy:
+		var out []byte
+		for i := 0; i < rv.Len(); i++ {
+			elem, err := packedElement(*ty.Elem, rv.Index(i), true)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, elem...)
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("type %s cannot be packed", ty.String())
+}
@@ -0,0 +1,239 @@
+package abi
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/bytejedi/tron-sdk-go/proto/core/contract"
+	ethabi "github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+// Entry types newer java-tron nodes report that are missing from the
+// generated SmartContract_ABI_Entry_EntryType enum.
+const (
+	entryTypeReceive contract.SmartContract_ABI_Entry_EntryType = 5
+	entryTypeError   contract.SmartContract_ABI_Entry_EntryType = 6
+)
+
+var stateMutabilityNames = map[contract.SmartContract_ABI_Entry_StateMutabilityType]string{
+	contract.SmartContract_ABI_Entry_Pure:       "pure",
+	contract.SmartContract_ABI_Entry_View:       "view",
+	contract.SmartContract_ABI_Entry_Nonpayable: "nonpayable",
+	contract.SmartContract_ABI_Entry_Payable:    "payable",
+}
+
+var stateMutabilityTypes = map[string]contract.SmartContract_ABI_Entry_StateMutabilityType{
+	"pure":       contract.SmartContract_ABI_Entry_Pure,
+	"view":       contract.SmartContract_ABI_Entry_View,
+	"nonpayable": contract.SmartContract_ABI_Entry_Nonpayable,
+	"payable":    contract.SmartContract_ABI_Entry_Payable,
+}
+
+// errMissingComponents is returned for tuple parameters stored on chain as
+// plain "tuple", without the component types.
+var errMissingComponents = errors.New("tuple components are missing")
+
+// ProtoToABI converts the ABI stored on chain, as returned by GetContract,
+// into a go-ethereum ABI. Error entries have no place in ethabi.ABI and are
+// skipped, register them with Decoder.AddProtoABI instead.
+//
+// Tuples written by ABIToProto keep their component types, but java-tron and
+// TronWeb store struct parameters as plain "tuple". Entries with such
+// parameters cannot be rebuilt and are returned as skipped, use the JSON ABI
+// of the contract to call them.
+func ProtoToABI(p *contract.SmartContract_ABI) (a ethabi.ABI, skipped []*contract.SmartContract_ABI_Entry, err error) {
+	a = ethabi.ABI{
+		Methods: make(map[string]ethabi.Method),
+		Events:  make(map[string]ethabi.Event),
+	}
+	for i, e := range p.GetEntrys() {
+		inputs, err := protoToArguments(e.GetInputs())
+		if errors.Is(err, errMissingComponents) {
+			skipped = append(skipped, e)
+			continue
+		}
+		if err != nil {
+			return ethabi.ABI{}, nil, fmt.Errorf("entry %d (%s): inputs: %v", i, e.GetName(), err)
+		}
+		outputs, err := protoToArguments(e.GetOutputs())
+		if errors.Is(err, errMissingComponents) {
+			skipped = append(skipped, e)
+			continue
+		}
+		if err != nil {
+			return ethabi.ABI{}, nil, fmt.Errorf("entry %d (%s): outputs: %v", i, e.GetName(), err)
+		}
+		mutability := stateMutabilityNames[e.GetStateMutability()]
+
+		switch e.GetType() {
+		case contract.SmartContract_ABI_Entry_Constructor:
+			a.Constructor = ethabi.NewMethod("", "", ethabi.Constructor, mutability, e.GetConstant(), e.GetPayable(), inputs, nil)
+		case contract.SmartContract_ABI_Entry_Function:
+			name := overloadedName(e.GetName(), func(n string) bool { _, ok := a.Methods[n]; return ok })
+			a.Methods[name] = ethabi.NewMethod(name, e.GetName(), ethabi.Function, mutability, e.GetConstant(), e.GetPayable(), inputs, outputs)
+		case contract.SmartContract_ABI_Entry_Event:
+			name := overloadedName(e.GetName(), func(n string) bool { _, ok := a.Events[n]; return ok })
+			a.Events[name] = ethabi.NewEvent(name, e.GetName(), e.GetAnonymous(), inputs)
+		case contract.SmartContract_ABI_Entry_Fallback:
+			a.Fallback = ethabi.NewMethod("", "", ethabi.Fallback, mutability, e.GetConstant(), e.GetPayable(), nil, nil)
+		case entryTypeReceive:
+			a.Receive = ethabi.NewMethod("", "", ethabi.Receive, mutability, e.GetConstant(), e.GetPayable(), nil, nil)
+		case entryTypeError:
+		default:
+			return ethabi.ABI{}, nil, fmt.Errorf("entry %d (%s): unknown entry type %v", i, e.GetName(), e.GetType())
+		}
+	}
+	return a, skipped, nil
+}
+
+// ABIToProto converts a go-ethereum ABI into the SmartContract_ABI form used
+// on chain, e.g. to deploy a contract. Methods and events are sorted by name
+// since ethabi.ABI does not keep the declaration order.
+//
+// Tuples are stored in their canonical form, e.g. "(address,uint256)[]", so
+// ProtoToABI can rebuild them. The names of the components are not kept and
+// come back as field0, field1 and so on.
+func ABIToProto(a ethabi.ABI) *contract.SmartContract_ABI {
+	p := &contract.SmartContract_ABI{}
+
+	c := a.Constructor
+	if c.Inputs != nil || c.StateMutability != "" || c.Payable {
+		p.Entrys = append(p.Entrys, methodToProto(contract.SmartContract_ABI_Entry_Constructor, c))
+	}
+
+	methods := make([]ethabi.Method, 0, len(a.Methods))
+	for _, m := range a.Methods {
+		methods = append(methods, m)
+	}
+	sort.Slice(methods, func(i, j int) bool { return methods[i].Name < methods[j].Name })
+	for _, m := range methods {
+		p.Entrys = append(p.Entrys, methodToProto(contract.SmartContract_ABI_Entry_Function, m))
+	}
+
+	events := make([]ethabi.Event, 0, len(a.Events))
+	for _, e := range a.Events {
+		events = append(events, e)
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Name < events[j].Name })
+	for _, e := range events {
+		p.Entrys = append(p.Entrys, &contract.SmartContract_ABI_Entry{
+			Anonymous: e.Anonymous,
+			Name:      e.RawName,
+			Inputs:    argumentsToProto(e.Inputs),
+			Type:      contract.SmartContract_ABI_Entry_Event,
+		})
+	}
+
+	if a.HasFallback() {
+		p.Entrys = append(p.Entrys, methodToProto(contract.SmartContract_ABI_Entry_Fallback, a.Fallback))
+	}
+	if a.HasReceive() {
+		p.Entrys = append(p.Entrys, methodToProto(entryTypeReceive, a.Receive))
+	}
+	return p
+}
+
+// AddProtoABI registers the methods and custom errors of an on-chain ABI.
+// Entries with tuples that lack their components cannot be registered and are
+// returned as skipped, as with ProtoToABI.
+func (d *Decoder) AddProtoABI(p *contract.SmartContract_ABI) (skipped []*contract.SmartContract_ABI_Entry, err error) {
+	a, skipped, err := ProtoToABI(p)
+	if err != nil {
+		return nil, err
+	}
+	d.AddABI(a)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, e := range p.GetEntrys() {
+		if e.GetType() != entryTypeError {
+			continue
+		}
+		inputs, err := protoToArguments(e.GetInputs())
+		if errors.Is(err, errMissingComponents) {
+			skipped = append(skipped, e)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error %s: %v", e.GetName(), err)
+		}
+		m := ethabi.NewMethod(e.GetName(), e.GetName(), ethabi.Function, "", false, false, inputs, nil)
+		d.errors[string(m.ID)] = appendMethod(d.errors[string(m.ID)], m)
+	}
+	return skipped, nil
+}
+
+func methodToProto(ty contract.SmartContract_ABI_Entry_EntryType, m ethabi.Method) *contract.SmartContract_ABI_Entry {
+	return &contract.SmartContract_ABI_Entry{
+		Constant:        m.Constant,
+		Name:            m.RawName,
+		Inputs:          argumentsToProto(m.Inputs),
+		Outputs:         argumentsToProto(m.Outputs),
+		Type:            ty,
+		Payable:         m.Payable,
+		StateMutability: stateMutabilityTypes[m.StateMutability],
+	}
+}
+
+func argumentsToProto(args ethabi.Arguments) []*contract.SmartContract_ABI_Entry_Param {
+	if args == nil {
+		return nil
+	}
+	params := make([]*contract.SmartContract_ABI_Entry_Param, len(args))
+	for i, arg := range args {
+		params[i] = &contract.SmartContract_ABI_Entry_Param{
+			Indexed: arg.Indexed,
+			Name:    arg.Name,
+			Type:    arg.Type.String(),
+		}
+	}
+	return params
+}
+
+func protoToArguments(params []*contract.SmartContract_ABI_Entry_Param) (ethabi.Arguments, error) {
+	if params == nil {
+		return nil, nil
+	}
+	args := make(ethabi.Arguments, len(params))
+	for i, p := range params {
+		ty, err := parseType(p.GetType())
+		if err != nil {
+			return nil, fmt.Errorf("param %d (%s): %v", i, p.GetType(), err)
+		}
+		if hasEmptyTuple(ty) {
+			return nil, fmt.Errorf("param %d (%s): %w", i, p.GetType(), errMissingComponents)
+		}
+		args[i] = ethabi.Argument{Name: p.GetName(), Type: ty, Indexed: p.GetIndexed()}
+	}
+	return args, nil
+}
+
+// hasEmptyTuple reports whether ty is or contains a tuple without components,
+// which is how some tools store tuples on chain.
+func hasEmptyTuple(ty ethabi.Type) bool {
+	switch ty.T {
+	case ethabi.SliceTy, ethabi.ArrayTy:
+		return hasEmptyTuple(*ty.Elem)
+	case ethabi.TupleTy:
+		if len(ty.TupleElems) == 0 {
+			return true
+		}
+		for _, elem := range ty.TupleElems {
+			if hasEmptyTuple(*elem) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// overloadedName returns the next free name for an overloaded method or
+// event the way go-ethereum names them: name, name0, name1, ...
+func overloadedName(rawName string, taken func(string) bool) string {
+	name := rawName
+	for idx := 0; taken(name); idx++ {
+		name = fmt.Sprintf("%s%d", rawName, idx)
+	}
+	return name
+}
@@ -0,0 +1,255 @@
+package abi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/bytejedi/tron-sdk-go/proto/api"
+	"github.com/bytejedi/tron-sdk-go/proto/core"
+	"github.com/bytejedi/tron-sdk-go/utils"
+	ethabi "github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+var (
+	// errorSelector is the selector of the solidity Error(string) revert
+	errorSelector = Signature("Error(string)")
+	// panicSelector is the selector of the solidity Panic(uint256) revert
+	panicSelector = Signature("Panic(uint256)")
+)
+
+// panicReasons maps solidity panic codes to their meaning.
+var panicReasons = map[uint64]string{
+	0x00: "generic compiler inserted panic",
+	0x01: "assert(false)",
+	0x11: "arithmetic underflow or overflow",
+	0x12: "division or modulo by zero",
+	0x21: "conversion to invalid enum value",
+	0x22: "incorrectly encoded storage byte array",
+	0x31: "pop() on an empty array",
+	0x32: "array index out of bounds",
+	0x41: "too much memory allocated",
+	0x51: "call to a zero-initialized internal function",
+}
+
+// RevertError is a failed contract call with its revert data decoded.
+type RevertError struct {
+	Name      string                 // Error, Panic, the custom error name, or empty if unknown
+	Signature string                 // Canonical signature of the error, if known
+	Reason    string                 // Human readable reason
+	PanicCode *big.Int               // Code of a Panic(uint256) revert
+	Args      map[string]interface{} // Arguments of a custom error
+	Data      []byte                 // Raw revert data
+}
+
+// Error implements the standard error interface.
+func (err *RevertError) Error() string {
+	if err.Reason == "" {
+		return "execution reverted"
+	}
+	return "execution reverted: " + err.Reason
+}
+
+// PanicReason returns the meaning of a solidity panic code.
+func PanicReason(code *big.Int) string {
+	if code.IsUint64() {
+		if reason, ok := panicReasons[code.Uint64()]; ok {
+			return reason
+		}
+	}
+	return "unknown panic code"
+}
+
+// AddErrors registers the custom errors declared in a JSON ABI. All other
+// entries are ignored, which also allows passing ABIs that go-ethereum's
+// parser rejects because of their error entries.
+func (d *Decoder) AddErrors(abiJSON io.Reader) error {
+	var entries []struct {
+		Type   string
+		Name   string
+		Inputs []ethabi.Argument
+	}
+	if err := json.NewDecoder(abiJSON).Decode(&entries); err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, e := range entries {
+		if e.Type != "error" {
+			continue
+		}
+		m := ethabi.NewMethod(e.Name, e.Name, ethabi.Function, "", false, false, e.Inputs, nil)
+		d.errors[string(m.ID)] = appendMethod(d.errors[string(m.ID)], m)
+	}
+	return nil
+}
+
+// AddErrorSignature registers a custom error by its canonical signature, e.g.
+// "InsufficientBalance(uint256,uint256)" or
+// "error InsufficientBalance(uint256 available, uint256 required)".
+func (d *Decoder) AddErrorSignature(sig string) error {
+	m, err := parseFunction(sig, "error")
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.errors[string(m.ID)] = appendMethod(d.errors[string(m.ID)], m)
+	return nil
+}
+
+// DecodeRevert decodes Error(string) and Panic(uint256) revert data.
+func DecodeRevert(data []byte) *RevertError {
+	return NewDecoder().DecodeRevert(data)
+}
+
+// DecodeRevert decodes revert data into a RevertError. Besides Error(string)
+// and Panic(uint256) it knows the custom errors registered on the decoder.
+// Data that cannot be decoded is reported as hex.
+func (d *Decoder) DecodeRevert(data []byte) *RevertError {
+	res := &RevertError{Data: utils.CopyBytes(data)}
+	if len(data) == 0 {
+		return res
+	}
+	if len(data) < 4 {
+		res.Reason = utils.BytesToHexString(data)
+		return res
+	}
+
+	switch {
+	case bytes.Equal(data[:4], errorSelector):
+		if reason, err := ethabi.UnpackRevert(data); err == nil {
+			res.Name, res.Signature, res.Reason = "Error", "Error(string)", reason
+			return res
+		}
+	case bytes.Equal(data[:4], panicSelector) && len(data) == 36:
+		code := new(big.Int).SetBytes(data[4:])
+		res.Name, res.Signature, res.PanicCode = "Panic", "Panic(uint256)", code
+		res.Reason = fmt.Sprintf("panic 0x%x (%s)", code, PanicReason(code))
+		return res
+	}
+
+	d.mu.RLock()
+	candidates := d.errors[string(data[:4])]
+	d.mu.RUnlock()
+	for i := range candidates {
+		m := &candidates[i]
+		args, err := decodeArguments(m.Inputs, data[4:])
+		if err != nil {
+			continue
+		}
+		parts := make([]string, len(m.Inputs))
+		for j, input := range m.Inputs {
+			key := argumentKey(input.Name, j)
+			parts[j] = fmt.Sprintf("%s=%v", key, args[key])
+			if input.Name == "" {
+				parts[j] = fmt.Sprintf("%v", args[key])
+			}
+		}
+		res.Name, res.Signature, res.Args = m.RawName, m.Sig, args
+		res.Reason = fmt.Sprintf("%s(%s)", m.RawName, strings.Join(parts, ", "))
+		return res
+	}
+	res.Reason = utils.BytesToHexString(data)
+	return res
+}
+
+// TransactionInfoError returns the reason a contract transaction failed, or
+// nil if it succeeded.
+func TransactionInfoError(info *core.TransactionInfo) error {
+	return NewDecoder().TransactionInfoError(info)
+}
+
+// TransactionInfoError returns the reason a contract transaction failed, or
+// nil if it succeeded. The revert data in contractResult is decoded, falling
+// back to resMessage when there is none.
+func (d *Decoder) TransactionInfoError(info *core.TransactionInfo) error {
+	if info.GetResult() != core.TransactionInfo_FAILED {
+		return nil
+	}
+	if res := info.GetContractResult(); len(res) > 0 && len(res[0]) > 0 {
+		return d.DecodeRevert(res[0])
+	}
+	return d.messageError(info.GetResMessage())
+}
+
+// TransactionExtentionError returns the reason a constant or estimated
+// contract call failed, or nil if it succeeded.
+func TransactionExtentionError(ext *api.TransactionExtention) error {
+	return NewDecoder().TransactionExtentionError(ext)
+}
+
+// TransactionExtentionError returns the reason a constant or estimated
+// contract call failed, or nil if it succeeded. A call counts as failed if
+// the node reports an error, the contract result of the transaction is not
+// SUCCESS, which java-tron uses for reverts with result true, or
+// constant_result holds Error, Panic or registered custom error data.
+func (d *Decoder) TransactionExtentionError(ext *api.TransactionExtention) error {
+	var data []byte
+	if res := ext.GetConstantResult(); len(res) > 0 {
+		data = res[0]
+	}
+	if ret := ext.GetResult(); ret != nil && !ret.GetResult() {
+		if len(data) > 0 {
+			return d.DecodeRevert(data)
+		}
+		return d.messageError(ret.GetMessage())
+	}
+	if ret := ext.GetTransaction().GetRet(); len(ret) > 0 {
+		switch code := ret[0].GetContractRet(); code {
+		case core.Transaction_Result_DEFAULT, core.Transaction_Result_SUCCESS:
+		default:
+			if len(data) > 0 {
+				return d.DecodeRevert(data)
+			}
+			return &RevertError{Reason: code.String()}
+		}
+	}
+	// Revert data is a selector followed by words, unlike return data
+	if len(data)%32 == 4 && d.isRevertSelector(data[:4]) {
+		if rerr := d.DecodeRevert(data); rerr.Name != "" {
+			return rerr
+		}
+	}
+	return nil
+}
+
+// isRevertSelector reports whether selector belongs to Error, Panic or a
+// registered custom error.
+func (d *Decoder) isRevertSelector(selector []byte) bool {
+	if bytes.Equal(selector, errorSelector) || bytes.Equal(selector, panicSelector) {
+		return true
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return len(d.errors[string(selector)]) > 0
+}
+
+// messageError turns a node result message into a RevertError. The message
+// is either text or raw revert data.
+func (d *Decoder) messageError(msg []byte) *RevertError {
+	if len(msg) >= 4 && !isPrintable(msg) {
+		return d.DecodeRevert(msg)
+	}
+	return &RevertError{Reason: string(msg), Data: utils.CopyBytes(msg)}
+}
+
+// isPrintable reports whether b is valid utf8 text without control characters.
+func isPrintable(b []byte) bool {
+	if !utf8.Valid(b) {
+		return false
+	}
+	for _, r := range string(b) {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,296 @@
+package abi
+
+import (
+	"fmt"
+	"strings"
+
+	ethabi "github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+// splitTopLevel splits s on the commas that are not nested in parentheses.
+func splitTopLevel(s string) ([]string, error) {
+	var (
+		parts []string
+		depth int
+		start int
+	)
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("unbalanced parentheses in %q", s)
+			}
+		case ',':
+			if depth == 0 {
+				parts = append(parts, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("unbalanced parentheses in %q", s)
+	}
+	if last := strings.TrimSpace(s[start:]); last != "" || len(parts) > 0 {
+		parts = append(parts, last)
+	}
+	for _, p := range parts {
+		if p == "" {
+			return nil, fmt.Errorf("empty type in %q", s)
+		}
+	}
+	return parts, nil
+}
+
+// closingParen returns the index of the parenthesis closing the one at open.
+func closingParen(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// dataLocations are the solidity data location keywords that may appear
+// between a parameter type and its name.
+var dataLocations = map[string]bool{"memory": true, "calldata": true, "storage": true}
+
+// splitTypeName splits a parameter declaration such as "address to" or
+// "(uint256 a,bool b)[] list" into its type and the words following it.
+func splitTypeName(s string) (string, []string, error) {
+	s = trimTupleKeyword(strings.TrimSpace(s))
+	if s == "" {
+		return "", nil, fmt.Errorf("empty parameter")
+	}
+	if !strings.HasPrefix(s, "(") {
+		fields := strings.Fields(s)
+		return fields[0], fields[1:], nil
+	}
+	end := closingParen(s, 0)
+	if end < 0 {
+		return "", nil, fmt.Errorf("unbalanced parentheses in %q", s)
+	}
+	i := end + 1
+	for i < len(s) && s[i] != ' ' && s[i] != '\t' {
+		i++
+	}
+	return s[:i], strings.Fields(s[i:]), nil
+}
+
+// trimTupleKeyword strips the optional "tuple" keyword of "tuple(...)".
+func trimTupleKeyword(s string) string {
+	if strings.HasPrefix(s, "tuple(") {
+		return s[len("tuple"):]
+	}
+	return s
+}
+
+// parseParam parses a parameter declaration, a solidity type optionally
+// followed by "indexed", a data location and a name.
+func parseParam(s string) (ethabi.ArgumentMarshaling, error) {
+	typ, words, err := splitTypeName(s)
+	if err != nil {
+		return ethabi.ArgumentMarshaling{}, err
+	}
+	m, err := parseTypeMarshaling(typ)
+	if err != nil {
+		return ethabi.ArgumentMarshaling{}, err
+	}
+	for i, w := range words {
+		switch {
+		case w == "indexed":
+			m.Indexed = true
+		case dataLocations[w]:
+		case i == len(words)-1:
+			m.Name = w
+		default:
+			return ethabi.ArgumentMarshaling{}, fmt.Errorf("invalid parameter %q", s)
+		}
+	}
+	return m, nil
+}
+
+// parseTypeMarshaling parses a solidity type, where tuples are written in
+// their canonical form, e.g. "(address,uint256)[]", optionally with names
+// for the components.
+func parseTypeMarshaling(s string) (ethabi.ArgumentMarshaling, error) {
+	s = trimTupleKeyword(strings.TrimSpace(s))
+	if !strings.HasPrefix(s, "(") {
+		return ethabi.ArgumentMarshaling{Type: normalizeType(s)}, nil
+	}
+
+	end := closingParen(s, 0)
+	if end < 0 {
+		return ethabi.ArgumentMarshaling{}, fmt.Errorf("unbalanced parentheses in %q", s)
+	}
+	parts, err := splitTopLevel(s[1:end])
+	if err != nil {
+		return ethabi.ArgumentMarshaling{}, err
+	}
+	components := make([]ethabi.ArgumentMarshaling, len(parts))
+	for i, p := range parts {
+		c, err := parseParam(p)
+		if err != nil {
+			return ethabi.ArgumentMarshaling{}, err
+		}
+		if c.Name == "" {
+			// go-ethereum maps tuple fields to struct fields by name, so
+			// anonymous components need one
+			c.Name = anonymousFieldName(i)
+		}
+		components[i] = c
+	}
+	return ethabi.ArgumentMarshaling{Type: "tuple" + s[end+1:], Components: components}, nil
+}
+
+// anonymousFieldName is the name given to the i-th unnamed tuple component.
+func anonymousFieldName(i int) string {
+	return fmt.Sprintf("field%d", i)
+}
+
+// parseType parses a solidity type string into an abi type.
+func parseType(s string) (ethabi.Type, error) {
+	m, err := parseTypeMarshaling(s)
+	if err != nil {
+		return ethabi.Type{}, err
+	}
+	return ethabi.NewType(m.Type, "", m.Components)
+}
+
+// parseArguments parses a list of parameter declarations.
+func parseArguments(params []string) (ethabi.Arguments, error) {
+	args := make(ethabi.Arguments, len(params))
+	for i, p := range params {
+		m, err := parseParam(p)
+		if err != nil {
+			return nil, err
+		}
+		ty, err := ethabi.NewType(m.Type, "", m.Components)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = ethabi.Argument{Name: m.Name, Type: ty, Indexed: m.Indexed}
+	}
+	return args, nil
+}
+
+// functionModifiers are the modifiers of a function declaration that do not
+// change its abi.
+var functionModifiers = map[string]bool{"public": true, "external": true, "virtual": true, "override": true}
+
+// ParseMethod builds a method from a human-readable signature, so it can be
+// used with Pack and DecodeOutputsMap without a JSON ABI. Canonical
+// signatures and solidity style declarations are both accepted:
+//
+//	transfer(address,uint256)
+//	transfer(address,uint256) returns (bool)
+//	function transfer(address to, uint256 amount) external returns (bool)
+//	function swap((address token, uint256 amount)[] path) payable
+//
+// Tuples are written in parentheses, optionally prefixed with "tuple". The
+// state mutability is taken from the view, pure, payable and nonpayable
+// modifiers.
+func ParseMethod(sig string) (*ethabi.Method, error) {
+	m, err := parseFunction(sig, "function")
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// parseFunction parses a function-like declaration, optionally starting with
+// keyword, e.g. "function" or "error".
+func parseFunction(sig, keyword string) (ethabi.Method, error) {
+	s := strings.TrimSpace(sig)
+	if strings.HasPrefix(s, keyword+" ") {
+		s = strings.TrimSpace(s[len(keyword):])
+	}
+	open := strings.Index(s, "(")
+	if open <= 0 {
+		return ethabi.Method{}, fmt.Errorf("invalid signature %q: missing name or parameters", sig)
+	}
+	name := strings.TrimSpace(s[:open])
+	if !isIdentifier(name) {
+		return ethabi.Method{}, fmt.Errorf("invalid signature %q: invalid name %q", sig, name)
+	}
+	end := closingParen(s, open)
+	if end < 0 {
+		return ethabi.Method{}, fmt.Errorf("invalid signature %q: unbalanced parentheses", sig)
+	}
+	inputs, err := parseParamList(s[open+1 : end])
+	if err != nil {
+		return ethabi.Method{}, fmt.Errorf("invalid signature %q: %v", sig, err)
+	}
+
+	var (
+		outputs    ethabi.Arguments
+		mutability string
+		rest       = strings.TrimSpace(s[end+1:])
+	)
+	for rest != "" {
+		if strings.HasPrefix(rest, "returns") {
+			after := strings.TrimSpace(rest[len("returns"):])
+			e := -1
+			if strings.HasPrefix(after, "(") {
+				e = closingParen(after, 0)
+			}
+			if e < 0 || outputs != nil {
+				return ethabi.Method{}, fmt.Errorf("invalid signature %q: invalid returns clause", sig)
+			}
+			if outputs, err = parseParamList(after[1:e]); err != nil {
+				return ethabi.Method{}, fmt.Errorf("invalid signature %q: returns: %v", sig, err)
+			}
+			rest = strings.TrimSpace(after[e+1:])
+			continue
+		}
+		word := strings.Fields(rest)[0]
+		rest = strings.TrimSpace(rest[len(word):])
+		switch {
+		case word == "view" || word == "pure" || word == "payable" || word == "nonpayable":
+			if mutability != "" {
+				return ethabi.Method{}, fmt.Errorf("invalid signature %q: conflicting modifiers %s and %s", sig, mutability, word)
+			}
+			mutability = word
+		case functionModifiers[word]:
+		default:
+			return ethabi.Method{}, fmt.Errorf("invalid signature %q: unknown modifier %q", sig, word)
+		}
+	}
+	isConst := mutability == "view" || mutability == "pure"
+	return ethabi.NewMethod(name, name, ethabi.Function, mutability, isConst, mutability == "payable", inputs, outputs), nil
+}
+
+// parseParamList parses the comma separated parameters between parentheses.
+func parseParamList(s string) (ethabi.Arguments, error) {
+	parts, err := splitTopLevel(s)
+	if err != nil {
+		return nil, err
+	}
+	return parseArguments(parts)
+}
+
+// isIdentifier reports whether s is a valid solidity identifier.
+func isIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		switch {
+		case c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
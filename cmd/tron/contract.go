@@ -0,0 +1,241 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bytejedi/tron-sdk-go/abi"
+	"github.com/bytejedi/tron-sdk-go/keystore"
+	"github.com/bytejedi/tron-sdk-go/proto/api"
+	"github.com/bytejedi/tron-sdk-go/proto/core"
+	"github.com/bytejedi/tron-sdk-go/proto/core/contract"
+	ethabi "github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+var (
+	trc20Decimals = mustParseMethod("decimals() view returns (uint8)")
+	trc20Transfer = mustParseMethod("transfer(address to, uint256 value) returns (bool)")
+)
+
+func mustParseMethod(sig string) *ethabi.Method {
+	m, err := abi.ParseMethod(sig)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func callFlags(c *cli, fs *flag.FlagSet) {
+	fs.StringVar(&c.from, "from", "", "address to call from, the contract itself if empty")
+}
+
+func triggerFlags(c *cli, fs *flag.FlagSet) {
+	sendFlags(c, fs)
+	fs.StringVar(&c.feeLimit, "feelimit", "100", "maximum TRX to burn for energy")
+	fs.StringVar(&c.value, "value", "0", "TRX to send along with the call")
+}
+
+func sendTRC20Flags(c *cli, fs *flag.FlagSet) {
+	triggerFlags(c, fs)
+	decimalsFlag(c, fs)
+}
+
+// callJSON is the output of call.
+type callJSON struct {
+	Method  string                 `json:"method"`
+	Outputs map[string]interface{} `json:"outputs,omitempty"`
+	Result  string                 `json:"result"`
+}
+
+// method parses a method signature argument. Without a returns clause the
+// outputs are looked up in the ABI the contract was deployed with, if any.
+func (c *cli) method(contractAddr keystore.Address, sig string) (*ethabi.Method, error) {
+	m, err := abi.ParseMethod(sig)
+	if err != nil || strings.Contains(sig, "returns") {
+		return m, err
+	}
+	ctx, cancel := c.context()
+	defer cancel()
+	sc, err := c.client.GetContract(ctx, &api.BytesMessage{Value: contractAddr.Bytes()})
+	if err != nil {
+		return nil, err
+	}
+	a, _, err := abi.ProtoToABI(sc.GetAbi())
+	if err != nil {
+		return nil, fmt.Errorf("ABI of %s: %v", contractAddr, err)
+	}
+	for _, known := range a.Methods {
+		if known.Sig == m.Sig {
+			return &known, nil
+		}
+	}
+	return m, nil
+}
+
+// constantCall calls method of a contract without creating a transaction.
+func (c *cli) constantCall(owner, contractAddr keystore.Address, method *ethabi.Method, params string) ([]byte, error) {
+	data, err := abi.Pack(method, params)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := c.context()
+	defer cancel()
+	ext, err := c.client.TriggerConstantContract(ctx, &contract.TriggerSmartContract{
+		OwnerAddress:    owner.Bytes(),
+		ContractAddress: contractAddr.Bytes(),
+		Data:            data,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := abi.TransactionExtentionError(ext); err != nil {
+		return nil, err
+	}
+	if res := ext.GetConstantResult(); len(res) > 0 {
+		return res[0], nil
+	}
+	return nil, nil
+}
+
+func (c *cli) call(fs *flag.FlagSet) error {
+	contractAddr, err := parseAddress("contract", fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	owner := contractAddr
+	if c.from != "" {
+		if owner, err = parseAddress("caller", c.from); err != nil {
+			return err
+		}
+	}
+	method, err := c.method(contractAddr, fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	result, err := c.constantCall(owner, contractAddr, method, fs.Arg(2))
+	if err != nil {
+		return err
+	}
+
+	out := callJSON{Method: method.Sig, Result: hex.EncodeToString(result)}
+	rows := [][]string{{"method", out.Method}}
+	if len(method.Outputs) > 0 {
+		if out.Outputs, err = abi.DecodeOutputsMap(method, result); err != nil {
+			return fmt.Errorf("decoding result %x: %v", result, err)
+		}
+		keys := make([]string, 0, len(out.Outputs))
+		for k := range out.Outputs {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			rows = append(rows, []string{k, outputString(out.Outputs[k])})
+		}
+	} else {
+		rows = append(rows, []string{"result", out.Result})
+	}
+	return c.print(out, rows)
+}
+
+// outputString formats a decoded output for a table, strings as is and
+// lists and tuples as JSON.
+func outputString(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// triggerSettings parses the -value and -feelimit flags into sun.
+func (c *cli) triggerSettings() (value, feeLimit int64, err error) {
+	if value, err = parseAmount64(c.value, trxDecimals); err != nil {
+		return 0, 0, err
+	}
+	if feeLimit, err = parseAmount64(c.feeLimit, trxDecimals); err != nil {
+		return 0, 0, err
+	}
+	if feeLimit == 0 {
+		return 0, 0, errors.New("need a -feelimit above zero")
+	}
+	return value, feeLimit, nil
+}
+
+// triggerContract creates, signs and broadcasts a call of a contract method.
+func (c *cli) triggerContract(contractAddr keystore.Address, method *ethabi.Method, params string) error {
+	value, feeLimit, err := c.triggerSettings()
+	if err != nil {
+		return err
+	}
+	data, err := abi.Pack(method, params)
+	if err != nil {
+		return err
+	}
+	ks, from, err := c.sender()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := c.context()
+	defer cancel()
+	req := &contract.TriggerSmartContract{
+		OwnerAddress:    from.Address.Bytes(),
+		ContractAddress: contractAddr.Bytes(),
+		CallValue:       value,
+		Data:            data,
+	}
+	ext, err := c.client.TriggerContract(ctx, req)
+	return c.submit(ks, from, core.Transaction_Contract_TriggerSmartContract, req, ext, err, func(raw *core.TransactionRaw) {
+		raw.FeeLimit = feeLimit
+	})
+}
+
+func (c *cli) trigger(fs *flag.FlagSet) error {
+	contractAddr, err := parseAddress("contract", fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	method, err := abi.ParseMethod(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	return c.triggerContract(contractAddr, method, fs.Arg(2))
+}
+
+func (c *cli) sendTRC20(fs *flag.FlagSet) error {
+	contractAddr, err := parseAddress("contract", fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	to, err := parseAddress("recipient", fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	result, err := c.constantCall(contractAddr, contractAddr, trc20Decimals, "")
+	if err != nil {
+		return fmt.Errorf("decimals of %s: %v", contractAddr, err)
+	}
+	outputs, err := abi.DecodeOutputsMap(trc20Decimals, result)
+	if err != nil {
+		return fmt.Errorf("decimals of %s: %v", contractAddr, err)
+	}
+	reported, _ := strconv.Atoi(outputs["0"].(string))
+	decimals, err := c.tokenDecimals(contractAddr.String(), reported)
+	if err != nil {
+		return err
+	}
+	amount, err := parseAmount(fs.Arg(2), decimals)
+	if err != nil {
+		return err
+	}
+	params, _ := json.Marshal([]string{to.String(), amount.String()})
+	return c.triggerContract(contractAddr, trc20Transfer, string(params))
+}
@@ -0,0 +1,300 @@
+// Command tron queries and sends transactions to a TRON network over the gRPC
+// API of a full node: it shows accounts, resources, blocks and transactions,
+// sends TRX, TRC10 and TRC20 tokens, calls and triggers contracts, freezes,
+// unfreezes and votes, and broadcasts transactions signed elsewhere.
+//
+// The node is selected by a network profile, mainnet, shasta and nile being
+// built in. More profiles, or API keys for the built-in ones, are read from
+// the YAML file given with -config:
+//
+//	default: private
+//	networks:
+//	  private:
+//	    node: 10.0.0.1:50051
+//	  mainnet:
+//	    apikey: 00000000-0000-0000-0000-000000000000
+//
+// Transactions are signed with the accounts of a keystore directory, as
+// managed by tronkey. The passphrase is read from the first line of the file
+// given with -passfile or prompted for on the terminal. Amounts are given in
+// TRX or token units, e.g. 1.5, and converted with the decimals of the token.
+// Unless the profile uses TLS, the decimals the node reports have to be
+// confirmed with -decimals, since a node reporting more would inflate the
+// amount signed.
+// Results are printed as tables, or with -json as JSON for scripting.
+//
+// Usage:
+//
+//	tron [-network name] [-node host:port] [-json] <command> [flags] [args]
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bytejedi/tron-sdk-go/keystore"
+	"github.com/bytejedi/tron-sdk-go/proto/api"
+	"golang.org/x/crypto/ssh/terminal"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// command is a tron subcommand.
+type command struct {
+	usage string // Arguments following the flags, optional ones in brackets
+	help  string
+	run   func(c *cli, fs *flag.FlagSet) error
+	flags func(c *cli, fs *flag.FlagSet)
+}
+
+var commands = map[string]*command{
+	"account":    {"<address>", "Show the balances, frozen TRX and votes of an account", (*cli).account, nil},
+	"resources":  {"<address>", "Show the bandwidth and energy of an account", (*cli).resources, nil},
+	"block":      {"[number]", "Show a block, the latest one without number", (*cli).block, nil},
+	"tx":         {"<txid>", "Show a transaction and its receipt", (*cli).tx, nil},
+	"send":       {"<to> <amount>", "Send TRX", (*cli).send, sendFlags},
+	"send-trc10": {"<token-id> <to> <amount>", "Send a TRC10 token", (*cli).sendTRC10, sendTokenFlags},
+	"send-trc20": {"<contract> <to> <amount>", "Send a TRC20 token", (*cli).sendTRC20, sendTRC20Flags},
+	"call":       {"<contract> <method> [params]", "Call a constant contract method", (*cli).call, callFlags},
+	"trigger":    {"<contract> <method> [params]", "Send a transaction calling a contract method", (*cli).trigger, triggerFlags},
+	"freeze":     {"<amount>", "Freeze TRX for bandwidth or energy", (*cli).freeze, freezeFlags},
+	"unfreeze":   {"", "Unfreeze TRX frozen for bandwidth or energy", (*cli).unfreeze, unfreezeFlags},
+	"vote":       {"<witness>=<votes> ...", "Vote for witnesses, replacing all previous votes", (*cli).vote, sendFlags},
+	"broadcast":  {"<txfile>", "Broadcast a signed JSON transaction", (*cli).broadcast, nil},
+}
+
+func main() {
+	c := &cli{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr, dial: dialNetwork}
+	if terminal.IsTerminal(int(os.Stdin.Fd())) {
+		c.readPassword = func(prompt string) (string, error) {
+			fmt.Fprint(os.Stderr, prompt)
+			defer fmt.Fprintln(os.Stderr)
+			pass, err := terminal.ReadPassword(int(os.Stdin.Fd()))
+			return string(pass), err
+		}
+	}
+	if err := c.run(os.Args[1:]); err != nil {
+		if err != flag.ErrHelp {
+			fmt.Fprintln(os.Stderr, "tron:", err)
+		}
+		os.Exit(2)
+	}
+}
+
+// cli holds the settings, connection and I/O of a tron invocation.
+type cli struct {
+	stdin          io.Reader
+	stdout, stderr io.Writer
+
+	// readPassword prompts for a passphrase, nil without a terminal
+	readPassword func(prompt string) (string, error)
+	// dial connects to the node of a network
+	dial func(n *network) (*grpc.ClientConn, error)
+
+	network     *network
+	client      api.WalletClient
+	keystoreDir string
+	timeout     time.Duration
+	json        bool
+
+	// Command flags
+	from     string // Sending account
+	passFile string // Passphrase file
+	signOnly bool   // Print the signed transaction instead of broadcasting it
+	feeLimit string // Fee limit of contract calls in TRX
+	value    string // TRX sent along with a contract call
+	resource string // Resource to freeze or unfreeze
+	duration int64  // Freeze duration in days
+	receiver string // Account receiving the frozen resource
+	decimals int    // Decimals of the token sent, -1 if not given
+}
+
+// run parses the global flags, connects to the node and runs the command of
+// args.
+func (c *cli) run(args []string) error {
+	fs := flag.NewFlagSet("tron", flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	config := fs.String("config", defaultConfig(), "YAML file with network profiles")
+	name := fs.String("network", os.Getenv("TRON_NETWORK"), "network profile, the default of the config file or mainnet if empty")
+	node := fs.String("node", "", "gRPC address of the node, overriding the one of the profile")
+	fs.StringVar(&c.keystoreDir, "keystore", defaultKeystore(), "keystore directory")
+	fs.DurationVar(&c.timeout, "timeout", 30*time.Second, "timeout of each request to the node")
+	fs.BoolVar(&c.json, "json", false, "print results as JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(c.stderr, "Usage: tron [flags] <command> [command flags] [args]\n\nFlags:")
+		fs.PrintDefaults()
+		fmt.Fprintln(c.stderr, "\nCommands:")
+		names := make([]string, 0, len(commands))
+		for name := range commands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(c.stderr, "  %-12s %s\n", name, commands[name].help)
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	cmdName := fs.Arg(0)
+	cmd, ok := commands[cmdName]
+	if !ok {
+		return fmt.Errorf("unknown command %q", cmdName)
+	}
+	cfs := flag.NewFlagSet(cmdName, flag.ContinueOnError)
+	cfs.SetOutput(c.stderr)
+	if cmd.flags != nil {
+		cmd.flags(c, cfs)
+	}
+	cfs.Usage = func() {
+		fmt.Fprintf(c.stderr, "Usage: tron %s [flags] %s\n\n%s.\n", cmdName, cmd.usage, cmd.help)
+		cfs.PrintDefaults()
+	}
+	if err := cfs.Parse(fs.Args()[1:]); err != nil {
+		return err
+	}
+	if min, max := argCount(cmd.usage); cfs.NArg() < min || (max >= 0 && cfs.NArg() > max) {
+		cfs.Usage()
+		return fmt.Errorf("%s takes %s arguments, have %d", cmdName, argRange(min, max), cfs.NArg())
+	}
+
+	n, err := loadNetwork(*config, *name)
+	if err != nil {
+		return err
+	}
+	if *node != "" {
+		n.Node = *node
+	}
+	c.network = n
+	conn, err := c.dial(n)
+	if err != nil {
+		return fmt.Errorf("connecting to %s: %v", n.Node, err)
+	}
+	defer conn.Close()
+	c.client = api.NewWalletClient(conn)
+	return cmd.run(c, cfs)
+}
+
+// argCount returns the minimum and maximum number of arguments of a usage
+// string, the maximum being -1 if the last argument repeats.
+func argCount(usage string) (min, max int) {
+	for _, arg := range strings.Fields(usage) {
+		switch {
+		case arg == "...":
+			return min, -1
+		case strings.HasPrefix(arg, "["):
+		default:
+			min++
+		}
+		max++
+	}
+	return min, max
+}
+
+func argRange(min, max int) string {
+	switch {
+	case max < 0:
+		return fmt.Sprintf("at least %d", min)
+	case min == max:
+		return fmt.Sprint(min)
+	}
+	return fmt.Sprintf("%d to %d", min, max)
+}
+
+// context returns the context of a request to the node, carrying the API key
+// of the network.
+func (c *cli) context() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	if c.network.APIKey != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "tron-pro-api-key", c.network.APIKey)
+	}
+	return ctx, cancel
+}
+
+// tronDir returns the directory of the tron and tronkey settings.
+func tronDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".tron")
+}
+
+// defaultConfig returns the network profile file used without -config.
+func defaultConfig() string {
+	if file := os.Getenv("TRON_CONFIG"); file != "" {
+		return file
+	}
+	return filepath.Join(tronDir(), "networks.yaml")
+}
+
+// defaultKeystore returns the keystore directory used without -keystore, the
+// same as tronkey's.
+func defaultKeystore() string {
+	if dir := os.Getenv("TRONKEY_KEYSTORE"); dir != "" {
+		return dir
+	}
+	return filepath.Join(tronDir(), "keystore")
+}
+
+// passphrase reads the passphrase from -passfile or the terminal.
+func (c *cli) passphrase(prompt string) (string, error) {
+	if c.passFile != "" {
+		data, err := readInput(c.stdin, c.passFile)
+		if err != nil {
+			return "", err
+		}
+		return firstLine(data), nil
+	}
+	if c.readPassword == nil {
+		return "", errors.New("no terminal to read the passphrase from, use -passfile")
+	}
+	return c.readPassword(prompt + ": ")
+}
+
+// readInput reads a file, or stdin if the name is "-".
+func readInput(stdin io.Reader, name string) ([]byte, error) {
+	if name == "-" {
+		return ioutil.ReadAll(stdin)
+	}
+	return ioutil.ReadFile(name)
+}
+
+// firstLine returns the first line of data without line terminator.
+func firstLine(data []byte) string {
+	s := string(data)
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSuffix(s, "\r")
+}
+
+// sender resolves the -from flag to an account of the keystore.
+func (c *cli) sender() (*keystore.KeyStore, keystore.Account, error) {
+	if c.from == "" {
+		return nil, keystore.Account{}, errors.New("need -from")
+	}
+	addr, err := keystore.ParseAddress(c.from)
+	if err != nil {
+		return nil, keystore.Account{}, err
+	}
+	ks := keystore.NewKeyStore(c.keystoreDir, keystore.StandardScryptN, keystore.StandardScryptP)
+	a, err := ks.Find(keystore.Account{Address: addr})
+	if err != nil {
+		return nil, keystore.Account{}, fmt.Errorf("%s: %w", addr, err)
+	}
+	return ks, a, nil
+}
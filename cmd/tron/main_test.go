@@ -0,0 +1,359 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bytejedi/tron-sdk-go/abi"
+	"github.com/bytejedi/tron-sdk-go/keystore"
+	"github.com/bytejedi/tron-sdk-go/proto/api"
+	"github.com/bytejedi/tron-sdk-go/proto/core"
+	"github.com/bytejedi/tron-sdk-go/proto/core/contract"
+	"github.com/bytejedi/tron-sdk-go/transaction"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+)
+
+// fakeNode is a full node answering the requests of the tests.
+type fakeNode struct {
+	api.UnimplementedWalletServer
+
+	mu        sync.Mutex
+	account   *core.Account
+	broadcast []*core.Transaction
+	redirect  []byte // Recipient swapped into the transfers the node builds
+}
+
+func (n *fakeNode) GetAccount(ctx context.Context, in *core.Account) (*core.Account, error) {
+	if bytes.Equal(in.GetAddress(), n.account.GetAddress()) {
+		return n.account, nil
+	}
+	return &core.Account{}, nil
+}
+
+// build wraps a contract into a transaction like the node does.
+func build(ty core.Transaction_Contract_ContractType, c proto.Message) (*api.TransactionExtention, error) {
+	tx, err := transaction.New(ty, c)
+	if err != nil {
+		return nil, err
+	}
+	blockID := sha256.Sum256([]byte("block"))
+	if err := transaction.SetReference(tx, blockID[:], transaction.DefaultExpiration); err != nil {
+		return nil, err
+	}
+	id, err := transaction.ID(tx)
+	if err != nil {
+		return nil, err
+	}
+	return &api.TransactionExtention{Transaction: tx, Txid: id, Result: &api.Return{Result: true}}, nil
+}
+
+func (n *fakeNode) CreateTransaction2(ctx context.Context, in *contract.TransferContract) (*api.TransactionExtention, error) {
+	if n.redirect != nil {
+		in = proto.Clone(in).(*contract.TransferContract)
+		in.ToAddress = n.redirect
+	}
+	return build(core.Transaction_Contract_TransferContract, in)
+}
+
+func (n *fakeNode) TriggerContract(ctx context.Context, in *contract.TriggerSmartContract) (*api.TransactionExtention, error) {
+	return build(core.Transaction_Contract_TriggerSmartContract, in)
+}
+
+// TriggerConstantContract returns 6 for decimals() and 42 for any other call.
+func (n *fakeNode) TriggerConstantContract(ctx context.Context, in *contract.TriggerSmartContract) (*api.TransactionExtention, error) {
+	result := math.U256Bytes(big.NewInt(42))
+	if bytes.HasPrefix(in.GetData(), trc20Decimals.ID) {
+		result = math.U256Bytes(big.NewInt(6))
+	}
+	return &api.TransactionExtention{ConstantResult: [][]byte{result}, Result: &api.Return{Result: true}}, nil
+}
+
+func (n *fakeNode) BroadcastTransaction(ctx context.Context, in *core.Transaction) (*api.Return, error) {
+	id, err := transaction.ID(in)
+	if err != nil {
+		return nil, err
+	}
+	for _, sig := range in.GetSignature() {
+		if _, err := crypto.SigToPub(id, sig); err != nil {
+			return &api.Return{Code: api.Return_SIGERROR, Message: []byte(err.Error())}, nil
+		}
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.broadcast = append(n.broadcast, in)
+	return &api.Return{Result: true}, nil
+}
+
+// last returns the contract of the last broadcast transaction and its
+// signer.
+func (n *fakeNode) last(t *testing.T, c proto.Message) keystore.Address {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if len(n.broadcast) == 0 {
+		t.Fatal("nothing broadcast")
+	}
+	tx := n.broadcast[len(n.broadcast)-1]
+	if err := tx.GetRawData().GetContract()[0].GetParameter().UnmarshalTo(c); err != nil {
+		t.Fatal(err)
+	}
+	id, _ := transaction.ID(tx)
+	pub, err := crypto.SigToPub(id, tx.GetSignature()[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keystore.PubkeyToAddress(*pub)
+}
+
+// startNode serves node on an in-memory listener, returning the dial
+// function of the cli.
+func startNode(t *testing.T, node *fakeNode) (func(*network) (*grpc.ClientConn, error), func()) {
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	api.RegisterWalletServer(srv, node)
+	go srv.Serve(lis)
+	dial := func(n *network) (*grpc.ClientConn, error) {
+		return grpc.Dial(n.Node, grpc.WithInsecure(), grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return lis.Dial()
+		}))
+	}
+	return dial, srv.Stop
+}
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadNetwork(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tron")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	n, err := loadNetwork(filepath.Join(dir, "missing.yaml"), "")
+	if err != nil || n.Name != "mainnet" || n.Node != builtinNetworks["mainnet"].Node {
+		t.Fatalf("without config: %+v, %v", n, err)
+	}
+	config := writeFile(t, dir, "networks.yaml", `
+default: private
+networks:
+  private:
+    node: 10.0.0.1:50051
+    tls: true
+  mainnet:
+    apikey: key
+`)
+	if n, err = loadNetwork(config, ""); err != nil || *n != (network{Name: "private", Node: "10.0.0.1:50051", TLS: true}) {
+		t.Errorf("default network: %+v, %v", n, err)
+	}
+	want := builtinNetworks["mainnet"]
+	want.Name, want.APIKey = "mainnet", "key"
+	if n, err = loadNetwork(config, "mainnet"); err != nil || *n != want {
+		t.Errorf("built-in network with API key: %+v, %v", n, err)
+	}
+	if _, err = loadNetwork(config, "devnet"); err == nil || !strings.Contains(err.Error(), "mainnet, nile, private, shasta") {
+		t.Errorf("unknown network: %v", err)
+	}
+	bad := writeFile(t, dir, "bad.yaml", "networks:\n  private:\n    host: 10.0.0.1:50051\n")
+	if _, err = loadNetwork(bad, "private"); err == nil {
+		t.Errorf("unknown setting accepted")
+	}
+}
+
+func TestAmounts(t *testing.T) {
+	for _, tt := range []struct {
+		in       string
+		decimals int
+		want     string
+	}{
+		{"1.5", 6, "1500000"},
+		{"1", 6, "1000000"},
+		{".000001", 6, "1"},
+		{"100", 0, "100"},
+		{"12345678901234567890.5", 18, "12345678901234567890500000000000000000"},
+	} {
+		n, err := parseAmount(tt.in, tt.decimals)
+		if err != nil || n.String() != tt.want {
+			t.Errorf("parseAmount(%q, %d) = %v, %v, want %s", tt.in, tt.decimals, n, err, tt.want)
+			continue
+		}
+		if back, _ := parseAmount(formatAmount(n, tt.decimals), tt.decimals); back.Cmp(n) != 0 {
+			t.Errorf("formatAmount(%s, %d) = %s", n, tt.decimals, formatAmount(n, tt.decimals))
+		}
+	}
+	for _, in := range []string{"", ".", "1.0000001", "-1", "+1", "1e6", "0x10", "1,5"} {
+		if n, err := parseAmount(in, 6); err == nil {
+			t.Errorf("parseAmount(%q) = %s, want error", in, n)
+		}
+	}
+	if _, err := parseAmount64("9223372036854.775808", 6); err == nil {
+		t.Errorf("amount overflowing int64 accepted")
+	}
+	if s := formatTRX(1500000); s != "1.5 TRX" {
+		t.Errorf("formatTRX(1500000) = %s", s)
+	}
+}
+
+func TestCommands(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tron")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ks := keystore.NewKeyStore(filepath.Join(dir, "keystore"), keystore.LightScryptN, keystore.LightScryptP)
+	a, err := ks.NewAccount("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pass := writeFile(t, dir, "pass", "secret\n")
+	key, _ := crypto.GenerateKey()
+	to := keystore.PubkeyToAddress(key.PublicKey)
+
+	node := &fakeNode{account: &core.Account{Address: a.Address.Bytes(), Balance: 2500000, AssetV2: map[string]int64{"1000001": 7}}}
+	dial, stop := startNode(t, node)
+	defer stop()
+
+	tron := func(out interface{}, args ...string) error {
+		var stdout, stderr bytes.Buffer
+		c := &cli{stdin: strings.NewReader(""), stdout: &stdout, stderr: &stderr, dial: dial}
+		args = append([]string{"-config", filepath.Join(dir, "none.yaml"), "-keystore", filepath.Join(dir, "keystore"), "-json"}, args...)
+		if err := c.run(args); err != nil {
+			return err
+		}
+		if out != nil {
+			if err := json.Unmarshal(stdout.Bytes(), out); err != nil {
+				t.Fatalf("%v: %v: %s", args, err, stdout.String())
+			}
+		}
+		return nil
+	}
+
+	var acc accountJSON
+	if err := tron(&acc, "account", a.Address.Hex()); err != nil {
+		t.Fatal(err)
+	}
+	if acc.Address != a.Address.String() || acc.Balance != 2500000 || acc.Assets["1000001"] != 7 {
+		t.Errorf("account: %+v", acc)
+	}
+	if err := tron(nil, "account", to.String()); err == nil {
+		t.Errorf("missing account found")
+	}
+
+	// TRX transfers are signed by the sender
+	var sent broadcastJSON
+	if err := tron(&sent, "send", "-from", a.Address.String(), "-passfile", pass, to.String(), "1.5"); err != nil {
+		t.Fatal(err)
+	}
+	var transfer contract.TransferContract
+	if signer := node.last(t, &transfer); signer != a.Address {
+		t.Errorf("signed by %s, want %s", signer, a.Address)
+	}
+	if !bytes.Equal(transfer.GetToAddress(), to.Bytes()) || transfer.GetAmount() != 1500000 {
+		t.Errorf("sent %d to %x", transfer.GetAmount(), transfer.GetToAddress())
+	}
+	if err := tron(nil, "send", "-from", to.String(), "-passfile", pass, a.Address.String(), "1"); !errors.Is(err, keystore.ErrNoMatch) {
+		t.Errorf("send from unknown account: %v", err)
+	}
+
+	// Transactions differing from the request are not signed
+	node.redirect = a.Address.Bytes()
+	count := len(node.broadcast)
+	if err := tron(nil, "send", "-from", a.Address.String(), "-passfile", pass, to.String(), "1.5"); err == nil {
+		t.Errorf("transfer to another recipient signed")
+	}
+	if err := tron(nil, "send", "-from", a.Address.String(), "-passfile", pass, "-sign-only", to.String(), "1.5"); err == nil {
+		t.Errorf("transfer to another recipient signed with -sign-only")
+	}
+	if len(node.broadcast) != count {
+		t.Errorf("transfer to another recipient broadcast")
+	}
+	node.redirect = nil
+
+	// TRC20 amounts are scaled by the decimals of the token, which have to
+	// be confirmed over plaintext connections
+	tokenKey, _ := crypto.GenerateKey()
+	token := keystore.PubkeyToAddress(tokenKey.PublicKey)
+	count = len(node.broadcast)
+	if err := tron(nil, "send-trc20", "-from", a.Address.String(), "-passfile", pass, token.String(), to.String(), "2.5"); err == nil {
+		t.Errorf("decimals of plaintext node trusted")
+	}
+	if err := tron(nil, "send-trc20", "-from", a.Address.String(), "-passfile", pass, "-decimals", "18", token.String(), to.String(), "2.5"); err == nil {
+		t.Errorf("decimals differing from -decimals accepted")
+	}
+	if len(node.broadcast) != count {
+		t.Errorf("transfer with unconfirmed decimals broadcast")
+	}
+	if err := tron(&sent, "send-trc20", "-from", a.Address.String(), "-passfile", pass, "-feelimit", "20", "-decimals", "6", token.String(), to.String(), "2.5"); err != nil {
+		t.Fatal(err)
+	}
+	var trigger contract.TriggerSmartContract
+	node.last(t, &trigger)
+	data, _ := abi.Pack(trc20Transfer, `["`+to.String()+`","2500000"]`)
+	if !bytes.Equal(trigger.GetContractAddress(), token.Bytes()) || !bytes.Equal(trigger.GetData(), data) {
+		t.Errorf("trc20 transfer %x to contract %x", trigger.GetData(), trigger.GetContractAddress())
+	}
+	if fee := node.broadcast[len(node.broadcast)-1].GetRawData().GetFeeLimit(); fee != 20000000 {
+		t.Errorf("fee limit %d, want 20000000", fee)
+	}
+
+	// Constant calls decode the outputs
+	var call callJSON
+	if err := tron(&call, "call", token.String(), "balanceOf(address) returns (uint256)", `["`+to.String()+`"]`); err != nil {
+		t.Fatal(err)
+	}
+	if call.Method != "balanceOf(address)" || call.Outputs["0"] != "42" {
+		t.Errorf("call: %+v", call)
+	}
+
+	// Transactions signed with -sign-only can be broadcast later
+	var signed signedTxJSON
+	if err := tron(&signed, "send", "-from", a.Address.String(), "-passfile", pass, "-sign-only", to.String(), "3"); err != nil {
+		t.Fatal(err)
+	}
+	count = len(node.broadcast)
+	if err := tron(&sent, "broadcast", writeFile(t, dir, "tx.json", signedTxString(t, signed))); err != nil {
+		t.Fatal(err)
+	}
+	if len(node.broadcast) != count+1 || sent.TxID != signed.TxID {
+		t.Errorf("broadcast %s, want %s", sent.TxID, signed.TxID)
+	}
+	signed.TxID = strings.Repeat("0", 64)
+	if err := tron(nil, "broadcast", writeFile(t, dir, "bad.json", signedTxString(t, signed))); err == nil {
+		t.Errorf("transaction with wrong txID broadcast")
+	}
+
+	if err := tron(nil, "vote", "-from", a.Address.String(), to.String()+"=1", to.Hex()+"=2"); err == nil {
+		t.Errorf("duplicate votes accepted")
+	}
+	if err := tron(nil, "block", "1", "2"); err == nil {
+		t.Errorf("extra argument accepted")
+	}
+}
+
+func signedTxString(t *testing.T, tx signedTxJSON) string {
+	b, err := json.Marshal(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
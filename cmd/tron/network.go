@@ -0,0 +1,104 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"gopkg.in/yaml.v2"
+)
+
+// network is a profile of the node to connect to.
+type network struct {
+	Name   string `yaml:"-"`
+	Node   string `yaml:"node"`   // gRPC address, host:port
+	TLS    bool   `yaml:"tls"`    // Connect with TLS instead of plaintext
+	APIKey string `yaml:"apikey"` // TronGrid API key sent with every request
+}
+
+// builtinNetworks are the profiles available without a config file, using
+// the public TronGrid nodes. These only speak plaintext gRPC, so the answers
+// are not authenticated: transactions built by the node are checked against
+// the request before signing.
+var builtinNetworks = map[string]network{
+	"mainnet": {Node: "grpc.trongrid.io:50051"},
+	"shasta":  {Node: "grpc.shasta.trongrid.io:50051"},
+	"nile":    {Node: "grpc.nile.trongrid.io:50051"},
+}
+
+// networkConfig is the format of the network profile file.
+type networkConfig struct {
+	Default  string             `yaml:"default"`
+	Networks map[string]network `yaml:"networks"`
+}
+
+// loadNetwork returns the named profile from the built-in ones and those of
+// the config file, which may be missing. Settings of the file left empty
+// keep the values of a built-in profile of the same name. An empty name
+// selects the default of the file, or mainnet.
+func loadNetwork(file, name string) (*network, error) {
+	var config networkConfig
+	data, err := ioutil.ReadFile(file)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return nil, err
+	default:
+		if err := yaml.UnmarshalStrict(data, &config); err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+	}
+	if name == "" {
+		name = config.Default
+	}
+	if name == "" {
+		name = "mainnet"
+	}
+
+	n, builtin := builtinNetworks[name]
+	if custom, ok := config.Networks[name]; ok {
+		if custom.Node != "" {
+			n.Node = custom.Node
+			n.TLS = custom.TLS
+		}
+		if custom.APIKey != "" {
+			n.APIKey = custom.APIKey
+		}
+	} else if !builtin {
+		return nil, fmt.Errorf("unknown network %q, have %s", name, strings.Join(networkNames(config), ", "))
+	}
+	if n.Node == "" {
+		return nil, fmt.Errorf("network %q has no node", name)
+	}
+	n.Name = name
+	return &n, nil
+}
+
+// networkNames returns the sorted names of all profiles.
+func networkNames(config networkConfig) []string {
+	var names []string
+	for name := range builtinNetworks {
+		names = append(names, name)
+	}
+	for name := range config.Networks {
+		if _, ok := builtinNetworks[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// dialNetwork connects to the node of n.
+func dialNetwork(n *network) (*grpc.ClientConn, error) {
+	creds := grpc.WithInsecure()
+	if n.TLS {
+		creds = grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{}))
+	}
+	return grpc.Dial(n.Node, creds)
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bytejedi/tron-sdk-go/keystore"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// trxDecimals is the number of decimals of TRX, 1 TRX being 1000000 sun.
+const trxDecimals = 6
+
+// print writes v as JSON with -json or else rows as an aligned table.
+func (c *cli) print(v interface{}, rows [][]string) error {
+	if c.json {
+		enc := json.NewEncoder(c.stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	w := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
+
+// parseAmount converts a decimal amount in token units into base units, e.g.
+// "1.5" with 6 decimals into 1500000.
+func parseAmount(s string, decimals int) (*big.Int, error) {
+	whole, frac := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		whole, frac = s[:i], s[i+1:]
+	}
+	if len(frac) > decimals {
+		return nil, fmt.Errorf("invalid amount %q: more than %d decimals", s, decimals)
+	}
+	n, ok := new(big.Int).SetString(whole+frac+strings.Repeat("0", decimals-len(frac)), 10)
+	if !ok || whole == "" && frac == "" || strings.ContainsAny(s, "+-") {
+		return nil, fmt.Errorf("invalid amount %q", s)
+	}
+	return n, nil
+}
+
+// parseAmount64 is parseAmount for amounts that have to fit an int64, like
+// those of TRX and TRC10 tokens.
+func parseAmount64(s string, decimals int) (int64, error) {
+	n, err := parseAmount(s, decimals)
+	if err != nil {
+		return 0, err
+	}
+	if !n.IsInt64() {
+		return 0, fmt.Errorf("invalid amount %q: too large", s)
+	}
+	return n.Int64(), nil
+}
+
+// formatAmount converts base units into a decimal amount in token units
+// without trailing zeros.
+func formatAmount(n *big.Int, decimals int) string {
+	s := new(big.Int).Abs(n).String()
+	if len(s) <= decimals {
+		s = strings.Repeat("0", decimals-len(s)+1) + s
+	}
+	whole, frac := s[:len(s)-decimals], strings.TrimRight(s[len(s)-decimals:], "0")
+	if frac != "" {
+		whole += "." + frac
+	}
+	if n.Sign() < 0 {
+		whole = "-" + whole
+	}
+	return whole
+}
+
+// formatTRX formats an amount of sun as TRX.
+func formatTRX(sun int64) string {
+	return formatAmount(big.NewInt(sun), trxDecimals) + " TRX"
+}
+
+// formatTime formats a timestamp in milliseconds, as used on chain.
+func formatTime(ms int64) string {
+	if ms == 0 {
+		return "-"
+	}
+	return time.Unix(0, ms*int64(time.Millisecond)).UTC().Format(time.RFC3339)
+}
+
+// parseAddress parses an address argument, adding the argument name to
+// errors.
+func parseAddress(name, s string) (keystore.Address, error) {
+	addr, err := keystore.ParseAddress(s)
+	if err != nil {
+		return keystore.Address{}, fmt.Errorf("invalid %s %q: %v", name, s, err)
+	}
+	return addr, nil
+}
+
+// addressString formats an address in its base58 form, or as hex if it is
+// not a valid address.
+func addressString(b []byte) string {
+	var addr keystore.Address
+	if len(b) != len(addr) {
+		return hex.EncodeToString(b)
+	}
+	copy(addr[:], b)
+	if !addr.IsValid() {
+		return hex.EncodeToString(b)
+	}
+	return addr.String()
+}
+
+// parseTxID parses a hex encoded transaction id.
+func parseTxID(s string) ([]byte, error) {
+	id, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil || len(id) != 32 {
+		return nil, errors.New("invalid transaction id " + s)
+	}
+	return id, nil
+}
+
+// messageFields renders the populated fields of a contract message for
+// output, keyed by their proto names. Address fields are shown in base58,
+// names of assets and accounts as text and other bytes as hex.
+func messageFields(m protoreflect.Message) map[string]interface{} {
+	fields := make(map[string]interface{})
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		fields[string(fd.Name())] = fieldValue(fd, v)
+		return true
+	})
+	return fields
+}
+
+func fieldValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) interface{} {
+	if fd.IsList() {
+		list := v.List()
+		items := make([]interface{}, list.Len())
+		for i := range items {
+			items[i] = scalarValue(fd, list.Get(i))
+		}
+		return items
+	}
+	if fd.IsMap() {
+		items := make(map[string]interface{})
+		v.Map().Range(func(k protoreflect.MapKey, mv protoreflect.Value) bool {
+			items[k.String()] = scalarValue(fd.MapValue(), mv)
+			return true
+		})
+		return items
+	}
+	return scalarValue(fd, v)
+}
+
+func scalarValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) interface{} {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return messageFields(v.Message())
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return string(ev.Name())
+		}
+		return int32(v.Enum())
+	case protoreflect.BytesKind:
+		name := string(fd.Name())
+		switch {
+		case strings.HasSuffix(name, "address"):
+			return addressString(v.Bytes())
+		case strings.HasSuffix(name, "_name"):
+			return string(v.Bytes())
+		}
+		return hex.EncodeToString(v.Bytes())
+	}
+	return v.Interface()
+}
+
+// contractFields returns the fields of a contract message, see messageFields.
+func contractFields(m proto.Message) map[string]interface{} {
+	return messageFields(m.ProtoReflect())
+}
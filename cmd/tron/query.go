@@ -0,0 +1,315 @@
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/bytejedi/tron-sdk-go/abi"
+	"github.com/bytejedi/tron-sdk-go/proto/api"
+	"github.com/bytejedi/tron-sdk-go/proto/core"
+	"github.com/bytejedi/tron-sdk-go/transaction"
+)
+
+// accountJSON is the output of account, amounts are in sun.
+type accountJSON struct {
+	Address         string           `json:"address"`
+	Name            string           `json:"name,omitempty"`
+	Balance         int64            `json:"balance"`
+	FrozenBandwidth int64            `json:"frozenBandwidth"`
+	FrozenEnergy    int64            `json:"frozenEnergy"`
+	Assets          map[string]int64 `json:"assets"`
+	Votes           []voteJSON       `json:"votes"`
+	Created         int64            `json:"created"`
+	Witness         bool             `json:"witness"`
+}
+
+type voteJSON struct {
+	Witness string `json:"witness"`
+	Votes   int64  `json:"votes"`
+}
+
+func (c *cli) account(fs *flag.FlagSet) error {
+	addr, err := parseAddress("address", fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	ctx, cancel := c.context()
+	defer cancel()
+	acc, err := c.client.GetAccount(ctx, &core.Account{Address: addr.Bytes()})
+	if err != nil {
+		return err
+	}
+	if len(acc.GetAddress()) == 0 {
+		return fmt.Errorf("account %s not found", addr)
+	}
+
+	out := accountJSON{
+		Address:      addr.String(),
+		Name:         string(acc.GetAccountName()),
+		Balance:      acc.GetBalance(),
+		FrozenEnergy: acc.GetAccountResource().GetFrozenBalanceForEnergy().GetFrozenBalance(),
+		Assets:       make(map[string]int64),
+		Votes:        make([]voteJSON, 0),
+		Created:      acc.GetCreateTime(),
+		Witness:      acc.GetIsWitness(),
+	}
+	for _, f := range acc.GetFrozen() {
+		out.FrozenBandwidth += f.GetFrozenBalance()
+	}
+	for id, amount := range acc.GetAssetV2() {
+		out.Assets[id] = amount
+	}
+	for _, v := range acc.GetVotes() {
+		out.Votes = append(out.Votes, voteJSON{Witness: addressString(v.GetVoteAddress()), Votes: v.GetVoteCount()})
+	}
+
+	rows := [][]string{
+		{"address", out.Address},
+		{"name", out.Name},
+		{"balance", formatTRX(out.Balance)},
+		{"frozen for bandwidth", formatTRX(out.FrozenBandwidth)},
+		{"frozen for energy", formatTRX(out.FrozenEnergy)},
+		{"created", formatTime(out.Created)},
+		{"witness", strconv.FormatBool(out.Witness)},
+	}
+	ids := make([]string, 0, len(out.Assets))
+	for id := range out.Assets {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		rows = append(rows, []string{"token " + id, strconv.FormatInt(out.Assets[id], 10)})
+	}
+	for _, v := range out.Votes {
+		rows = append(rows, []string{"votes for " + v.Witness, strconv.FormatInt(v.Votes, 10)})
+	}
+	return c.print(out, rows)
+}
+
+// resourcesJSON is the output of resources.
+type resourcesJSON struct {
+	Address           string `json:"address"`
+	FreeNetUsed       int64  `json:"freeNetUsed"`
+	FreeNetLimit      int64  `json:"freeNetLimit"`
+	NetUsed           int64  `json:"netUsed"`
+	NetLimit          int64  `json:"netLimit"`
+	EnergyUsed        int64  `json:"energyUsed"`
+	EnergyLimit       int64  `json:"energyLimit"`
+	TotalNetLimit     int64  `json:"totalNetLimit"`
+	TotalNetWeight    int64  `json:"totalNetWeight"`
+	TotalEnergyLimit  int64  `json:"totalEnergyLimit"`
+	TotalEnergyWeight int64  `json:"totalEnergyWeight"`
+}
+
+func (c *cli) resources(fs *flag.FlagSet) error {
+	addr, err := parseAddress("address", fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	ctx, cancel := c.context()
+	defer cancel()
+	res, err := c.client.GetAccountResource(ctx, &core.Account{Address: addr.Bytes()})
+	if err != nil {
+		return err
+	}
+	out := resourcesJSON{
+		Address:           addr.String(),
+		FreeNetUsed:       res.GetFreeNetUsed(),
+		FreeNetLimit:      res.GetFreeNetLimit(),
+		NetUsed:           res.GetNetUsed(),
+		NetLimit:          res.GetNetLimit(),
+		EnergyUsed:        res.GetEnergyUsed(),
+		EnergyLimit:       res.GetEnergyLimit(),
+		TotalNetLimit:     res.GetTotalNetLimit(),
+		TotalNetWeight:    res.GetTotalNetWeight(),
+		TotalEnergyLimit:  res.GetTotalEnergyLimit(),
+		TotalEnergyWeight: res.GetTotalEnergyWeight(),
+	}
+	itoa := func(n int64) string { return strconv.FormatInt(n, 10) }
+	return c.print(out, [][]string{
+		{"RESOURCE", "USED", "LIMIT"},
+		{"free bandwidth", itoa(out.FreeNetUsed), itoa(out.FreeNetLimit)},
+		{"bandwidth", itoa(out.NetUsed), itoa(out.NetLimit)},
+		{"energy", itoa(out.EnergyUsed), itoa(out.EnergyLimit)},
+	})
+}
+
+// blockJSON is the output of block.
+type blockJSON struct {
+	Number       int64        `json:"number"`
+	ID           string       `json:"id"`
+	Parent       string       `json:"parent"`
+	Timestamp    int64        `json:"timestamp"`
+	Witness      string       `json:"witness"`
+	Transactions []txListJSON `json:"transactions"`
+}
+
+type txListJSON struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+}
+
+func (c *cli) block(fs *flag.FlagSet) error {
+	ctx, cancel := c.context()
+	defer cancel()
+	var (
+		block *api.BlockExtention
+		err   error
+	)
+	if fs.NArg() == 0 {
+		block, err = c.client.GetNowBlock2(ctx, &api.EmptyMessage{})
+	} else {
+		num, perr := strconv.ParseInt(fs.Arg(0), 10, 64)
+		if perr != nil || num < 0 {
+			return fmt.Errorf("invalid block number %q", fs.Arg(0))
+		}
+		block, err = c.client.GetBlockByNum2(ctx, &api.NumberMessage{Num: num})
+	}
+	if err != nil {
+		return err
+	}
+	if len(block.GetBlockid()) == 0 {
+		return fmt.Errorf("block %s not found", fs.Arg(0))
+	}
+
+	raw := block.GetBlockHeader().GetRawData()
+	out := blockJSON{
+		Number:       raw.GetNumber(),
+		ID:           hex.EncodeToString(block.GetBlockid()),
+		Parent:       hex.EncodeToString(raw.GetParentHash()),
+		Timestamp:    raw.GetTimestamp(),
+		Witness:      addressString(raw.GetWitnessAddress()),
+		Transactions: make([]txListJSON, 0, len(block.GetTransactions())),
+	}
+	for _, ext := range block.GetTransactions() {
+		tx := txListJSON{ID: hex.EncodeToString(ext.GetTxid())}
+		if contracts := ext.GetTransaction().GetRawData().GetContract(); len(contracts) > 0 {
+			tx.Type = contracts[0].GetType().String()
+		}
+		out.Transactions = append(out.Transactions, tx)
+	}
+
+	rows := [][]string{
+		{"number", strconv.FormatInt(out.Number, 10)},
+		{"id", out.ID},
+		{"parent", out.Parent},
+		{"time", formatTime(out.Timestamp)},
+		{"witness", out.Witness},
+		{"transactions", strconv.Itoa(len(out.Transactions))},
+	}
+	for _, tx := range out.Transactions {
+		rows = append(rows, []string{"  " + tx.ID, tx.Type})
+	}
+	return c.print(out, rows)
+}
+
+// txJSON is the output of tx, amounts are in sun.
+type txJSON struct {
+	ID          string                   `json:"id"`
+	Contracts   []map[string]interface{} `json:"contracts"`
+	Signatures  int                      `json:"signatures"`
+	Expiration  int64                    `json:"expiration"`
+	FeeLimit    int64                    `json:"feeLimit,omitempty"`
+	Block       int64                    `json:"block"`
+	Timestamp   int64                    `json:"timestamp"`
+	Result      string                   `json:"result"`
+	Error       string                   `json:"error,omitempty"`
+	Fee         int64                    `json:"fee"`
+	EnergyUsage int64                    `json:"energyUsage"`
+	NetUsage    int64                    `json:"netUsage"`
+	Contract    string                   `json:"contractAddress,omitempty"`
+}
+
+func (c *cli) tx(fs *flag.FlagSet) error {
+	id, err := parseTxID(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	ctx, cancel := c.context()
+	defer cancel()
+	tx, err := c.client.GetTransactionById(ctx, &api.BytesMessage{Value: id})
+	if err != nil {
+		return err
+	}
+	if tx.GetRawData() == nil {
+		return fmt.Errorf("transaction %x not found", id)
+	}
+	info, err := c.client.GetTransactionInfoById(ctx, &api.BytesMessage{Value: id})
+	if err != nil {
+		return err
+	}
+	txID, err := transaction.ID(tx)
+	if err != nil {
+		return err
+	}
+
+	out := txJSON{
+		ID:          hex.EncodeToString(txID),
+		Contracts:   make([]map[string]interface{}, 0, len(tx.GetRawData().GetContract())),
+		Signatures:  len(tx.GetSignature()),
+		Expiration:  tx.GetRawData().GetExpiration(),
+		FeeLimit:    tx.GetRawData().GetFeeLimit(),
+		Block:       info.GetBlockNumber(),
+		Timestamp:   info.GetBlockTimeStamp(),
+		Result:      "PENDING",
+		Fee:         info.GetFee(),
+		EnergyUsage: info.GetReceipt().GetEnergyUsageTotal(),
+		NetUsage:    info.GetReceipt().GetNetUsage(),
+	}
+	for _, contract := range tx.GetRawData().GetContract() {
+		fields := map[string]interface{}{"type": contract.GetType().String()}
+		if m, err := contract.GetParameter().UnmarshalNew(); err == nil {
+			fields = contractFields(m)
+			fields["type"] = contract.GetType().String()
+		}
+		out.Contracts = append(out.Contracts, fields)
+	}
+	if len(info.GetId()) > 0 {
+		out.Result = info.GetResult().String()
+		if ret := tx.GetRet(); len(ret) > 0 && ret[0].GetContractRet() != core.Transaction_Result_DEFAULT {
+			out.Result = ret[0].GetContractRet().String()
+		}
+		if err := abi.TransactionInfoError(info); err != nil {
+			out.Error = err.Error()
+		}
+	}
+	if addr := info.GetContractAddress(); len(addr) > 0 {
+		out.Contract = addressString(addr)
+	}
+
+	rows := [][]string{
+		{"id", out.ID},
+		{"block", strconv.FormatInt(out.Block, 10)},
+		{"time", formatTime(out.Timestamp)},
+		{"result", out.Result},
+	}
+	if out.Error != "" {
+		rows = append(rows, []string{"error", out.Error})
+	}
+	rows = append(rows,
+		[]string{"fee", formatTRX(out.Fee)},
+		[]string{"energy usage", strconv.FormatInt(out.EnergyUsage, 10)},
+		[]string{"net usage", strconv.FormatInt(out.NetUsage, 10)},
+		[]string{"signatures", strconv.Itoa(out.Signatures)},
+	)
+	if out.Contract != "" {
+		rows = append(rows, []string{"contract address", out.Contract})
+	}
+	for _, fields := range out.Contracts {
+		rows = append(rows, []string{"contract", fmt.Sprint(fields["type"])})
+		keys := make([]string, 0, len(fields))
+		for k := range fields {
+			if k != "type" {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			rows = append(rows, []string{"  " + k, fmt.Sprint(fields[k])})
+		}
+	}
+	return c.print(out, rows)
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bytejedi/tron-sdk-go/keystore"
+	"github.com/bytejedi/tron-sdk-go/proto/api"
+	"github.com/bytejedi/tron-sdk-go/proto/core"
+	"github.com/bytejedi/tron-sdk-go/proto/core/contract"
+)
+
+func (c *cli) send(fs *flag.FlagSet) error {
+	to, err := parseAddress("recipient", fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	amount, err := parseAmount64(fs.Arg(1), trxDecimals)
+	if err != nil {
+		return err
+	}
+	ks, from, err := c.sender()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := c.context()
+	defer cancel()
+	req := &contract.TransferContract{
+		OwnerAddress: from.Address.Bytes(),
+		ToAddress:    to.Bytes(),
+		Amount:       amount,
+	}
+	ext, err := c.client.CreateTransaction2(ctx, req)
+	return c.submit(ks, from, core.Transaction_Contract_TransferContract, req, ext, err, nil)
+}
+
+func sendTokenFlags(c *cli, fs *flag.FlagSet) {
+	sendFlags(c, fs)
+	decimalsFlag(c, fs)
+}
+
+func decimalsFlag(c *cli, fs *flag.FlagSet) {
+	fs.IntVar(&c.decimals, "decimals", -1, "decimals of the token, required unless the node is reached over TLS")
+}
+
+// tokenDecimals returns the decimals to scale the amount of token with,
+// checking those the node reported against -decimals. Over a plaintext
+// connection the node can't be trusted to report them, so -decimals is
+// required then.
+func (c *cli) tokenDecimals(token string, reported int) (int, error) {
+	switch {
+	case c.decimals >= 0 && c.decimals != reported:
+		return 0, fmt.Errorf("node reports %d decimals for %s, not %d", reported, token, c.decimals)
+	case c.decimals < 0 && !c.network.TLS:
+		return 0, fmt.Errorf("node reports %d decimals for %s over an unauthenticated connection, confirm them with -decimals", reported, token)
+	}
+	return reported, nil
+}
+
+func (c *cli) sendTRC10(fs *flag.FlagSet) error {
+	id := fs.Arg(0)
+	if _, err := strconv.ParseUint(id, 10, 64); err != nil {
+		return fmt.Errorf("invalid token id %q", id)
+	}
+	to, err := parseAddress("recipient", fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	ks, from, err := c.sender()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := c.context()
+	defer cancel()
+	asset, err := c.client.GetAssetIssueById(ctx, &api.BytesMessage{Value: []byte(id)})
+	if err != nil {
+		return err
+	}
+	if asset.GetId() != id {
+		return fmt.Errorf("token %s not found", id)
+	}
+	decimals, err := c.tokenDecimals("token "+id, int(asset.GetPrecision()))
+	if err != nil {
+		return err
+	}
+	amount, err := parseAmount64(fs.Arg(2), decimals)
+	if err != nil {
+		return err
+	}
+	req := &contract.TransferAssetContract{
+		AssetName:    []byte(id),
+		OwnerAddress: from.Address.Bytes(),
+		ToAddress:    to.Bytes(),
+		Amount:       amount,
+	}
+	ext, err := c.client.TransferAsset2(ctx, req)
+	return c.submit(ks, from, core.Transaction_Contract_TransferAssetContract, req, ext, err, nil)
+}
+
+func freezeFlags(c *cli, fs *flag.FlagSet) {
+	unfreezeFlags(c, fs)
+	fs.Int64Var(&c.duration, "duration", 3, "days to freeze the TRX for")
+}
+
+func unfreezeFlags(c *cli, fs *flag.FlagSet) {
+	sendFlags(c, fs)
+	fs.StringVar(&c.resource, "resource", "bandwidth", "resource of the frozen TRX, bandwidth or energy")
+	fs.StringVar(&c.receiver, "receiver", "", "address of the account the resource is delegated to")
+}
+
+// freezeTarget parses the -resource and -receiver flags.
+func (c *cli) freezeTarget() (contract.ResourceCode, []byte, error) {
+	var resource contract.ResourceCode
+	switch strings.ToLower(c.resource) {
+	case "bandwidth":
+		resource = contract.ResourceCode_BANDWIDTH
+	case "energy":
+		resource = contract.ResourceCode_ENERGY
+	default:
+		return 0, nil, fmt.Errorf("invalid resource %q, want bandwidth or energy", c.resource)
+	}
+	if c.receiver == "" {
+		return resource, nil, nil
+	}
+	receiver, err := parseAddress("receiver", c.receiver)
+	if err != nil {
+		return 0, nil, err
+	}
+	return resource, receiver.Bytes(), nil
+}
+
+func (c *cli) freeze(fs *flag.FlagSet) error {
+	amount, err := parseAmount64(fs.Arg(0), trxDecimals)
+	if err != nil {
+		return err
+	}
+	resource, receiver, err := c.freezeTarget()
+	if err != nil {
+		return err
+	}
+	ks, from, err := c.sender()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := c.context()
+	defer cancel()
+	req := &contract.FreezeBalanceContract{
+		OwnerAddress:    from.Address.Bytes(),
+		FrozenBalance:   amount,
+		FrozenDuration:  c.duration,
+		Resource:        resource,
+		ReceiverAddress: receiver,
+	}
+	ext, err := c.client.FreezeBalance2(ctx, req)
+	return c.submit(ks, from, core.Transaction_Contract_FreezeBalanceContract, req, ext, err, nil)
+}
+
+func (c *cli) unfreeze(fs *flag.FlagSet) error {
+	resource, receiver, err := c.freezeTarget()
+	if err != nil {
+		return err
+	}
+	ks, from, err := c.sender()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := c.context()
+	defer cancel()
+	req := &contract.UnfreezeBalanceContract{
+		OwnerAddress:    from.Address.Bytes(),
+		Resource:        resource,
+		ReceiverAddress: receiver,
+	}
+	ext, err := c.client.UnfreezeBalance2(ctx, req)
+	return c.submit(ks, from, core.Transaction_Contract_UnfreezeBalanceContract, req, ext, err, nil)
+}
+
+// parseVotes parses vote arguments of the form <witness>=<votes>.
+func parseVotes(args []string) ([]*contract.VoteWitnessContract_Vote, error) {
+	votes := make([]*contract.VoteWitnessContract_Vote, 0, len(args))
+	seen := make(map[keystore.Address]bool, len(args))
+	for _, arg := range args {
+		i := strings.LastIndexByte(arg, '=')
+		if i < 0 {
+			return nil, fmt.Errorf("invalid vote %q, want <witness>=<votes>", arg)
+		}
+		witness, err := parseAddress("witness", arg[:i])
+		if err != nil {
+			return nil, err
+		}
+		if seen[witness] {
+			return nil, fmt.Errorf("duplicate vote for %s", witness)
+		}
+		seen[witness] = true
+		count, err := strconv.ParseInt(arg[i+1:], 10, 64)
+		if err != nil || count <= 0 {
+			return nil, fmt.Errorf("invalid vote count in %q", arg)
+		}
+		votes = append(votes, &contract.VoteWitnessContract_Vote{VoteAddress: witness.Bytes(), VoteCount: count})
+	}
+	return votes, nil
+}
+
+func (c *cli) vote(fs *flag.FlagSet) error {
+	votes, err := parseVotes(fs.Args())
+	if err != nil {
+		return err
+	}
+	ks, from, err := c.sender()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := c.context()
+	defer cancel()
+	req := &contract.VoteWitnessContract{
+		OwnerAddress: from.Address.Bytes(),
+		Votes:        votes,
+	}
+	ext, err := c.client.VoteWitnessAccount2(ctx, req)
+	return c.submit(ks, from, core.Transaction_Contract_VoteWitnessContract, req, ext, err, nil)
+}
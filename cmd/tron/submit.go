@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/bytejedi/tron-sdk-go/keystore"
+	"github.com/bytejedi/tron-sdk-go/proto/api"
+	"github.com/bytejedi/tron-sdk-go/proto/core"
+	"github.com/bytejedi/tron-sdk-go/transaction"
+	"google.golang.org/protobuf/proto"
+)
+
+func sendFlags(c *cli, fs *flag.FlagSet) {
+	fs.StringVar(&c.from, "from", "", "address of the sending account in the keystore")
+	fs.StringVar(&c.passFile, "passfile", "", "file holding the passphrase of the account")
+	fs.BoolVar(&c.signOnly, "sign-only", false, "print the signed transaction as JSON instead of broadcasting it")
+}
+
+// signedTxJSON is a signed transaction in the format of TronWeb, as printed
+// with -sign-only and read by broadcast. The raw_data object TronWeb adds is
+// ignored.
+type signedTxJSON struct {
+	TxID       string   `json:"txID"`
+	RawDataHex string   `json:"raw_data_hex"`
+	Signature  []string `json:"signature"`
+}
+
+// broadcastJSON is the output of commands broadcasting a transaction.
+type broadcastJSON struct {
+	TxID string `json:"txID"`
+}
+
+// submit signs the transaction the node built for a command with the -from
+// account and broadcasts it, or prints it with -sign-only. The transaction
+// must hold just the requested contract of type ty with the parameter want,
+// and can be adjusted by edit before it is signed.
+func (c *cli) submit(ks *keystore.KeyStore, from keystore.Account, ty core.Transaction_Contract_ContractType, want proto.Message, ext *api.TransactionExtention, err error, edit func(raw *core.TransactionRaw)) error {
+	if err != nil {
+		return err
+	}
+	if ret := ext.GetResult(); ret != nil && !ret.GetResult() {
+		return fmt.Errorf("creating transaction: %s: %s", ret.GetCode(), ret.GetMessage())
+	}
+	tx := ext.GetTransaction()
+	if len(tx.GetRawData().GetContract()) == 0 {
+		return errors.New("node returned no transaction")
+	}
+	if err := checkBuilt(tx, ty, want); err != nil {
+		return err
+	}
+	if edit != nil {
+		edit(tx.RawData)
+	}
+
+	pass, err := c.passphrase("Passphrase of " + from.Address.String())
+	if err != nil {
+		return err
+	}
+	if tx, err = ks.SignTransactionWithPassphrase(from, pass, tx); err != nil {
+		return err
+	}
+	if c.signOnly {
+		out, err := newSignedTxJSON(tx)
+		if err != nil {
+			return err
+		}
+		return c.print(out, [][]string{
+			{"txID", out.TxID},
+			{"raw_data_hex", out.RawDataHex},
+			{"signature", strings.Join(out.Signature, ",")},
+		})
+	}
+	return c.broadcastTx(tx)
+}
+
+// checkBuilt checks that a transaction built by the node does exactly what
+// was requested. The node is not trusted, and the connection to it may not
+// be authenticated either, so nothing it adds or changes may get signed.
+func checkBuilt(tx *core.Transaction, ty core.Transaction_Contract_ContractType, want proto.Message) error {
+	raw := tx.GetRawData()
+	if len(raw.GetContract()) != 1 {
+		return fmt.Errorf("node returned a transaction with %d contracts", len(raw.GetContract()))
+	}
+	ct := raw.GetContract()[0]
+	if ct.GetType() != ty {
+		return fmt.Errorf("node returned a %s instead of a %s", ct.GetType(), ty)
+	}
+	have := want.ProtoReflect().New().Interface()
+	if err := ct.GetParameter().UnmarshalTo(have); err != nil {
+		return fmt.Errorf("node returned an invalid %s: %v", ty, err)
+	}
+	if !proto.Equal(have, want) {
+		return fmt.Errorf("node returned a %s differing from the request", ty)
+	}
+	if ct.GetPermissionId() != 0 || len(ct.GetProvider()) != 0 || len(ct.GetContractName()) != 0 ||
+		len(raw.GetData()) != 0 || len(raw.GetAuths()) != 0 || len(raw.GetScripts()) != 0 ||
+		raw.GetFeeLimit() != 0 || len(tx.GetSignature()) != 0 {
+		return errors.New("node returned a transaction with settings that were not requested")
+	}
+	return nil
+}
+
+// broadcastTx broadcasts a signed transaction and prints its id.
+func (c *cli) broadcastTx(tx *core.Transaction) error {
+	id, err := transaction.ID(tx)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := c.context()
+	defer cancel()
+	ret, err := c.client.BroadcastTransaction(ctx, tx)
+	if err != nil {
+		return err
+	}
+	if !ret.GetResult() {
+		return fmt.Errorf("broadcasting transaction %x: %s: %s", id, ret.GetCode(), ret.GetMessage())
+	}
+	out := broadcastJSON{TxID: hex.EncodeToString(id)}
+	return c.print(out, [][]string{{"txID", out.TxID}})
+}
+
+func newSignedTxJSON(tx *core.Transaction) (*signedTxJSON, error) {
+	raw, err := proto.Marshal(tx.GetRawData())
+	if err != nil {
+		return nil, err
+	}
+	id, err := transaction.ID(tx)
+	if err != nil {
+		return nil, err
+	}
+	out := &signedTxJSON{TxID: hex.EncodeToString(id), RawDataHex: hex.EncodeToString(raw)}
+	for _, sig := range tx.GetSignature() {
+		out.Signature = append(out.Signature, hex.EncodeToString(sig))
+	}
+	return out, nil
+}
+
+// parseSignedTx decodes a signed transaction in the format of signedTxJSON,
+// checking its txID if present.
+func parseSignedTx(data []byte) (*core.Transaction, error) {
+	var in signedTxJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return nil, fmt.Errorf("invalid transaction: %v", err)
+	}
+	raw, err := hex.DecodeString(strings.TrimPrefix(in.RawDataHex, "0x"))
+	if err != nil || len(raw) == 0 {
+		return nil, errors.New("invalid transaction: need hex encoded raw_data_hex")
+	}
+	tx := &core.Transaction{RawData: new(core.TransactionRaw)}
+	if err := proto.Unmarshal(raw, tx.RawData); err != nil {
+		return nil, fmt.Errorf("invalid transaction raw data: %v", err)
+	}
+	// The node hashes the raw data as encoded again, which has to give the
+	// signed bytes
+	if again, err := proto.Marshal(tx.RawData); err != nil || !bytes.Equal(again, raw) {
+		return nil, errors.New("invalid transaction: raw data is not canonically encoded")
+	}
+	if in.TxID != "" {
+		want, err := parseTxID(in.TxID)
+		if err != nil {
+			return nil, err
+		}
+		if id, _ := transaction.ID(tx); !bytes.Equal(id, want) {
+			return nil, fmt.Errorf("invalid transaction: txID %s does not match raw data %x", in.TxID, id)
+		}
+	}
+	if len(in.Signature) == 0 {
+		return nil, errors.New("transaction is not signed")
+	}
+	for i, s := range in.Signature {
+		sig, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+		if err != nil || len(sig) != 65 {
+			return nil, fmt.Errorf("invalid signature %d", i)
+		}
+		tx.Signature = append(tx.Signature, sig)
+	}
+	return tx, nil
+}
+
+func (c *cli) broadcast(fs *flag.FlagSet) error {
+	data, err := readInput(c.stdin, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	tx, err := parseSignedTx(data)
+	if err != nil {
+		return err
+	}
+	return c.broadcastTx(tx)
+}
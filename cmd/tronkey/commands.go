@@ -0,0 +1,319 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/bytejedi/tron-sdk-go/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// accountJSON is the output of commands returning accounts.
+type accountJSON struct {
+	Address string `json:"address"`
+	Hex     string `json:"hex"`
+	URL     string `json:"url,omitempty"`
+}
+
+func newAccountJSON(a keystore.Account) accountJSON {
+	out := accountJSON{Address: a.Address.String(), Hex: a.Address.Hex()}
+	if a.URL != (keystore.URL{}) {
+		out.URL = a.URL.String()
+	}
+	return out
+}
+
+// print writes v as JSON with -json or else as text.
+func (c *cli) print(v interface{}, text string) error {
+	if c.json {
+		enc := json.NewEncoder(c.stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	_, err := fmt.Fprintln(c.stdout, text)
+	return err
+}
+
+// printAccount prints a single account.
+func (c *cli) printAccount(a keystore.Account) error {
+	out := newAccountJSON(a)
+	return c.print(out, fmt.Sprintf("%s %s", out.Address, out.Hex))
+}
+
+// findAccount resolves an address argument to an account of the keystore.
+func (c *cli) findAccount(arg string) (keystore.Account, error) {
+	addr, err := keystore.ParseAddress(arg)
+	if err != nil {
+		return keystore.Account{}, err
+	}
+	return c.ks.Find(keystore.Account{Address: addr})
+}
+
+func (c *cli) newAccount(fs *flag.FlagSet) error {
+	pass, err := c.newPassphrase(c.passFile, "Passphrase")
+	if err != nil {
+		return err
+	}
+	a, err := c.ks.NewAccount(pass)
+	if err != nil {
+		return err
+	}
+	return c.printAccount(a)
+}
+
+func (c *cli) list(fs *flag.FlagSet) error {
+	accounts := make([]accountJSON, 0)
+	lines := make([]string, 0)
+	for i, a := range c.ks.Accounts() {
+		out := newAccountJSON(a)
+		accounts = append(accounts, out)
+		lines = append(lines, fmt.Sprintf("#%d: %s %s %s", i, out.Address, out.Hex, out.URL))
+	}
+	return c.print(accounts, strings.Join(lines, "\n"))
+}
+
+func (c *cli) importKey(fs *flag.FlagSet) error {
+	data, err := readSecret(c.stdin, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(strings.TrimSpace(string(data)), "0x"))
+	if err != nil {
+		return fmt.Errorf("invalid private key: %v", err)
+	}
+	defer keystore.ZeroKey(key)
+
+	pass, err := c.newPassphrase(c.passFile, "Passphrase")
+	if err != nil {
+		return err
+	}
+	a, err := c.ks.ImportECDSA(key, pass)
+	if err != nil {
+		return err
+	}
+	return c.printAccount(a)
+}
+
+func (c *cli) importJSON(fs *flag.FlagSet) error {
+	keyJSON, err := readSecret(c.stdin, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	pass, err := c.passphrase("Passphrase of the key")
+	if err != nil {
+		return err
+	}
+	a, err := c.ks.Import(keyJSON, pass)
+	if err != nil {
+		return err
+	}
+	return c.printAccount(a)
+}
+
+func mnemonicFlags(c *cli, fs *flag.FlagSet) {
+	passFlags(c, fs)
+	fs.StringVar(&c.path, "path", keystore.DefaultBaseDerivationPath.String(), "derivation path of the key")
+	fs.StringVar(&c.bip39File, "bip39passfile", "", "file holding the optional BIP39 password")
+}
+
+func (c *cli) importMnemonic(fs *flag.FlagSet) error {
+	data, err := readSecret(c.stdin, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	path, err := keystore.ParseDerivationPath(c.path)
+	if err != nil {
+		return err
+	}
+	w, err := keystore.NewHDWallet(strings.Join(strings.Fields(string(data)), " "))
+	if err != nil {
+		return err
+	}
+	var password string
+	if c.bip39File != "" {
+		if password, err = c.readPassphrase(c.bip39File, "", false); err != nil {
+			return err
+		}
+	}
+	if err := w.Open(password); err != nil {
+		return err
+	}
+	defer w.Close()
+
+	derived, err := w.Derive(path, true)
+	if err != nil {
+		return err
+	}
+	key, err := w.PrivateKey(derived)
+	if err != nil {
+		return err
+	}
+	defer keystore.ZeroKey(key)
+
+	pass, err := c.newPassphrase(c.passFile, "Passphrase")
+	if err != nil {
+		return err
+	}
+	a, err := c.ks.ImportECDSA(key, pass)
+	if err != nil {
+		return err
+	}
+	return c.printAccount(a)
+}
+
+func exportFlags(c *cli, fs *flag.FlagSet) {
+	newPassFlags(c, fs)
+	fs.StringVar(&c.out, "out", "", "file to write the key to instead of stdout")
+}
+
+func (c *cli) export(fs *flag.FlagSet) error {
+	a, err := c.findAccount(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	pass, err := c.passphrase("Passphrase")
+	if err != nil {
+		return err
+	}
+	newPass, err := c.newPassphrase(c.newFile, "Passphrase of the exported key")
+	if err != nil {
+		return err
+	}
+	keyJSON, err := c.ks.Export(a, pass, newPass)
+	if err != nil {
+		return err
+	}
+	if c.out != "" {
+		return ioutil.WriteFile(c.out, keyJSON, 0600)
+	}
+	_, err = fmt.Fprintln(c.stdout, string(keyJSON))
+	return err
+}
+
+func (c *cli) update(fs *flag.FlagSet) error {
+	a, err := c.findAccount(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	pass, err := c.passphrase("Passphrase")
+	if err != nil {
+		return err
+	}
+	newPass, err := c.newPassphrase(c.newFile, "New passphrase")
+	if err != nil {
+		return err
+	}
+	if err := c.ks.Update(a, pass, newPass); err != nil {
+		return err
+	}
+	return c.printAccount(a)
+}
+
+func (c *cli) delete(fs *flag.FlagSet) error {
+	a, err := c.findAccount(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	pass, err := c.passphrase("Passphrase")
+	if err != nil {
+		return err
+	}
+	if err := c.ks.Delete(a, pass); err != nil {
+		return err
+	}
+	return c.printAccount(a)
+}
+
+func signFlags(c *cli, fs *flag.FlagSet) {
+	passFlags(c, fs)
+	fs.StringVar(&c.raw, "raw", "", "hex encoded raw data of the transaction")
+	fs.StringVar(&c.hash, "hash", "", "hex encoded transaction hash, checked against -raw if both are given")
+}
+
+// signatureJSON is the output of sign.
+type signatureJSON struct {
+	Address   string `json:"address"`
+	TxID      string `json:"txId"`
+	Signature string `json:"signature"`
+}
+
+// signedTxID decodes the -raw and -hash flags, returning the transaction ID
+// to sign. Both are checked here since the wallet may not be the local
+// keystore, and the printed ID has to be the signed one.
+func signedTxID(raw, hash string) ([]byte, error) {
+	var id []byte
+	if raw != "" {
+		data, err := hex.DecodeString(strings.TrimPrefix(raw, "0x"))
+		if err != nil || len(data) == 0 {
+			return nil, errors.New("invalid raw data, need hex")
+		}
+		sum := sha256.Sum256(data)
+		id = sum[:]
+	}
+	if hash != "" {
+		h, err := hex.DecodeString(strings.TrimPrefix(hash, "0x"))
+		if err != nil || len(h) != sha256.Size || id != nil && !bytes.Equal(h, id) {
+			return nil, keystore.ErrInvalidTxID
+		}
+		id = h
+	}
+	if id == nil {
+		return nil, errors.New("need -raw or -hash")
+	}
+	return id, nil
+}
+
+func (c *cli) sign(fs *flag.FlagSet) error {
+	id, err := signedTxID(c.raw, c.hash)
+	if err != nil {
+		return err
+	}
+	txID := hex.EncodeToString(id)
+	a, err := c.findAccount(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	var wallet keystore.Wallet
+	for _, w := range c.ks.Wallets() {
+		if w.Contains(a) {
+			wallet = w
+			break
+		}
+	}
+	if wallet == nil {
+		return keystore.ErrUnknownAccount
+	}
+	pass, err := c.passphrase("Passphrase")
+	if err != nil {
+		return err
+	}
+	sig, err := wallet.SignTxWithPassphrase(a, pass, c.raw, txID)
+	if err != nil {
+		return err
+	}
+	out := signatureJSON{Address: a.Address.String(), TxID: txID, Signature: hex.EncodeToString(sig)}
+	return c.print(out, out.Signature)
+}
+
+// addressJSON is the output of address.
+type addressJSON struct {
+	Base58 string `json:"base58"`
+	Hex    string `json:"hex"`
+	EVM    string `json:"evm"`
+}
+
+func (c *cli) address(fs *flag.FlagSet) error {
+	addr, err := keystore.ParseAddress(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	out := addressJSON{Base58: addr.String(), Hex: addr.Hex(), EVM: addr.EVM().Hex()}
+	return c.print(out, fmt.Sprintf("base58: %s\nhex:    %s\nevm:    %s", out.Base58, out.Hex, out.EVM))
+}
@@ -0,0 +1,218 @@
+// Command tronkey manages the accounts of a keystore directory: it creates,
+// lists, imports, exports, re-encrypts and deletes keys, signs transactions
+// and converts addresses.
+//
+// Passphrases are read from the files given with -passfile and -newpassfile,
+// of which only the first line is used, or prompted for on the terminal.
+// Secrets to import are read from a file or, given as "-", from stdin. With
+// -json all results are printed as JSON for scripting.
+//
+// Usage:
+//
+//	tronkey [-keystore dir] [-json] [-lightkdf] <command> [flags] [args]
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bytejedi/tron-sdk-go/keystore"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// command is a tronkey subcommand.
+type command struct {
+	usage string // Arguments following the flags
+	help  string
+	run   func(c *cli, fs *flag.FlagSet) error
+	flags func(c *cli, fs *flag.FlagSet)
+}
+
+var commands = map[string]*command{
+	"new":             {"", "Create a new account", (*cli).newAccount, passFlags},
+	"list":            {"", "List the accounts of the keystore", (*cli).list, nil},
+	"import-key":      {"<keyfile>", "Import a hex encoded private key", (*cli).importKey, passFlags},
+	"import-json":     {"<jsonfile>", "Import a V3 JSON key, keeping its passphrase", (*cli).importJSON, passFlags},
+	"import-mnemonic": {"<mnemonicfile>", "Import the key derived from a BIP39 mnemonic", (*cli).importMnemonic, mnemonicFlags},
+	"export":          {"<address>", "Export an account as V3 JSON key", (*cli).export, exportFlags},
+	"update":          {"<address>", "Change the passphrase of an account", (*cli).update, newPassFlags},
+	"delete":          {"<address>", "Delete an account", (*cli).delete, passFlags},
+	"sign":            {"<address>", "Sign hex encoded raw transaction data or a transaction hash", (*cli).sign, signFlags},
+	"address":         {"<address>", "Show an address in base58 and hex", (*cli).address, nil},
+}
+
+func main() {
+	c := &cli{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}
+	if terminal.IsTerminal(int(os.Stdin.Fd())) {
+		c.readPassword = func(prompt string) (string, error) {
+			fmt.Fprint(os.Stderr, prompt)
+			defer fmt.Fprintln(os.Stderr)
+			pass, err := terminal.ReadPassword(int(os.Stdin.Fd()))
+			return string(pass), err
+		}
+	}
+	if err := c.run(os.Args[1:]); err != nil {
+		if err != flag.ErrHelp {
+			fmt.Fprintln(os.Stderr, "tronkey:", err)
+		}
+		os.Exit(2)
+	}
+}
+
+// cli holds the settings and I/O of a tronkey invocation.
+type cli struct {
+	stdin          io.Reader
+	stdout, stderr io.Writer
+
+	// readPassword prompts for a passphrase, nil without a terminal
+	readPassword func(prompt string) (string, error)
+
+	ks   *keystore.KeyStore
+	json bool
+
+	// Command flags
+	passFile  string // Passphrase file
+	newFile   string // New passphrase file
+	path      string // Derivation path of import-mnemonic
+	bip39File string // BIP39 password file of import-mnemonic
+	out       string // Output file of export
+	raw       string // Raw data to sign
+	hash      string // Transaction hash to sign
+}
+
+// run parses the global flags and runs the command of args.
+func (c *cli) run(args []string) error {
+	fs := flag.NewFlagSet("tronkey", flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	dir := fs.String("keystore", defaultKeystore(), "keystore directory")
+	fs.BoolVar(&c.json, "json", false, "print results as JSON")
+	light := fs.Bool("lightkdf", false, "encrypt keys with light scrypt parameters, for tests only")
+	fs.Usage = func() {
+		fmt.Fprintln(c.stderr, "Usage: tronkey [flags] <command> [command flags] [args]\n\nFlags:")
+		fs.PrintDefaults()
+		fmt.Fprintln(c.stderr, "\nCommands:")
+		names := make([]string, 0, len(commands))
+		for name := range commands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(c.stderr, "  %-16s %s\n", name, commands[name].help)
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	name := fs.Arg(0)
+	cmd, ok := commands[name]
+	if !ok {
+		return fmt.Errorf("unknown command %q", name)
+	}
+	cfs := flag.NewFlagSet(name, flag.ContinueOnError)
+	cfs.SetOutput(c.stderr)
+	if cmd.flags != nil {
+		cmd.flags(c, cfs)
+	}
+	cfs.Usage = func() {
+		fmt.Fprintf(c.stderr, "Usage: tronkey %s [flags] %s\n\n%s.\n", name, cmd.usage, cmd.help)
+		cfs.PrintDefaults()
+	}
+	if err := cfs.Parse(fs.Args()[1:]); err != nil {
+		return err
+	}
+	if want := len(strings.Fields(cmd.usage)); cfs.NArg() != want {
+		cfs.Usage()
+		return fmt.Errorf("%s takes %d arguments, have %d", name, want, cfs.NArg())
+	}
+	if *light {
+		c.ks = keystore.NewKeyStore(*dir, keystore.LightScryptN, keystore.LightScryptP)
+	} else {
+		c.ks = keystore.NewKeyStore(*dir, keystore.StandardScryptN, keystore.StandardScryptP)
+	}
+	return cmd.run(c, cfs)
+}
+
+// defaultKeystore returns the keystore directory used without -keystore.
+func defaultKeystore() string {
+	if dir := os.Getenv("TRONKEY_KEYSTORE"); dir != "" {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "keystore"
+	}
+	return filepath.Join(home, ".tron", "keystore")
+}
+
+func passFlags(c *cli, fs *flag.FlagSet) {
+	fs.StringVar(&c.passFile, "passfile", "", "file holding the passphrase")
+}
+
+func newPassFlags(c *cli, fs *flag.FlagSet) {
+	passFlags(c, fs)
+	fs.StringVar(&c.newFile, "newpassfile", "", "file holding the new passphrase")
+}
+
+// passphrase reads the passphrase from -passfile or the terminal.
+func (c *cli) passphrase(prompt string) (string, error) {
+	return c.readPassphrase(c.passFile, prompt, false)
+}
+
+// newPassphrase reads a new passphrase from file or the terminal, where it
+// has to be entered twice.
+func (c *cli) newPassphrase(file, prompt string) (string, error) {
+	return c.readPassphrase(file, prompt, true)
+}
+
+func (c *cli) readPassphrase(file, prompt string, confirm bool) (string, error) {
+	if file != "" {
+		data, err := readSecret(c.stdin, file)
+		if err != nil {
+			return "", err
+		}
+		return firstLine(data), nil
+	}
+	if c.readPassword == nil {
+		return "", errors.New("no terminal to read the passphrase from, use a passphrase file")
+	}
+	pass, err := c.readPassword(prompt + ": ")
+	if err != nil || !confirm {
+		return pass, err
+	}
+	again, err := c.readPassword("Repeat " + strings.ToLower(prompt[:1]) + prompt[1:] + ": ")
+	if err != nil {
+		return "", err
+	}
+	if pass != again {
+		return "", errors.New("passphrases do not match")
+	}
+	return pass, nil
+}
+
+// readSecret reads a file, or stdin if the name is "-".
+func readSecret(stdin io.Reader, name string) ([]byte, error) {
+	if name == "-" {
+		return ioutil.ReadAll(stdin)
+	}
+	return ioutil.ReadFile(name)
+}
+
+// firstLine returns the first line of data without line terminator.
+func firstLine(data []byte) string {
+	s := string(data)
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSuffix(s, "\r")
+}
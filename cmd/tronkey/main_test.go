@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bytejedi/tron-sdk-go/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// tronkey runs a command in dir with JSON output, decoding the output into
+// out if given.
+func tronkey(t *testing.T, dir, stdin string, out interface{}, args ...string) error {
+	var stdout, stderr bytes.Buffer
+	c := &cli{stdin: strings.NewReader(stdin), stdout: &stdout, stderr: &stderr}
+	args = append([]string{"-keystore", filepath.Join(dir, "keystore"), "-lightkdf", "-json"}, args...)
+	if err := c.run(args); err != nil {
+		return err
+	}
+	if out != nil {
+		if err := json.Unmarshal(stdout.Bytes(), out); err != nil {
+			t.Fatalf("%v: %v: %s", args, err, stdout.String())
+		}
+	}
+	return nil
+}
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestTronkey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tronkey")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	pass := writeFile(t, dir, "pass", "secret\n")
+	newPass := writeFile(t, dir, "newpass", "changed\n")
+
+	// Create and import accounts
+	var created, imported, derived accountJSON
+	if err := tronkey(t, dir, "", &created, "new", "-passfile", pass); err != nil {
+		t.Fatal(err)
+	}
+	key, _ := crypto.GenerateKey()
+	want := keystore.PubkeyToAddress(key.PublicKey)
+	if err := tronkey(t, dir, hex.EncodeToString(crypto.FromECDSA(key))+"\n", &imported, "import-key", "-passfile", pass, "-"); err != nil {
+		t.Fatal(err)
+	}
+	if imported.Address != want.String() || imported.Hex != want.Hex() {
+		t.Errorf("imported %+v, want %s", imported, want)
+	}
+	mnemonic := writeFile(t, dir, "mnemonic", "abandon abandon abandon abandon abandon abandon\nabandon abandon abandon abandon abandon about\n")
+	if err := tronkey(t, dir, "", &derived, "import-mnemonic", "-passfile", pass, mnemonic); err != nil {
+		t.Fatal(err)
+	}
+	if derived.Address != "TUEZSdKsoDHQMeZwihtdoBiN46zxhGWYdH" {
+		t.Errorf("derived %s", derived.Address)
+	}
+	var accounts []accountJSON
+	if err := tronkey(t, dir, "", &accounts, "list"); err != nil {
+		t.Fatal(err)
+	}
+	if len(accounts) != 3 {
+		t.Fatalf("have %d accounts, want 3", len(accounts))
+	}
+
+	// Sign with the imported key
+	raw := "0a0201002208abcdef0123456789"
+	rawData, _ := hex.DecodeString(raw)
+	id := sha256.Sum256(rawData)
+	var sig signatureJSON
+	if err := tronkey(t, dir, "", &sig, "sign", "-passfile", pass, "-raw", raw, imported.Address); err != nil {
+		t.Fatal(err)
+	}
+	sigBytes, _ := hex.DecodeString(sig.Signature)
+	if pub, err := crypto.SigToPub(id[:], sigBytes); err != nil || keystore.PubkeyToAddress(*pub) != want {
+		t.Errorf("signature does not recover to the account: %v", err)
+	}
+	if sig.TxID != hex.EncodeToString(id[:]) {
+		t.Errorf("unexpected tx id %s", sig.TxID)
+	}
+	if err := tronkey(t, dir, "", nil, "sign", "-passfile", pass, "-raw", raw, "-hash", hex.EncodeToString(make([]byte, 32)), imported.Address); err != keystore.ErrInvalidTxID {
+		t.Errorf("mismatching hash: have %v, want %v", err, keystore.ErrInvalidTxID)
+	}
+	for _, bad := range []string{"0x", "zz", raw + "0"} {
+		if err := tronkey(t, dir, "", nil, "sign", "-passfile", pass, "-raw", bad, imported.Address); err == nil {
+			t.Errorf("signed invalid raw data %q", bad)
+		}
+	}
+
+	// Change the passphrase, export, delete and import again
+	if err := tronkey(t, dir, "", nil, "update", "-passfile", pass, "-newpassfile", newPass, imported.Hex); err != nil {
+		t.Fatal(err)
+	}
+	exported := filepath.Join(dir, "exported.json")
+	if err := tronkey(t, dir, "", nil, "export", "-passfile", pass, "-newpassfile", newPass, "-out", exported, imported.Address); err != keystore.ErrDecrypt {
+		t.Errorf("export with old passphrase: have %v, want %v", err, keystore.ErrDecrypt)
+	}
+	if err := tronkey(t, dir, "", nil, "export", "-passfile", newPass, "-newpassfile", pass, "-out", exported, imported.Address); err != nil {
+		t.Fatal(err)
+	}
+	if err := tronkey(t, dir, "", nil, "delete", "-passfile", newPass, imported.Address); err != nil {
+		t.Fatal(err)
+	}
+	var reimported accountJSON
+	if err := tronkey(t, dir, "", &reimported, "import-json", "-passfile", pass, exported); err != nil {
+		t.Fatal(err)
+	}
+	if reimported.Address != imported.Address {
+		t.Errorf("reimported %s, want %s", reimported.Address, imported.Address)
+	}
+
+	// Passphrases need a file without terminal
+	if err := tronkey(t, dir, "", nil, "new"); err == nil {
+		t.Errorf("passphrase read without terminal")
+	}
+	var addr addressJSON
+	if err := tronkey(t, dir, "", &addr, "address", want.EVM().Hex()); err != nil {
+		t.Fatal(err)
+	}
+	if addr.Base58 != want.String() || addr.Hex != want.Hex() {
+		t.Errorf("unexpected address forms %+v", addr)
+	}
+}
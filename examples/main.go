@@ -13,41 +13,27 @@ import (
 	ethabi "github.com/ethereum/go-ethereum/accounts/abi"
 	ethcmn "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/miguelmota/go-ethereum-hdwallet"
-	"github.com/tyler-smith/go-bip39"
-	"github.com/tyler-smith/go-bip39/wordlists"
 )
 
 func main() {
 	keystorePassword := "password"
 
-	// Set wordlist
-	bip39.SetWordList(wordlists.English)
-	entropy, err := bip39.NewEntropy(256)
+	mnemonic, err := keystore.NewMnemonic(keystore.MnemonicEntropy24Words)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	mnemonic, _ := bip39.NewMnemonic(entropy)
-	seed := bip39.NewSeed(mnemonic, keystorePassword)
-
-	wallet, err := hdwallet.NewFromSeed(seed)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	path := hdwallet.MustParseDerivationPath("m/44'/195'/0'/0/0")
-	account, err := wallet.Derive(path, false)
+	wallet, err := keystore.NewHDWallet(mnemonic)
 	if err != nil {
 		log.Fatal(err)
 	}
-
-	privKeyHex, err := wallet.PrivateKeyHex(account)
-	if err != nil {
+	if err := wallet.Open(keystorePassword); err != nil {
 		log.Fatal(err)
 	}
+	defer wallet.Close()
 
-	pubKeyHex, err := wallet.PublicKeyHex(account)
+	path := keystore.MustParseDerivationPath("m/44'/195'/0'/0/0")
+	account, err := wallet.Derive(path, true)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -56,13 +42,12 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
-	publicKeyECDSA, err := wallet.PublicKey(account)
-	if err != nil {
-		log.Fatal(err)
-	}
+	publicKeyECDSA := &privateKeyECDSA.PublicKey
+	privKeyHex := hex.EncodeToString(crypto.FromECDSA(privateKeyECDSA))
+	pubKeyHex := hex.EncodeToString(crypto.FromECDSAPub(publicKeyECDSA)[1:])
 
 	keystoreKey := keystore.NewKeyFromECDSA(privateKeyECDSA)
-	keyjson, err := keystore.EncryptKey(keystoreKey, keystorePassword, keystore.StandardScryptN, keystore.StandardScryptP)
+	keyjson, err := keystore.EncryptKey(keystoreKey, keystorePassword, keystore.StandardScryptKDF)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -71,7 +56,7 @@ func main() {
 
 	fmt.Println("mnemonic:", mnemonic)
 	fmt.Println("base58 address:", tronAddress.String())
-	fmt.Println("hex address:", hex.EncodeToString(tronAddress))
+	fmt.Println("hex address:", hex.EncodeToString(tronAddress.Bytes()))
 	fmt.Println("private key:", privKeyHex)
 	fmt.Println("public key:", pubKeyHex)
 	fmt.Println("keystore:", string(keyjson))
@@ -8,7 +8,6 @@ require (
 	github.com/ethereum/go-ethereum v1.9.18
 	github.com/golang/protobuf v1.4.2
 	github.com/google/uuid v1.1.1
-	github.com/miguelmota/go-ethereum-hdwallet v0.0.0-20200123000308-a60dcd172b4c
 	github.com/pborman/uuid v1.2.0
 	github.com/rjeczalik/notify v0.9.2
 	github.com/shengdoushi/base58 v1.0.0
@@ -18,4 +17,5 @@ require (
 	google.golang.org/genproto v0.0.0-20200806141610-86f49bd18e98
 	google.golang.org/grpc v1.31.0
 	google.golang.org/protobuf v1.25.0
+	gopkg.in/yaml.v2 v2.2.2
 )
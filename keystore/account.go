@@ -5,8 +5,6 @@ import (
 	"golang.org/x/crypto/sha3"
 )
 
-type DerivationPath []uint32
-
 // Account represents an Ethereum account located at a specific location defined
 // by the optional URL field.
 type Account struct {
@@ -58,6 +56,18 @@ type Wallet interface {
 
 	// SignTxWithPassphrase is identical to SignTx, but also takes a password
 	SignTxWithPassphrase(account Account, passphrase, rawData, txHash string) ([]byte, error)
+
+	// SignMessageV1 signs message with the TIP-191 v1 scheme of TronWeb, see
+	// MessageHashV1. The account must be unlocked, or its wallet open, and the
+	// signature has V as 27 or 28 like TronWeb's.
+	SignMessageV1(account Account, message []byte) ([]byte, error)
+
+	// SignMessageV2 signs message with the TIP-191 v2 scheme of TronWeb, see
+	// MessageHashV2, like SignMessageV1.
+	SignMessageV2(account Account, message []byte) ([]byte, error)
+
+	// SignTypedData signs the TIP-712 hash of typed data, like SignMessageV1.
+	SignTypedData(account Account, data *TypedData) ([]byte, error)
 }
 
 const (
@@ -90,6 +100,8 @@ type WalletEventType int
 //   keccak256("\x19Ethereum Signed Message:\n"${message length}${message}).
 //
 // This gives context to the signed message and prevents signing of transactions.
+// The header differs from the TIP-191 one of TronWeb, use MessageHashV2 for
+// messages signed or verified by Tron wallets.
 func TextHash(data []byte) []byte {
 	hash, _ := TextAndHash(data)
 	return hash
@@ -1,11 +1,8 @@
 package keystore
 
 import (
-	"bufio"
-	"bytes"
 	"encoding/json"
 	"fmt"
-	"os"
 	"path/filepath"
 	"sort"
 	"strings"
@@ -47,22 +44,24 @@ func (err *AmbiguousAddrError) Error() string {
 
 // accountCache is a live index of all accounts in the keystore.
 type accountCache struct {
-	keydir   string
+	storage  Storage
 	watcher  *watcher
 	mu       sync.Mutex
 	all      accountsByURL
 	byAddr   map[string][]Account
+	seeds    map[string]seedFile // Encrypted seed files by path
 	throttle *time.Timer
 	notify   chan struct{}
 	fileC    fileCache
 }
 
-func newAccountCache(keydir string) (*accountCache, chan struct{}) {
+func newAccountCache(storage Storage) (*accountCache, chan struct{}) {
 	ac := &accountCache{
-		keydir: keydir,
-		byAddr: make(map[string][]Account),
-		notify: make(chan struct{}, 1),
-		fileC:  fileCache{all: mapset.NewThreadUnsafeSet()},
+		storage: storage,
+		byAddr:  make(map[string][]Account),
+		seeds:   make(map[string]seedFile),
+		notify:  make(chan struct{}, 1),
+		fileC:   fileCache{all: mapset.NewThreadUnsafeSet()},
 	}
 	ac.watcher = newWatcher(ac)
 	return ac, ac.notify
@@ -77,6 +76,26 @@ func (ac *accountCache) accounts() []Account {
 	return cpy
 }
 
+// seedFiles returns the seed files in the keystore, sorted by URL.
+func (ac *accountCache) seedFiles() []seedFile {
+	ac.maybeReload()
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	cpy := make([]seedFile, 0, len(ac.seeds))
+	for _, sf := range ac.seeds {
+		cpy = append(cpy, sf)
+	}
+	sort.Slice(cpy, func(i, j int) bool { return cpy[i].url.Cmp(cpy[j].url) < 0 })
+	return cpy
+}
+
+// addSeed adds or replaces a seed file.
+func (ac *accountCache) addSeed(sf seedFile) {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	ac.seeds[sf.url.Path] = sf
+}
+
 func (ac *accountCache) hasAddress(addr Address) bool {
 	ac.maybeReload()
 	ac.mu.Lock()
@@ -91,7 +110,7 @@ func (ac *accountCache) add(newAccount Account) {
 	i := sort.Search(len(ac.all), func(i int) bool { return ac.all[i].URL.Cmp(newAccount.URL) >= 0 })
 	if i < len(ac.all) &&
 		ac.all[i].URL == newAccount.URL &&
-		bytes.Equal(ac.all[i].Address, newAccount.Address) {
+		ac.all[i].Address == newAccount.Address {
 		return
 	}
 	// newAccount is not in the cache.
@@ -118,6 +137,7 @@ func (ac *accountCache) delete(removed Account) {
 func (ac *accountCache) deleteByFile(path string) {
 	ac.mu.Lock()
 	defer ac.mu.Unlock()
+	delete(ac.seeds, path)
 	i := sort.Search(len(ac.all), func(i int) bool { return ac.all[i].URL.Path >= path })
 
 	if i < len(ac.all) && ac.all[i].URL.Path == path {
@@ -133,7 +153,7 @@ func (ac *accountCache) deleteByFile(path string) {
 
 func removeAccount(slice []Account, elem Account) []Account {
 	for i := range slice {
-		if slice[i].URL == elem.URL && bytes.Equal(slice[i].Address, elem.Address) {
+		if slice[i].URL == elem.URL && slice[i].Address == elem.Address {
 			return append(slice[:i], slice[i+1:]...)
 		}
 	}
@@ -146,20 +166,20 @@ func removeAccount(slice []Account, elem Account) []Account {
 func (ac *accountCache) find(a Account) (Account, error) {
 	// Limit search to address candidates if possible.
 	matches := ac.all
-	if a.Address != nil {
+	if !a.Address.IsZero() {
 		matches = ac.byAddr[a.Address.String()]
 	}
 	if a.URL.Path != "" {
 		// If only the basename is specified, complete the path.
 		if !strings.ContainsRune(a.URL.Path, filepath.Separator) {
-			a.URL.Path = filepath.Join(ac.keydir, a.URL.Path)
+			a.URL.Path = ac.storage.JoinPath(a.URL.Path)
 		}
 		for i := range matches {
 			if matches[i].URL == a.URL {
 				return matches[i], nil
 			}
 		}
-		if a.Address == nil {
+		if a.Address.IsZero() {
 			return Account{}, ErrNoMatch
 		}
 	}
@@ -217,7 +237,7 @@ func (ac *accountCache) close() {
 // updates the account cache accordingly
 func (ac *accountCache) scanAccounts() error {
 	// Scan the entire folder metadata for file changes
-	creates, deletes, updates, err := ac.fileC.scan(ac.keydir)
+	creates, deletes, updates, err := ac.fileC.scan(ac.storage)
 	if err != nil {
 		zap.L().Error("Failed to reload keystore contents", zap.Error(err))
 		return err
@@ -228,29 +248,37 @@ func (ac *accountCache) scanAccounts() error {
 	}
 	// Create a helper method to scan the contents of the key files
 	var (
-		buf = new(bufio.Reader)
 		key struct {
-			Address string `json:"address"`
+			Type     string            `json:"type"`
+			Address  string            `json:"address"`
+			Accounts []seedAccountJSON `json:"accounts"`
 		}
 	)
 	readAccount := func(path string) *Account {
-		fd, err := os.Open(path)
+		data, err := ac.storage.Read(path)
 		if err != nil {
-			fmt.Printf("NFailed to open keys: %v", err)
 			zap.L().Error("Failed to open keystore file", zap.String("path", path), zap.Error(err))
 			return nil
 		}
-		defer fd.Close()
-		buf.Reset(fd)
 		// Parse the address.
-		key.Address = ""
-		err = json.NewDecoder(buf).Decode(&key)
+		key.Type, key.Address, key.Accounts = "", "", nil
+		err = json.Unmarshal(data, &key)
+		if err == nil && key.Type == seedFileType {
+			// Seed files are tracked separately, they hold an HD wallet
+			sf, err := parseSeedFile(path, key.Accounts)
+			if err != nil {
+				zap.L().Error("Failed to decode seed file", zap.String("path", path), zap.Error(err))
+				return nil
+			}
+			ac.addSeed(sf)
+			return nil
+		}
 		addr, _ := HexToAddress(key.Address)
 
 		switch {
 		case err != nil:
 			fmt.Printf("Failed to decode keystore key: [%s] %+v", path, err)
-		case (addr == nil):
+		case addr.IsZero():
 			fmt.Printf("Failed to decode keystore key, missing or zero address: [%s] %+v", path, err)
 		default:
 			return &Account{
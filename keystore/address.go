@@ -2,9 +2,14 @@ package keystore
 
 import (
 	"crypto/ecdsa"
-	"math/big"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
 
 	"github.com/bytejedi/tron-sdk-go/utils"
+	ethcmn "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 )
 
@@ -13,52 +18,180 @@ const (
 	HashLength = 32
 	// AddressLength is the expected length of the address
 	AddressLength = 21
+	// EVMAddressLength is the length of the address without the tron prefix,
+	// as used inside the TVM
+	EVMAddressLength = 20
 	// TronBytePrefix is the hex prefix to address
 	TronBytePrefix = byte(0x41)
 )
 
-// Address represents the 21 byte address of an Tron account.
-type Address []byte
+// ErrInvalidAddress is returned when a value cannot be parsed as a tron address.
+var ErrInvalidAddress = errors.New("invalid address")
+
+// Address represents the 21 byte address of an Tron account. A valid address
+// always starts with TronBytePrefix, the zero value is the empty address.
+type Address [AddressLength]byte
+
+// BytesToAddress converts b into an Address. It accepts the 21 byte form
+// starting with TronBytePrefix and the 20 byte evm form.
+func BytesToAddress(b []byte) (Address, error) {
+	var a Address
+	switch {
+	case len(b) == AddressLength && b[0] == TronBytePrefix:
+		copy(a[:], b)
+	case len(b) == EVMAddressLength:
+		a[0] = TronBytePrefix
+		copy(a[1:], b)
+	default:
+		return Address{}, fmt.Errorf("%w: %d bytes %x", ErrInvalidAddress, len(b), b)
+	}
+	return a, nil
+}
+
+// EVMToAddress converts a 20 byte evm address into a tron address.
+func EVMToAddress(addr ethcmn.Address) Address {
+	var a Address
+	a[0] = TronBytePrefix
+	copy(a[1:], addr[:])
+	return a
+}
+
+// ParseAddress parses s in any of the common textual address forms:
+//
+//	TCQRkmYMbb8bzrZfrtcokox8hwVmY3DCVP            base58check
+//	41 + 40 hex digits, optionally 0x prefixed      tron hex
+//	0x + 40 hex digits, or 40 bare hex digits       evm hex
+func ParseAddress(s string) (Address, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "T") {
+		return Base58ToAddress(s)
+	}
+	return HexToAddress(s)
+}
+
+// IsValidAddress reports whether s can be parsed by ParseAddress.
+func IsValidAddress(s string) bool {
+	_, err := ParseAddress(s)
+	return err == nil
+}
+
+// HexToAddress parses a hex encoded address, either the 21 byte tron form
+// or the 20 byte evm form, with or without the 0x prefix.
+func HexToAddress(s string) (Address, error) {
+	h := s
+	if utils.Has0xPrefix(h) {
+		h = h[2:]
+	}
+	if len(h) != 2*AddressLength && len(h) != 2*EVMAddressLength {
+		return Address{}, fmt.Errorf("%w: %q has invalid length", ErrInvalidAddress, s)
+	}
+	b, err := hex.DecodeString(h)
+	if err != nil {
+		return Address{}, fmt.Errorf("%w: %q: %v", ErrInvalidAddress, s, err)
+	}
+	return BytesToAddress(b)
+}
+
+// Base58ToAddress parses a base58check encoded address.
+func Base58ToAddress(s string) (Address, error) {
+	b, err := utils.DecodeCheck(s)
+	if err != nil {
+		return Address{}, fmt.Errorf("%w: %q: %v", ErrInvalidAddress, s, err)
+	}
+	if len(b) != AddressLength || b[0] != TronBytePrefix {
+		return Address{}, fmt.Errorf("%w: %q is not a tron address", ErrInvalidAddress, s)
+	}
+	var a Address
+	copy(a[:], b)
+	return a, nil
+}
+
+// IsValid reports whether a is a non-empty address with the tron prefix.
+func (a Address) IsValid() bool {
+	return a[0] == TronBytePrefix
+}
+
+// IsZero reports whether a is the empty address.
+func (a Address) IsZero() bool {
+	return a == Address{}
+}
 
 // Bytes get bytes from address
 func (a Address) Bytes() []byte {
 	return a[:]
 }
 
+// EVM returns the 20 byte address used inside the TVM.
+func (a Address) EVM() ethcmn.Address {
+	return ethcmn.BytesToAddress(a[1:])
+}
+
 // Hex get bytes from address in string
 func (a Address) Hex() string {
 	return utils.ToHex(a[:])
 }
 
-// HexToAddress returns Address with byte values of s.
-// If s is larger than len(h), s will be cropped from the left.
-func HexToAddress(s string) (Address, error) {
-	return utils.FromHex(s)
+// String implements fmt.Stringer. The empty address is rendered as "".
+func (a Address) String() string {
+	if a.IsZero() {
+		return ""
+	}
+	return utils.EncodeCheck(a[:])
 }
 
-// Base58ToAddress returns Address with byte values of s.
-func Base58ToAddress(s string) (Address, error) {
-	addr, err := utils.DecodeCheck(s)
+// MarshalText implements encoding.TextMarshaler using the base58 form.
+func (a Address) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. Any format understood
+// by ParseAddress is accepted, the empty string yields the empty address.
+func (a *Address) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*a = Address{}
+		return nil
+	}
+	addr, err := ParseAddress(string(text))
 	if err != nil {
-		return nil, err
+		return err
 	}
-	return addr, nil
+	*a = addr
+	return nil
 }
 
-// String implements fmt.Stringer.
-func (a Address) String() string {
-	if a[0] == 0 {
-		return new(big.Int).SetBytes(a.Bytes()).String()
+// Value implements driver.Valuer, storing the address as its 21 raw bytes.
+// The empty address is stored as NULL.
+func (a Address) Value() (driver.Value, error) {
+	if a.IsZero() {
+		return nil, nil
 	}
-	return utils.EncodeCheck(a.Bytes())
+	return a.Bytes(), nil
+}
+
+// Scan implements sql.Scanner. Raw 20/21 byte values as well as any string
+// understood by ParseAddress are accepted.
+func (a *Address) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*a = Address{}
+		return nil
+	case []byte:
+		if len(v) == AddressLength || len(v) == EVMAddressLength {
+			addr, err := BytesToAddress(v)
+			if err != nil {
+				return err
+			}
+			*a = addr
+			return nil
+		}
+		return a.UnmarshalText(v)
+	case string:
+		return a.UnmarshalText([]byte(v))
+	}
+	return fmt.Errorf("%w: cannot scan %T into Address", ErrInvalidAddress, src)
 }
 
 // PubkeyToAddress returns address from ecdsa public key
 func PubkeyToAddress(p ecdsa.PublicKey) Address {
-	address := crypto.PubkeyToAddress(p)
-
-	addressTron := make([]byte, 0)
-	addressTron = append(addressTron, TronBytePrefix)
-	addressTron = append(addressTron, address.Bytes()...)
-	return addressTron
+	return EVMToAddress(crypto.PubkeyToAddress(p))
 }
@@ -0,0 +1,85 @@
+package keystore
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParseAddress(t *testing.T) {
+	const (
+		b58   = "TCQRkmYMbb8bzrZfrtcokox8hwVmY3DCVP"
+		tron  = "411a6f3cb4b1d2bc5a1f9b7cdb5f2e4fd6a9dc7e4c"
+		evm20 = "1a6f3cb4b1d2bc5a1f9b7cdb5f2e4fd6a9dc7e4c"
+	)
+	want, err := Base58ToAddress(b58)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !want.IsValid() || want.String() != b58 {
+		t.Fatalf("unexpected address %x", want.Bytes())
+	}
+	hexForm := want.Hex()[2:]
+	for _, s := range []string{b58, " " + b58 + " ", hexForm, "0x" + hexForm, "0x" + hexForm[2:], hexForm[2:]} {
+		addr, err := ParseAddress(s)
+		if err != nil {
+			t.Errorf("%q: %v", s, err)
+			continue
+		}
+		if addr != want {
+			t.Errorf("%q: have %s, want %s", s, addr, want)
+		}
+	}
+
+	for _, s := range []string{"", "T", b58[:len(b58)-1] + "Q", "42" + hexForm[2:], hexForm[:40] + "zz", "0x1234", tron + "00", evm20 + "0"} {
+		if _, err := ParseAddress(s); !errors.Is(err, ErrInvalidAddress) {
+			t.Errorf("%q: expected ErrInvalidAddress, got %v", s, err)
+		}
+	}
+
+	var zero Address
+	if zero.IsValid() || zero.String() != "" {
+		t.Error("zero address should be invalid and render empty")
+	}
+}
+
+func TestAddressMarshaling(t *testing.T) {
+	addr, _ := ParseAddress("TCQRkmYMbb8bzrZfrtcokox8hwVmY3DCVP")
+	type wrapper struct {
+		Address Address `json:"address"`
+	}
+	out, err := json.Marshal(wrapper{addr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back wrapper
+	if err := json.Unmarshal(out, &back); err != nil {
+		t.Fatal(err)
+	}
+	if back.Address != addr {
+		t.Errorf("json round trip: have %s, want %s (%s)", back.Address, addr, out)
+	}
+	if err := json.Unmarshal([]byte(`{"address":"0x`+addr.Hex()[4:]+`"}`), &back); err != nil || back.Address != addr {
+		t.Errorf("json evm hex: have %s, err %v", back.Address, err)
+	}
+	if err := json.Unmarshal([]byte(`{"address":"nope"}`), &back); err == nil {
+		t.Error("expected error for invalid address")
+	}
+
+	v, err := addr.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var scanned Address
+	for _, src := range []interface{}{v, addr.String(), addr.Bytes()[1:]} {
+		if err := scanned.Scan(src); err != nil || scanned != addr {
+			t.Errorf("scan %T: have %s, err %v", src, scanned, err)
+		}
+	}
+	if err := scanned.Scan(nil); err != nil || !scanned.IsZero() {
+		t.Errorf("scan nil: have %s, err %v", scanned, err)
+	}
+	if v, _ := scanned.Value(); v != nil {
+		t.Errorf("zero address should be stored as NULL, have %v", v)
+	}
+}
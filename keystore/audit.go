@@ -0,0 +1,175 @@
+package keystore
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/user"
+	"strings"
+	"time"
+
+	"github.com/bytejedi/tron-sdk-go/proto/core"
+	"github.com/bytejedi/tron-sdk-go/proto/core/contract"
+	"google.golang.org/protobuf/proto"
+)
+
+// Operations recorded in the audit log.
+const (
+	AuditUnlock    = "Unlock"
+	AuditSignTx    = "SignTx"
+	AuditSignHash  = "SignHash"
+	AuditExport    = "Export"
+	AuditImport    = "Import"
+	AuditUpdate    = "Update"
+	AuditDelete    = "Delete"
+	AuditReencrypt = "Reencrypt"
+	AuditRollback  = "RollbackReencrypt"
+)
+
+// AuditOK is the outcome of a successful operation.
+const AuditOK = "ok"
+
+// AuditRecord describes a keystore operation.
+type AuditRecord struct {
+	Time     time.Time `json:"time"`
+	Op       string    `json:"op"`
+	Account  Address   `json:"account"`
+	Wallet   string    `json:"wallet,omitempty"`   // URL of the HD wallet of a mnemonic or seed operation
+	TxID     string    `json:"txId,omitempty"`     // Hex encoded id of a signed transaction
+	Hash     string    `json:"hash,omitempty"`     // Hex encoded hash signed with SignHash
+	Contract string    `json:"contract,omitempty"` // Summary of the contracts of a signed transaction
+	Caller   string    `json:"caller"`
+	Outcome  string    `json:"outcome"` // AuditOK or the error
+}
+
+// AuditSink receives the audit records of a KeyStore. Record is called
+// synchronously before the operation returns and must be safe for
+// concurrent use.
+type AuditSink interface {
+	Record(rec *AuditRecord) error
+}
+
+// SetAuditSink makes the keystore record every Unlock, TimedUnlock, SignTx,
+// SignHash, Export, Import, Update and Delete, including the passphrase
+// variants, in sink. The mnemonic and seed operations of HD wallets, the
+// signatures of their accounts, share backups and restores, Reencrypt and
+// RollbackReencrypt are recorded as well. The caller identifies the user of
+// the keystore where no WithCaller view is used, if empty the user, host and
+// process id are used. A nil sink disables auditing.
+//
+// If a record can't be written the operation fails with the sink's error, in
+// particular no signature is handed out unrecorded.
+func (ks *KeyStore) SetAuditSink(sink AuditSink, caller string) {
+	if caller == "" {
+		caller = defaultCaller()
+	}
+	ks.auditMu.Lock()
+	defer ks.auditMu.Unlock()
+
+	ks.auditSink, ks.auditCaller = sink, caller
+}
+
+// WithCaller returns a view of the keystore recording caller in the audit
+// records of its operations, including those done with the wallets returned
+// by its Wallets. It is meant for services acting on behalf of several
+// users, deriving a view for every request.
+func (ks *KeyStore) WithCaller(caller string) *KeyStore {
+	return &KeyStore{keyStoreState: ks.keyStoreState, caller: caller}
+}
+
+// audit records the outcome err of an operation, returning err or the error
+// of the sink.
+func (ks *KeyStore) audit(rec AuditRecord, err error) error {
+	ks.auditMu.RLock()
+	sink, caller := ks.auditSink, ks.auditCaller
+	ks.auditMu.RUnlock()
+
+	if sink == nil {
+		return err
+	}
+	if ks.caller != "" {
+		caller = ks.caller
+	}
+	rec.Time = time.Now().UTC()
+	rec.Caller = caller
+	rec.Outcome = AuditOK
+	if err != nil {
+		rec.Outcome = err.Error()
+	}
+	if serr := sink.Record(&rec); serr != nil {
+		return fmt.Errorf("audit failed: %v", serr)
+	}
+	return err
+}
+
+// defaultCaller identifies the current process.
+func defaultCaller() string {
+	name := fmt.Sprint(os.Getuid())
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s@%s[%d]", name, host, os.Getpid())
+}
+
+// rawDataSummary summarizes the contracts of the hex encoded raw data of a
+// transaction, returning "" if it can't be decoded.
+func rawDataSummary(rawData string) string {
+	if rawData == "" {
+		return ""
+	}
+	data, err := hex.DecodeString(strings.TrimPrefix(rawData, "0x"))
+	if err != nil {
+		return ""
+	}
+	raw := new(core.TransactionRaw)
+	if err := proto.Unmarshal(data, raw); err != nil {
+		return ""
+	}
+	return contractSummary(raw)
+}
+
+// contractSummary describes the contracts of a transaction, with the
+// recipients and amounts of transfers and the methods of contract calls.
+func contractSummary(raw *core.TransactionRaw) string {
+	var parts []string
+	for _, c := range raw.GetContract() {
+		s := c.GetType().String()
+		switch c.GetType() {
+		case core.Transaction_Contract_TransferContract:
+			tc := new(contract.TransferContract)
+			if c.GetParameter().UnmarshalTo(tc) == nil {
+				s += fmt.Sprintf(" owner=%s to=%s amount=%d", summaryAddress(tc.GetOwnerAddress()), summaryAddress(tc.GetToAddress()), tc.GetAmount())
+			}
+		case core.Transaction_Contract_TransferAssetContract:
+			tc := new(contract.TransferAssetContract)
+			if c.GetParameter().UnmarshalTo(tc) == nil {
+				s += fmt.Sprintf(" owner=%s to=%s asset=%s amount=%d", summaryAddress(tc.GetOwnerAddress()), summaryAddress(tc.GetToAddress()), tc.GetAssetName(), tc.GetAmount())
+			}
+		case core.Transaction_Contract_TriggerSmartContract:
+			tc := new(contract.TriggerSmartContract)
+			if c.GetParameter().UnmarshalTo(tc) == nil {
+				s += fmt.Sprintf(" owner=%s contract=%s", summaryAddress(tc.GetOwnerAddress()), summaryAddress(tc.GetContractAddress()))
+				if data := tc.GetData(); len(data) >= 4 {
+					s += fmt.Sprintf(" method=%x", data[:4])
+				}
+				if v := tc.GetCallValue(); v != 0 {
+					s += fmt.Sprintf(" callValue=%d", v)
+				}
+				if v := tc.GetCallTokenValue(); v != 0 {
+					s += fmt.Sprintf(" tokenId=%d tokenValue=%d", tc.GetTokenId(), v)
+				}
+			}
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// summaryAddress formats a raw address, falling back to hex for invalid ones.
+func summaryAddress(b []byte) string {
+	if addr, err := BytesToAddress(b); err == nil {
+		return addr.String()
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,179 @@
+package keystore
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// ErrAuditTampered is returned when an audit log fails the verification.
+var ErrAuditTampered = errors.New("audit log tampered")
+
+// MinAuditKeyLength is the minimum length of the key of an audit log.
+const MinAuditKeyLength = 16
+
+// errAuditKey is returned for audit log keys shorter than MinAuditKeyLength.
+var errAuditKey = fmt.Errorf("audit log key must be at least %d bytes", MinAuditKeyLength)
+
+// auditEntry is a line of an audit file. Hash chains the entry to its
+// predecessor: hmac-sha256(key, prev || seq || record), with seq as 8 byte
+// big endian and record as the JSON of the line.
+type auditEntry struct {
+	Seq    uint64          `json:"seq"`
+	Record json.RawMessage `json:"record"`
+	Prev   string          `json:"prev"`
+	Hash   string          `json:"hash"`
+}
+
+// auditGenesis is the predecessor hash of the first entry.
+var auditGenesis = hex.EncodeToString(make([]byte, sha256.Size))
+
+// auditHash computes the chained hash of an entry.
+func auditHash(key []byte, prev string, seq uint64, record []byte) (string, error) {
+	p, err := hex.DecodeString(prev)
+	if err != nil {
+		return "", err
+	}
+	var s [8]byte
+	binary.BigEndian.PutUint64(s[:], seq)
+
+	h := hmac.New(sha256.New, key)
+	h.Write(p)
+	h.Write(s[:])
+	h.Write(record)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// AuditFile is an AuditSink appending hash-chained JSON lines to a file.
+// Every record commits to all records before it under a secret key, so
+// modifying, removing or reordering records breaks the chain, and without
+// the key it can't be rebuilt. The key must therefore be kept apart from the
+// log, e.g. in a secrets manager. Truncating the end of the log can only be
+// detected against a head hash saved outside of the log.
+type AuditFile struct {
+	f    *os.File
+	key  []byte
+	seq  uint64
+	head string
+	mu   sync.Mutex
+}
+
+// OpenAuditFile opens or creates the audit log at path, chained with key. An
+// existing log is verified first and not appended to if it was tampered
+// with.
+func OpenAuditFile(path string, key []byte) (*AuditFile, error) {
+	if len(key) < MinAuditKeyLength {
+		return nil, errAuditKey
+	}
+	n, head, err := VerifyAuditFile(path, key)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &AuditFile{f: f, key: append([]byte(nil), key...), seq: uint64(n), head: head}, nil
+}
+
+// Record implements AuditSink, appending rec and syncing it to disk.
+func (af *AuditFile) Record(rec *AuditRecord) error {
+	record, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	af.mu.Lock()
+	defer af.mu.Unlock()
+
+	hash, err := auditHash(af.key, af.head, af.seq, record)
+	if err != nil {
+		return err
+	}
+	line, err := json.Marshal(auditEntry{Seq: af.seq, Record: record, Prev: af.head, Hash: hash})
+	if err != nil {
+		return err
+	}
+	if _, err := af.f.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	if err := af.f.Sync(); err != nil {
+		return err
+	}
+	af.seq, af.head = af.seq+1, hash
+	return nil
+}
+
+// Head returns the hash of the last record. It has to be saved outside of the
+// log, e.g. in a monitoring system, to detect truncation later on.
+func (af *AuditFile) Head() string {
+	af.mu.Lock()
+	defer af.mu.Unlock()
+	return af.head
+}
+
+// Close closes the file.
+func (af *AuditFile) Close() error {
+	return af.f.Close()
+}
+
+// VerifyAuditFile verifies the audit log at path, see VerifyAuditLog.
+func VerifyAuditFile(path string, key []byte) (int, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, auditGenesis, err
+	}
+	defer f.Close()
+	return VerifyAuditLog(f, key)
+}
+
+// VerifyAuditLog checks the hash chain of an audit log with key, returning
+// the number of records and the hash of the last one, which should be
+// compared with the last saved head. Errors caused by a broken chain wrap
+// ErrAuditTampered.
+func VerifyAuditLog(r io.Reader, key []byte) (int, string, error) {
+	if len(key) < MinAuditKeyLength {
+		return 0, auditGenesis, errAuditKey
+	}
+	var (
+		br   = bufio.NewReader(r)
+		head = auditGenesis
+		n    = 0
+	)
+	for {
+		line, err := br.ReadBytes('\n')
+		if err == io.EOF && len(line) == 0 {
+			return n, head, nil
+		}
+		if err != nil && err != io.EOF {
+			return n, head, err
+		}
+		if !bytes.HasSuffix(line, []byte{'\n'}) {
+			return n, head, fmt.Errorf("%w: record %d is truncated", ErrAuditTampered, n)
+		}
+		var entry auditEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return n, head, fmt.Errorf("%w: record %d: %v", ErrAuditTampered, n, err)
+		}
+		if entry.Seq != uint64(n) {
+			return n, head, fmt.Errorf("%w: record %d has sequence number %d", ErrAuditTampered, n, entry.Seq)
+		}
+		if entry.Prev != head {
+			return n, head, fmt.Errorf("%w: record %d does not follow its predecessor", ErrAuditTampered, n)
+		}
+		hash, err := auditHash(key, entry.Prev, entry.Seq, entry.Record)
+		if err != nil || !hmac.Equal([]byte(hash), []byte(entry.Hash)) {
+			return n, head, fmt.Errorf("%w: record %d hash mismatch", ErrAuditTampered, n)
+		}
+		head = hash
+		n++
+	}
+}
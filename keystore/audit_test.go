@@ -0,0 +1,264 @@
+package keystore
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/bytejedi/tron-sdk-go/proto/core"
+	"github.com/bytejedi/tron-sdk-go/proto/core/contract"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+type failingSink struct{}
+
+func (failingSink) Record(*AuditRecord) error { return errors.New("disk full") }
+
+func TestAuditLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tron-keystore-audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+	key := []byte("0123456789abcdef0123456789abcdef")
+
+	if _, err := OpenAuditFile(path, key[:8]); err != errAuditKey {
+		t.Errorf("short key: have %v, want %v", err, errAuditKey)
+	}
+	af, err := OpenAuditFile(path, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks := NewKeyStoreWithStorage(NewMemoryStorage(), LightScryptN, LightScryptP)
+	ks.SetAuditSink(af, "tester")
+
+	a, err := ks.NewAccount("pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.Unlock(a, "pw"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.SignHash(a, make([]byte, 32)); err != nil {
+		t.Fatal(err)
+	}
+	param, err := anypb.New(&contract.TransferContract{OwnerAddress: a.Address.Bytes(), ToAddress: a.Address.Bytes(), Amount: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := &core.Transaction{RawData: &core.TransactionRaw{
+		Contract: []*core.Transaction_Contract{{Type: core.Transaction_Contract_TransferContract, Parameter: param}},
+	}}
+	if _, err := ks.SignTx(a, tx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.SignHashWithPassphrase(a, "wrong", make([]byte, 32)); err != ErrDecrypt {
+		t.Fatalf("wrong passphrase: have %v, want %v", err, ErrDecrypt)
+	}
+	if _, err := ks.Export(a, "pw", "pw"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.Update(a, "pw", "new"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.Delete(a, "new"); err != nil {
+		t.Fatal(err)
+	}
+	af.Close()
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records []AuditRecord
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var entry auditEntry
+		var rec AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(entry.Record, &rec); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, rec)
+	}
+	ops := []string{AuditUnlock, AuditSignHash, AuditSignTx, AuditSignHash, AuditExport, AuditUpdate, AuditDelete}
+	if len(records) != len(ops) {
+		t.Fatalf("have %d records, want %d", len(records), len(ops))
+	}
+	for i, rec := range records {
+		if rec.Op != ops[i] || rec.Account != a.Address || rec.Caller != "tester" {
+			t.Errorf("record %d: unexpected %+v", i, rec)
+		}
+	}
+	if records[2].TxID == "" || records[2].Contract == "" || records[2].Outcome != AuditOK {
+		t.Errorf("incomplete SignTx record %+v", records[2])
+	}
+	if records[3].Outcome != ErrDecrypt.Error() {
+		t.Errorf("failed operation recorded as %q", records[3].Outcome)
+	}
+
+	n, head, err := VerifyAuditFile(path, key)
+	if err != nil || n != len(ops) {
+		t.Fatalf("verify: %d records, %v", n, err)
+	}
+	// Reopening continues the chain
+	af, err = OpenAuditFile(path, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if af.Head() != head {
+		t.Errorf("reopened head %s, want %s", af.Head(), head)
+	}
+	af.Close()
+
+	// Any modification or removal of a record is detected, also if the
+	// chain is rebuilt without the key
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	var forged bytes.Buffer
+	prev := auditGenesis
+	for i, line := range lines[:len(lines)-1] {
+		var entry auditEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			t.Fatal(err)
+		}
+		if i == 3 {
+			entry.Record = bytes.Replace(entry.Record, []byte(`"outcome":"could not decrypt`), []byte(`"outcome":"could not encrypt`), 1)
+		}
+		entry.Prev = prev
+		entry.Hash, _ = auditHash([]byte("guessed key 0123"), entry.Prev, entry.Seq, entry.Record)
+		prev = entry.Hash
+		out, _ := json.Marshal(entry)
+		forged.Write(append(out, '\n'))
+	}
+	tampered := [][]byte{
+		bytes.Replace(data, []byte(`"outcome":"could not decrypt`), []byte(`"outcome":"could not encrypt`), 1),
+		bytes.Join(append(append([][]byte{}, lines[:2]...), lines[3:]...), nil),
+		bytes.Join(append([][]byte{lines[1], lines[0]}, lines[2:]...), nil),
+		data[:len(data)-3],
+		forged.Bytes(),
+	}
+	for i, bad := range tampered {
+		if _, _, err := VerifyAuditLog(bytes.NewReader(bad), key); !errors.Is(err, ErrAuditTampered) {
+			t.Errorf("tampering %d: have %v, want %v", i, err, ErrAuditTampered)
+		}
+	}
+	if err := ioutil.WriteFile(path, tampered[0], 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenAuditFile(path, key); !errors.Is(err, ErrAuditTampered) {
+		t.Errorf("opened tampered log: %v", err)
+	}
+}
+
+func TestAuditSinkFailure(t *testing.T) {
+	ks := NewKeyStoreWithStorage(NewMemoryStorage(), LightScryptN, LightScryptP)
+	a, err := ks.NewAccount("pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.Unlock(a, "pw"); err != nil {
+		t.Fatal(err)
+	}
+	ks.SetAuditSink(failingSink{}, "")
+	if sig, err := ks.SignHash(a, make([]byte, 32)); err == nil || sig != nil {
+		t.Errorf("signature handed out without audit record")
+	}
+}
+
+// memorySink collects audit records.
+type memorySink struct {
+	records []AuditRecord
+	mu      sync.Mutex
+}
+
+func (s *memorySink) Record(rec *AuditRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, *rec)
+	return nil
+}
+
+func TestAuditCaller(t *testing.T) {
+	sink := new(memorySink)
+	ks := NewKeyStoreWithStorage(NewMemoryStorage(), LightScryptN, LightScryptP)
+	ks.SetAuditSink(sink, "service")
+	alice := ks.WithCaller("alice")
+
+	a, err := ks.NewAccount("pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := alice.Unlock(a, "pw"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.SignHash(a, make([]byte, 32)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := alice.Wallets()[0].(*keystoreWallet).SignData(a, "", []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	// HD wallets and their accounts are audited as well
+	w, err := alice.ImportMnemonic(testMnemonic, "", "pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Open("pw"); err != nil {
+		t.Fatal(err)
+	}
+	acc, err := w.Derive(DefaultBaseDerivationPath, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.SignData(acc, "", []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.SignTxWithPassphrase(acc, "wrong", "", hex.EncodeToString(make([]byte, 32))); err == nil {
+		t.Fatal("signed with wrong passphrase")
+	}
+	if _, err := ks.ExportMnemonic(w, "pw"); err != nil {
+		t.Fatal(err)
+	}
+	if err := alice.Reencrypt("pw", "new", ReencryptOptions{KDF: LightScryptKDF, Backup: NewMemoryStorage()}); err != nil {
+		t.Fatal(err)
+	}
+	if err := alice.DeleteHDWallet(w, "new"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		op, caller string
+		hd         bool
+	}{
+		{AuditUnlock, "alice", false},
+		{AuditSignHash, "service", false},
+		{AuditSignHash, "alice", false},
+		{AuditImport, "alice", true},
+		{AuditSignHash, "alice", true},
+		{AuditSignTx, "alice", true},
+		{AuditExport, "service", true},
+		{AuditReencrypt, "alice", false},
+		{AuditDelete, "alice", true},
+	}
+	if len(sink.records) != len(want) {
+		t.Fatalf("have %d records, want %d: %+v", len(sink.records), len(want), sink.records)
+	}
+	for i, rec := range sink.records {
+		if rec.Op != want[i].op || rec.Caller != want[i].caller || (rec.Wallet == w.URL().String()) != want[i].hd {
+			t.Errorf("record %d: unexpected %+v", i, rec)
+		}
+	}
+	if rec := sink.records[5]; rec.Account != acc.Address || rec.Outcome != ErrDecrypt.Error() {
+		t.Errorf("unexpected HD signature record %+v", rec)
+	}
+}
@@ -3,8 +3,43 @@ package keystore
 import (
 	"crypto/aes"
 	"crypto/cipher"
+	"fmt"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+const (
+	// CipherAES128CTR is the V3 cipher, authenticated with a separate keccak
+	// MAC. It is the default, as it is the only cipher understood by other
+	// TRON wallets such as TronLink and wallet-cli.
+	CipherAES128CTR = "aes-128-ctr"
+
+	// CipherAES256GCM is AES-256 in the GCM authenticated mode. The tag is
+	// appended to the ciphertext and the mac field is left empty.
+	CipherAES256GCM = "aes-256-gcm"
+
+	// CipherXChaCha20Poly1305 is the XChaCha20-Poly1305 AEAD with a 24 byte
+	// nonce. The tag is appended to the ciphertext and the mac field is left
+	// empty.
+	CipherXChaCha20Poly1305 = "xchacha20-poly1305"
 )
 
+// newAEAD returns the authenticated cipher with the given identifier, keyed
+// with the 32 byte key.
+func newAEAD(name string, key []byte) (cipher.AEAD, error) {
+	switch name {
+	case CipherAES256GCM:
+		aesBlock, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCM(aesBlock)
+	case CipherXChaCha20Poly1305:
+		return chacha20poly1305.NewX(key)
+	}
+	return nil, fmt.Errorf("Cipher not supported: %v", name)
+}
+
 func aesCTRXOR(key, inText, iv []byte) ([]byte, error) {
 	// AES-128 is selected due to size of encryptKey.
 	aesBlock, err := aes.NewCipher(key)
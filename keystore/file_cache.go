@@ -1,9 +1,7 @@
 package keystore
 
 import (
-	"io/ioutil"
 	"os"
-	"path/filepath"
 	"strings"
 	"sync"
 	"time"
@@ -19,13 +17,13 @@ type fileCache struct {
 	mu      sync.RWMutex
 }
 
-// scan performs a new scan on the given directory, compares against the already
-// cached filenames, and returns file sets: creates, deletes, updates.
-func (fc *fileCache) scan(keyDir string) (mapset.Set, mapset.Set, mapset.Set, error) {
+// scan performs a new scan on the given storage, compares against the already
+// cached paths, and returns file sets: creates, deletes, updates.
+func (fc *fileCache) scan(storage Storage) (mapset.Set, mapset.Set, mapset.Set, error) {
 	t0 := time.Now()
 
-	// List all the failes from the keystore folder
-	files, err := ioutil.ReadDir(keyDir)
+	// List all the files from the keystore storage
+	files, err := storage.List()
 	if err != nil {
 		return nil, nil, nil, err
 	}
@@ -40,18 +38,12 @@ func (fc *fileCache) scan(keyDir string) (mapset.Set, mapset.Set, mapset.Set, er
 
 	var newLastMod time.Time
 	for _, fi := range files {
-		path := filepath.Join(keyDir, fi.Name())
-		// Skip any non-key files from the folder
-		if nonKeyFile(fi) {
-			zap.L().Info("Ignoring file on account scan", zap.String("path", path))
-			continue
-		}
 		// Gather the set of all and fresly modified files
-		all.Add(path)
+		all.Add(fi.Path)
 
-		modified := fi.ModTime()
+		modified := fi.ModTime
 		if modified.After(fc.lastMod) {
-			mods.Add(path)
+			mods.Add(fi.Path)
 		}
 		if modified.After(newLastMod) {
 			newLastMod = modified
@@ -0,0 +1,155 @@
+package keystore
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/tyler-smith/go-bip39"
+)
+
+// Mnemonic entropy sizes in bits, yielding 12 and 24 word mnemonics.
+const (
+	MnemonicEntropy12Words = 128
+	MnemonicEntropy24Words = 256
+)
+
+var (
+	// ErrInvalidMnemonic is returned when a mnemonic fails the BIP39 word list
+	// or checksum validation.
+	ErrInvalidMnemonic = errors.New("invalid mnemonic")
+
+	// ErrInvalidSeed is returned when a seed is too short or too long for BIP32.
+	ErrInvalidSeed = errors.New("seed length must be between 128 and 512 bits")
+
+	// ErrInvalidChild is returned for the (astronomically unlikely) BIP32
+	// indexes that do not produce a valid key. Callers should skip the index.
+	ErrInvalidChild = errors.New("derived key is invalid, skip this index")
+)
+
+// masterKeySalt is the HMAC key BIP32 uses to derive the master key.
+var masterKeySalt = []byte("Bitcoin seed")
+
+// NewMnemonic generates a random BIP39 mnemonic with the given entropy in
+// bits, which must be a multiple of 32 between 128 and 256.
+func NewMnemonic(bits int) (string, error) {
+	entropy, err := bip39.NewEntropy(bits)
+	if err != nil {
+		return "", err
+	}
+	return bip39.NewMnemonic(entropy)
+}
+
+// ValidateMnemonic checks the words and checksum of a BIP39 mnemonic.
+func ValidateMnemonic(mnemonic string) error {
+	if _, err := bip39.EntropyFromMnemonic(mnemonic); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidMnemonic, err)
+	}
+	return nil
+}
+
+// NewSeed derives the BIP39 seed of a validated mnemonic. The password is the
+// optional BIP39 passphrase, sometimes called the 25th word. Redundant
+// whitespace between the words is ignored.
+func NewSeed(mnemonic, password string) ([]byte, error) {
+	mnemonic = strings.Join(strings.Fields(mnemonic), " ")
+	if err := ValidateMnemonic(mnemonic); err != nil {
+		return nil, err
+	}
+	return bip39.NewSeed(mnemonic, password), nil
+}
+
+// extendedKey is a BIP32 extended private key.
+type extendedKey struct {
+	key       []byte // 32 byte private key
+	chainCode []byte // 32 byte chain code
+}
+
+// newMasterKey derives the BIP32 master key of seed.
+func newMasterKey(seed []byte) (*extendedKey, error) {
+	if len(seed) < 16 || len(seed) > 64 {
+		return nil, ErrInvalidSeed
+	}
+	mac := hmac.New(sha512.New, masterKeySalt)
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+
+	k := new(big.Int).SetBytes(sum[:32])
+	if k.Sign() == 0 || k.Cmp(crypto.S256().Params().N) >= 0 {
+		return nil, ErrInvalidSeed
+	}
+	return &extendedKey{key: sum[:32], chainCode: sum[32:]}, nil
+}
+
+// child derives the private child key at index i.
+func (k *extendedKey) child(i uint32) (*extendedKey, error) {
+	var data []byte
+	if i >= HardenedKeyStart {
+		data = append([]byte{0}, k.key...)
+	} else {
+		priv, err := crypto.ToECDSA(k.key)
+		if err != nil {
+			return nil, err
+		}
+		data = crypto.CompressPubkey(&priv.PublicKey)
+	}
+	var index [4]byte
+	binary.BigEndian.PutUint32(index[:], i)
+	data = append(data, index[:]...)
+
+	mac := hmac.New(sha512.New, k.chainCode)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+
+	n := crypto.S256().Params().N
+	il := new(big.Int).SetBytes(sum[:32])
+	if il.Cmp(n) >= 0 {
+		return nil, ErrInvalidChild
+	}
+	il.Add(il, new(big.Int).SetBytes(k.key))
+	il.Mod(il, n)
+	if il.Sign() == 0 {
+		return nil, ErrInvalidChild
+	}
+	return &extendedKey{key: math.PaddedBigBytes(il, 32), chainCode: sum[32:]}, nil
+}
+
+// derive walks path starting from k. The returned key is always a copy, the
+// intermediate keys are wiped.
+func (k *extendedKey) derive(path DerivationPath) (*extendedKey, error) {
+	cur := &extendedKey{
+		key:       append([]byte(nil), k.key...),
+		chainCode: append([]byte(nil), k.chainCode...),
+	}
+	for i, n := range path {
+		next, err := cur.child(n)
+		cur.zero()
+		if err != nil {
+			return nil, fmt.Errorf("derive %s at component %d: %w", path, i, err)
+		}
+		cur = next
+	}
+	return cur, nil
+}
+
+// privateKey returns k as an ecdsa private key.
+func (k *extendedKey) privateKey() (*ecdsa.PrivateKey, error) {
+	return crypto.ToECDSA(k.key)
+}
+
+// zero wipes the key material of k.
+func (k *extendedKey) zero() {
+	for i := range k.key {
+		k.key[i] = 0
+	}
+	for i := range k.chainCode {
+		k.chainCode[i] = 0
+	}
+}
@@ -0,0 +1,514 @@
+package keystore
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/tyler-smith/go-bip39"
+)
+
+// HDWalletScheme is the protocol scheme prefixing in-memory HD wallet URLs.
+const HDWalletScheme = "hd"
+
+// DefaultGapLimit is the number of consecutive unused addresses after which
+// account discovery stops, as recommended by BIP44.
+const DefaultGapLimit = 20
+
+// ErrPathMismatch is returned if the derivation path pinned for an account
+// derives a different address, e.g. after the pinned accounts were tampered
+// with or the wallet was opened with another BIP39 passphrase.
+var ErrPathMismatch = errors.New("derivation path does not match account")
+
+// seedFunc recovers the BIP39 seed of a wallet given the passphrase passed
+// to Open.
+type seedFunc func(passphrase string) ([]byte, error)
+
+// HDWallet is a BIP32/BIP39/BIP44 hierarchical deterministic wallet. It is
+// created closed; Open derives the master key from the seed and Close wipes
+// it again. Pinned accounts stay listed while the wallet is closed, but can
+// only be signed with after reopening it or by using the WithPassphrase
+// signing methods.
+type HDWallet struct {
+	*hdWalletState
+
+	// audit, if set, records the key exports and signatures of the wallet
+	audit func(rec AuditRecord, err error) error
+}
+
+// hdWalletState is the state shared by an HDWallet and the views of it
+// recording another caller, see KeyStore.WithCaller.
+type hdWalletState struct {
+	url  URL      // Canonical URL identifying the wallet
+	seed seedFunc // Recovers the seed on Open
+
+	master   *extendedKey               // BIP32 master key, nil while closed
+	accounts []Account                  // Pinned accounts in derivation order
+	paths    map[Address]DerivationPath // Derivation paths of the pinned accounts
+
+	// persist, if set, saves the pinned accounts whenever they change
+	persist func(accounts []Account, paths []DerivationPath) error
+
+	mu sync.RWMutex
+}
+
+// NewHDWallet creates a closed HD wallet over a BIP39 mnemonic. The
+// passphrase given to Open is used as the optional BIP39 password.
+func NewHDWallet(mnemonic string) (*HDWallet, error) {
+	entropy, err := bip39.EntropyFromMnemonic(mnemonic)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMnemonic, err)
+	}
+	return newHDWallet(hdWalletURL(entropy), func(passphrase string) ([]byte, error) {
+		return NewSeed(mnemonic, passphrase)
+	}), nil
+}
+
+// NewHDWalletFromSeed creates a closed HD wallet over a raw BIP32 seed. The
+// passphrase given to Open is ignored.
+func NewHDWalletFromSeed(seed []byte) (*HDWallet, error) {
+	if len(seed) < 16 || len(seed) > 64 {
+		return nil, ErrInvalidSeed
+	}
+	seed = append([]byte(nil), seed...)
+	return newHDWallet(hdWalletURL(seed), func(string) ([]byte, error) {
+		return append([]byte(nil), seed...), nil
+	}), nil
+}
+
+func newHDWallet(url URL, seed seedFunc) *HDWallet {
+	return &HDWallet{hdWalletState: &hdWalletState{url: url, seed: seed, paths: make(map[Address]DerivationPath)}}
+}
+
+// withAudit returns a view of the wallet recording its key exports and
+// signatures with audit.
+func (w *HDWallet) withAudit(audit func(rec AuditRecord, err error) error) *HDWallet {
+	return &HDWallet{hdWalletState: w.hdWalletState, audit: audit}
+}
+
+// record passes the outcome err of an operation to the audit hook, if any.
+func (w *HDWallet) record(rec AuditRecord, err error) error {
+	if w.audit == nil {
+		return err
+	}
+	rec.Wallet = w.url.String()
+	return w.audit(rec, err)
+}
+
+// hdWalletURL identifies an in-memory wallet by a short fingerprint of its
+// secret, which is too short to help guessing it.
+func hdWalletURL(secret []byte) URL {
+	sum := sha256.Sum256(secret)
+	return URL{Scheme: HDWalletScheme, Path: fmt.Sprintf("%x", sum[:8])}
+}
+
+// URL implements Wallet, returning the URL of the wallet.
+func (w *HDWallet) URL() URL {
+	return w.url
+}
+
+// Status implements Wallet, returning whether the master key is available.
+func (w *HDWallet) Status() (string, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.master != nil {
+		return "Open", nil
+	}
+	return "Closed", nil
+}
+
+// Open implements Wallet, deriving the master key from the wallet seed. If
+// accounts were pinned before, the passphrase must reproduce them, so a
+// mistyped BIP39 password is reported as ErrInvalidPassphrase instead of
+// silently opening a different wallet.
+func (w *HDWallet) Open(passphrase string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.master != nil {
+		return ErrWalletAlreadyOpen
+	}
+	master, err := w.masterKey(passphrase)
+	if err != nil {
+		return err
+	}
+	w.master = master
+	return nil
+}
+
+// masterKey recovers the master key with passphrase and checks it against
+// the pinned accounts. Callers must hold w.mu.
+func (w *HDWallet) masterKey(passphrase string) (*extendedKey, error) {
+	seed, err := w.seed(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	master, err := newMasterKey(seed)
+	for i := range seed {
+		seed[i] = 0
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(w.accounts) > 0 {
+		addr, err := deriveAddress(master, w.paths[w.accounts[0].Address])
+		if err != nil || addr != w.accounts[0].Address {
+			master.zero()
+			return nil, ErrInvalidPassphrase
+		}
+	}
+	return master, nil
+}
+
+// Close implements Wallet, wiping the master key from memory.
+func (w *HDWallet) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.master == nil {
+		return ErrWalletClosed
+	}
+	w.master.zero()
+	w.master = nil
+	return nil
+}
+
+// Accounts implements Wallet, returning the pinned accounts.
+func (w *HDWallet) Accounts() []Account {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	cpy := make([]Account, len(w.accounts))
+	copy(cpy, w.accounts)
+	return cpy
+}
+
+// Contains implements Wallet, returning whether the account is pinned in
+// this wallet.
+func (w *HDWallet) Contains(account Account) bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	return w.contains(account)
+}
+
+func (w *HDWallet) contains(account Account) bool {
+	if _, ok := w.paths[account.Address]; !ok {
+		return false
+	}
+	return account.URL == (URL{}) || account.URL == w.accountURL(w.paths[account.Address])
+}
+
+// Path returns the derivation path of a pinned account.
+func (w *HDWallet) Path(account Account) (DerivationPath, bool) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	path, ok := w.paths[account.Address]
+	return path, ok && w.contains(account)
+}
+
+// Derive implements Wallet, deriving the account at path. If pin is set the
+// account is added to the list of tracked accounts.
+func (w *HDWallet) Derive(path DerivationPath, pin bool) (Account, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.master == nil {
+		return Account{}, ErrWalletClosed
+	}
+	addr, err := deriveAddress(w.master, path)
+	if err != nil {
+		return Account{}, err
+	}
+	account := Account{Address: addr, URL: w.accountURL(path)}
+	if pin {
+		if _, err := w.pin([]Account{account}, []DerivationPath{path}); err != nil {
+			return Account{}, err
+		}
+	}
+	return account, nil
+}
+
+// SelfDerive discovers the accounts of the wallet BIP44 style: starting at
+// base it derives consecutive accounts, pinning each one used reports as
+// used, until gap consecutive accounts in a row are unused. The first unused
+// account after the last used one is pinned as well, so the wallet always
+// offers a fresh address. A gap of zero or less means DefaultGapLimit.
+//
+// The newly pinned accounts are returned in derivation order.
+func (w *HDWallet) SelfDerive(base DerivationPath, gap int, used func(Account) (bool, error)) ([]Account, error) {
+	if gap <= 0 {
+		gap = DefaultGapLimit
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.master == nil {
+		return nil, ErrWalletClosed
+	}
+	var (
+		next   = DefaultIterator(base)
+		pinned []Account
+		fresh  *Account
+		paths  = make(map[Address]DerivationPath)
+	)
+	for unused := 0; unused < gap; {
+		path := append(DerivationPath(nil), next()...)
+		addr, err := deriveAddress(w.master, path)
+		if errors.Is(err, ErrInvalidChild) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		account := Account{Address: addr, URL: w.accountURL(path)}
+		paths[addr] = path
+
+		ok, err := used(account)
+		if err != nil {
+			return nil, fmt.Errorf("discover %s: %w", path, err)
+		}
+		if ok {
+			unused, fresh = 0, nil
+			pinned = append(pinned, account)
+			continue
+		}
+		if fresh == nil {
+			fresh = &account
+		}
+		unused++
+	}
+	if fresh != nil {
+		pinned = append(pinned, *fresh)
+	}
+	pinnedPaths := make([]DerivationPath, len(pinned))
+	for i, account := range pinned {
+		pinnedPaths[i] = paths[account.Address]
+	}
+	return w.pin(pinned, pinnedPaths)
+}
+
+// pin adds the accounts not tracked yet to the pinned accounts, persisting
+// the new list if the wallet is backed by storage. The newly added accounts
+// are returned. Callers must hold w.mu.
+func (w *HDWallet) pin(accounts []Account, paths []DerivationPath) ([]Account, error) {
+	var (
+		added      []Account
+		addedPaths []DerivationPath
+	)
+	for i, account := range accounts {
+		if _, ok := w.paths[account.Address]; ok {
+			continue
+		}
+		added = append(added, account)
+		addedPaths = append(addedPaths, append(DerivationPath(nil), paths[i]...))
+	}
+	if len(added) == 0 {
+		return nil, nil
+	}
+	if w.persist != nil {
+		all := append(append([]Account(nil), w.accounts...), added...)
+		allPaths := make([]DerivationPath, 0, len(all))
+		for _, account := range w.accounts {
+			allPaths = append(allPaths, w.paths[account.Address])
+		}
+		if err := w.persist(all, append(allPaths, addedPaths...)); err != nil {
+			return nil, err
+		}
+	}
+	for i, account := range added {
+		w.paths[account.Address] = addedPaths[i]
+		w.accounts = append(w.accounts, account)
+	}
+	return added, nil
+}
+
+// setPinned replaces the pinned accounts, e.g. after the backing seed file
+// was changed on disk.
+func (w *HDWallet) setPinned(accounts []Account, paths []DerivationPath) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.accounts = append([]Account(nil), accounts...)
+	w.paths = make(map[Address]DerivationPath, len(accounts))
+	for i, account := range accounts {
+		w.paths[account.Address] = paths[i]
+	}
+}
+
+// accountURL returns the URL of the account at path.
+func (w *HDWallet) accountURL(path DerivationPath) URL {
+	return hdAccountURL(w.url, path)
+}
+
+// hdAccountURL returns the URL of the account at path of the HD wallet
+// identified by url.
+func hdAccountURL(url URL, path DerivationPath) URL {
+	return URL{Scheme: url.Scheme, Path: fmt.Sprintf("%s/%s", url.Path, path)}
+}
+
+// PrivateKey returns the private key of a pinned account, e.g. to import it
+// into a KeyStore. The wallet must be open.
+func (w *HDWallet) PrivateKey(account Account) (*ecdsa.PrivateKey, error) {
+	key, err := w.privateKey(account)
+	if err = w.record(AuditRecord{Op: AuditExport, Account: account.Address}, err); err != nil {
+		if key != nil {
+			ZeroKey(key)
+		}
+		return nil, err
+	}
+	return key, nil
+}
+
+func (w *HDWallet) privateKey(account Account) (*ecdsa.PrivateKey, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if !w.contains(account) {
+		return nil, ErrUnknownAccount
+	}
+	if w.master == nil {
+		return nil, ErrWalletClosed
+	}
+	return w.accountKey(w.master, account)
+}
+
+// signHash signs hash with the key of a pinned account, using the master key
+// of the open wallet.
+func (w *HDWallet) signHash(account Account, hash []byte) ([]byte, error) {
+	rec := AuditRecord{Op: AuditSignHash, Account: account.Address, Hash: hex.EncodeToString(hash)}
+
+	key, err := w.privateKey(account)
+	if err != nil {
+		return nil, w.record(rec, err)
+	}
+	defer ZeroKey(key)
+
+	signature, err := crypto.Sign(hash, key)
+	if err = w.record(rec, err); err != nil {
+		return nil, err
+	}
+	return signature, nil
+}
+
+// signHashWithPassphrase signs hash with the key of a pinned account,
+// recovering the master key with passphrase just for this signature. The
+// outcome is recorded in rec.
+func (w *HDWallet) signHashWithPassphrase(rec AuditRecord, account Account, passphrase string, hash []byte) ([]byte, error) {
+	key, err := w.passphraseKey(account, passphrase)
+	if err != nil {
+		return nil, w.record(rec, err)
+	}
+	defer ZeroKey(key)
+
+	signature, err := crypto.Sign(hash, key)
+	if err = w.record(rec, err); err != nil {
+		return nil, err
+	}
+	return signature, nil
+}
+
+// passphraseKey derives the key of a pinned account from the master key
+// recovered with passphrase.
+func (w *HDWallet) passphraseKey(account Account, passphrase string) (*ecdsa.PrivateKey, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if !w.contains(account) {
+		return nil, ErrUnknownAccount
+	}
+	master, err := w.masterKey(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer master.zero()
+	return w.accountKey(master, account)
+}
+
+// SignData signs keccak256(data). The mimetype parameter describes the type of data being signed
+func (w *HDWallet) SignData(acc Account, mimeType string, data []byte) ([]byte, error) {
+	return w.signHash(acc, crypto.Keccak256(data))
+}
+
+// SignDataWithPassphrase signs keccak256(data). The mimetype parameter describes the type of data being signed
+func (w *HDWallet) SignDataWithPassphrase(acc Account, passphrase, mimeType string, data []byte) ([]byte, error) {
+	hash := crypto.Keccak256(data)
+	rec := AuditRecord{Op: AuditSignHash, Account: acc.Address, Hash: hex.EncodeToString(hash)}
+	return w.signHashWithPassphrase(rec, acc, passphrase, hash)
+}
+
+// SignText signs the hash of text as computed by TextHash.
+func (w *HDWallet) SignText(acc Account, text []byte) ([]byte, error) {
+	return w.signHash(acc, TextHash(text))
+}
+
+// SignTxWithPassphrase implements Wallet, signing the hex encoded raw data
+// or, if rawData is empty, the hex encoded transaction hash.
+func (w *HDWallet) SignTxWithPassphrase(acc Account, passphrase, rawData, txHash string) ([]byte, error) {
+	rec := AuditRecord{Op: AuditSignTx, Account: acc.Address, Contract: rawDataSummary(rawData)}
+
+	hash, err := txHashBytes(rawData, txHash)
+	if err != nil {
+		return nil, w.record(rec, err)
+	}
+	rec.TxID = hex.EncodeToString(hash)
+	return w.signHashWithPassphrase(rec, acc, passphrase, hash)
+}
+
+// SignMessageV1 implements Wallet, signing message with the TIP-191 v1 scheme.
+func (w *HDWallet) SignMessageV1(acc Account, message []byte) ([]byte, error) {
+	return messageSignature(w.signHash(acc, MessageHashV1(message)))
+}
+
+// SignMessageV2 implements Wallet, signing message with the TIP-191 v2 scheme.
+func (w *HDWallet) SignMessageV2(acc Account, message []byte) ([]byte, error) {
+	return messageSignature(w.signHash(acc, MessageHashV2(message)))
+}
+
+// SignTypedData implements Wallet, signing the TIP-712 hash of data.
+func (w *HDWallet) SignTypedData(acc Account, data *TypedData) ([]byte, error) {
+	hash, err := data.Hash()
+	if err != nil {
+		return nil, err
+	}
+	return messageSignature(w.signHash(acc, hash))
+}
+
+// derivePrivateKey derives the private key at path below master.
+func derivePrivateKey(master *extendedKey, path DerivationPath) (*ecdsa.PrivateKey, error) {
+	k, err := master.derive(path)
+	if err != nil {
+		return nil, err
+	}
+	defer k.zero()
+	return k.privateKey()
+}
+
+// accountKey derives the key of a pinned account below master, failing if
+// the recorded derivation path does not lead to the account's address.
+func (w *HDWallet) accountKey(master *extendedKey, account Account) (*ecdsa.PrivateKey, error) {
+	key, err := derivePrivateKey(master, w.paths[account.Address])
+	if err != nil {
+		return nil, err
+	}
+	if addr := PubkeyToAddress(key.PublicKey); addr != account.Address {
+		ZeroKey(key)
+		return nil, fmt.Errorf("%w: path %s derives %s, not %s", ErrPathMismatch, w.paths[account.Address], addr, account.Address)
+	}
+	return key, nil
+}
+
+// deriveAddress derives the address at path below master.
+func deriveAddress(master *extendedKey, path DerivationPath) (Address, error) {
+	key, err := derivePrivateKey(master, path)
+	if err != nil {
+		return Address{}, err
+	}
+	defer ZeroKey(key)
+	return PubkeyToAddress(key.PublicKey), nil
+}
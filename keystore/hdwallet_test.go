@@ -0,0 +1,165 @@
+package keystore
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const testMnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+func TestBIP32Vector(t *testing.T) {
+	// Test vector 1 of BIP32.
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	master, err := newMasterKey(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path string
+		key  string
+	}{
+		{"m/0'", "edb2e14f9ee77d26dd93b4ecede8d16ed408ce149b6cd80b0715a2d911a0afea"},
+		{"m/0'/1", "3c6cb8d0f6a264c91ea8b5030fadaa8e538b020f0a387421a12de9319dc93368"},
+		{"m/0'/1/2'/2/1000000000", "471b76e389e528d6de6d816857e012c5455051cad6660850e58372a6c3e6e7c8"},
+	}
+	if have := hex.EncodeToString(master.key); have != "e8f32e723decf4051aefac8e2c93c9c5b214313817cdb01a1494b917c8436b35" {
+		t.Errorf("master key mismatch: %s", have)
+	}
+	for _, tt := range tests {
+		k, err := master.derive(MustParseDerivationPath(tt.path))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.path, err)
+		}
+		if have := hex.EncodeToString(k.key); have != tt.key {
+			t.Errorf("%s: have %s, want %s", tt.path, have, tt.key)
+		}
+	}
+}
+
+func TestHDWallet(t *testing.T) {
+	w, err := NewHDWallet(testMnemonic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Derive(DefaultBaseDerivationPath, true); err != ErrWalletClosed {
+		t.Fatalf("derive on closed wallet: have %v, want %v", err, ErrWalletClosed)
+	}
+	if err := w.Open(""); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Open(""); err != ErrWalletAlreadyOpen {
+		t.Errorf("second open: have %v, want %v", err, ErrWalletAlreadyOpen)
+	}
+	want := []string{"TUEZSdKsoDHQMeZwihtdoBiN46zxhGWYdH", "TSeJkUh4Qv67VNFwY8LaAxERygNdy6NQZK"}
+	next := DefaultIterator(DefaultBaseDerivationPath)
+	for i, addr := range want {
+		acc, err := w.Derive(next(), i == 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if acc.Address.String() != addr {
+			t.Errorf("account %d: have %s, want %s", i, acc.Address, addr)
+		}
+	}
+	accs := w.Accounts()
+	if len(accs) != 1 || !w.Contains(accs[0]) || accs[0].URL.String() != w.URL().String()+"/m/44'/195'/0'/0/0" {
+		t.Fatalf("unexpected pinned accounts %v", accs)
+	}
+
+	hash := crypto.Keccak256([]byte("hello"))
+	sig, err := w.SignTxWithPassphrase(accs[0], "", "", hex.EncodeToString(hash))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := crypto.SigToPub(hash, sig)
+	if err != nil || PubkeyToAddress(*pub) != accs[0].Address {
+		t.Errorf("signature does not recover to the account: %v", err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.SignData(accs[0], "", []byte("hello")); err != ErrWalletClosed {
+		t.Errorf("sign on closed wallet: have %v, want %v", err, ErrWalletClosed)
+	}
+	// A different BIP39 password would yield different accounts
+	if err := w.Open("typo"); err != ErrInvalidPassphrase {
+		t.Errorf("open with wrong password: have %v, want %v", err, ErrInvalidPassphrase)
+	}
+	if _, err := w.SignTxWithPassphrase(accs[0], "typo", "", hex.EncodeToString(hash)); err != ErrInvalidPassphrase {
+		t.Errorf("sign with wrong password: have %v, want %v", err, ErrInvalidPassphrase)
+	}
+
+	// A pinned path leading elsewhere must not sign for the account
+	if err := w.Open(""); err != nil {
+		t.Fatal(err)
+	}
+	acc, err := w.Derive(next(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.paths[acc.Address] = next()
+	acc.URL = URL{}
+	if _, err := w.SignData(acc, "", []byte("hello")); !errors.Is(err, ErrPathMismatch) {
+		t.Errorf("sign with mismatched path: have %v, want %v", err, ErrPathMismatch)
+	}
+	if _, err := w.SignTxWithPassphrase(acc, "", "", hex.EncodeToString(hash)); !errors.Is(err, ErrPathMismatch) {
+		t.Errorf("sign with mismatched path and password: have %v, want %v", err, ErrPathMismatch)
+	}
+}
+
+func TestHDWalletSelfDerive(t *testing.T) {
+	w, _ := NewHDWallet(testMnemonic)
+	if err := w.Open(""); err != nil {
+		t.Fatal(err)
+	}
+	used := map[uint32]bool{0: true, 1: true, 4: true}
+	var checked int
+	accs, err := w.SelfDerive(DefaultBaseDerivationPath, 3, func(acc Account) (bool, error) {
+		checked++
+		path, _ := ParseDerivationPath(acc.URL.Path[len(w.URL().Path)+1:])
+		return used[path[len(path)-1]], nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 0, 1 and 4 are used, 5 is the fresh address, 5..7 hit the gap limit
+	if checked != 8 || len(accs) != 4 {
+		t.Fatalf("have %d checks and %d accounts, want 8 and 4", checked, len(accs))
+	}
+	for i, index := range []uint32{0, 1, 4, 5} {
+		path, ok := w.Path(accs[i])
+		if !ok || path[len(path)-1] != index {
+			t.Errorf("account %d: have path %v, want index %d", i, path, index)
+		}
+	}
+
+	fail := errors.New("node unavailable")
+	if _, err := w.SelfDerive(DefaultBaseDerivationPath, 0, func(Account) (bool, error) { return false, fail }); !errors.Is(err, fail) {
+		t.Errorf("have %v, want %v", err, fail)
+	}
+}
+
+func TestMnemonic(t *testing.T) {
+	m, err := NewMnemonic(MnemonicEntropy24Words)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateMnemonic(m); err != nil {
+		t.Errorf("generated mnemonic is invalid: %v", err)
+	}
+	for _, bad := range []string{"", "abandon", testMnemonic[:len(testMnemonic)-len("about")] + "abandon", testMnemonic + " zzz"} {
+		if err := ValidateMnemonic(bad); !errors.Is(err, ErrInvalidMnemonic) {
+			t.Errorf("%q: have %v, want ErrInvalidMnemonic", bad, err)
+		}
+	}
+	if _, err := ParseDerivationPath("/44'/195'"); err == nil {
+		t.Error("expected error for ambiguous path")
+	}
+	if p, _ := ParseDerivationPath("0/7"); p.String() != "m/44'/195'/0'/0/0/7" {
+		t.Errorf("relative path: have %s", p)
+	}
+}
@@ -0,0 +1,232 @@
+package keystore
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// KDFScrypt is the kdf identifier of scrypt, the V3 default.
+	KDFScrypt = keyHeaderKDF
+
+	// KDFArgon2id is the kdf identifier of Argon2id (RFC 9106).
+	KDFArgon2id = "argon2id"
+
+	// StandardArgon2Memory is the memory parameter of Argon2id in KiB, using
+	// 64MB memory as recommended by RFC 9106.
+	StandardArgon2Memory = 64 * 1024
+
+	// StandardArgon2Time is the number of Argon2id passes over the memory.
+	StandardArgon2Time = 3
+
+	// StandardArgon2Threads is the Argon2id parallelism.
+	StandardArgon2Threads = 4
+
+	// LightArgon2Memory is the memory parameter of Argon2id in KiB, using 4MB
+	// memory, for tests and low-end devices.
+	LightArgon2Memory = 4 * 1024
+
+	// LightArgon2Time is the number of Argon2id passes over the memory.
+	LightArgon2Time = 1
+
+	// LightArgon2Threads is the Argon2id parallelism.
+	LightArgon2Threads = 1
+
+	// MaxArgon2Memory and MaxArgon2Time bound the Argon2id parameters of
+	// keystore files, 1GB memory and 64 passes, so that a crafted file can't
+	// make decryption exhaust the memory or run for hours.
+	MaxArgon2Memory = 1024 * 1024
+	MaxArgon2Time   = 64
+
+	kdfDKLen = 32
+)
+
+// KDFOptions selects the key derivation function that turns the passphrase
+// into the key encrypting a keystore file, its cost parameters and the cipher
+// encrypting the file with that key. The zero value means scrypt with the
+// standard parameters and the V3 cipher.
+type KDFOptions struct {
+	KDF    string // KDFScrypt or KDFArgon2id
+	Cipher string // CipherAES128CTR, CipherAES256GCM or CipherXChaCha20Poly1305
+
+	ScryptN int // scrypt CPU/memory cost
+	ScryptP int // scrypt parallelization
+
+	Argon2Memory  uint32 // Argon2id memory in KiB
+	Argon2Time    uint32 // Argon2id passes
+	Argon2Threads uint8  // Argon2id parallelism
+}
+
+// ScryptKDF returns the options for scrypt with the given parameters.
+func ScryptKDF(n, p int) KDFOptions {
+	return KDFOptions{KDF: KDFScrypt, Cipher: CipherAES128CTR, ScryptN: n, ScryptP: p}
+}
+
+// Argon2idKDF returns the options for Argon2id with the given parameters.
+func Argon2idKDF(memory, time uint32, threads uint8) KDFOptions {
+	return KDFOptions{KDF: KDFArgon2id, Cipher: CipherAES128CTR, Argon2Memory: memory, Argon2Time: time, Argon2Threads: threads}
+}
+
+// WithCipher returns a copy of o encrypting with the given cipher. Files using
+// one of the AEAD ciphers can't be read by other TRON wallets.
+func (o KDFOptions) WithCipher(cipher string) KDFOptions {
+	o.Cipher = cipher
+	return o
+}
+
+// StandardScryptKDF and LightScryptKDF are the scrypt settings also used by
+// geth, StandardArgon2idKDF and LightArgon2idKDF their Argon2id equivalents.
+var (
+	StandardScryptKDF   = ScryptKDF(StandardScryptN, StandardScryptP)
+	LightScryptKDF      = ScryptKDF(LightScryptN, LightScryptP)
+	StandardArgon2idKDF = Argon2idKDF(StandardArgon2Memory, StandardArgon2Time, StandardArgon2Threads)
+	LightArgon2idKDF    = Argon2idKDF(LightArgon2Memory, LightArgon2Time, LightArgon2Threads)
+)
+
+// validate fills in the defaults and checks the parameters.
+func (o KDFOptions) validate() (KDFOptions, error) {
+	switch o.Cipher {
+	case "":
+		o.Cipher = CipherAES128CTR
+	case CipherAES128CTR, CipherAES256GCM, CipherXChaCha20Poly1305:
+	default:
+		return o, fmt.Errorf("Cipher not supported: %v", o.Cipher)
+	}
+	switch o.KDF {
+	case "", KDFScrypt:
+		o.KDF = KDFScrypt
+		if o.ScryptN == 0 && o.ScryptP == 0 {
+			o.ScryptN, o.ScryptP = StandardScryptN, StandardScryptP
+		}
+		if o.ScryptN <= 1 || o.ScryptN&(o.ScryptN-1) != 0 || o.ScryptP <= 0 {
+			return o, fmt.Errorf("invalid scrypt parameters n=%d p=%d", o.ScryptN, o.ScryptP)
+		}
+	case KDFArgon2id:
+		if o.Argon2Memory == 0 && o.Argon2Time == 0 && o.Argon2Threads == 0 {
+			o.Argon2Memory, o.Argon2Time, o.Argon2Threads = StandardArgon2Memory, StandardArgon2Time, StandardArgon2Threads
+		}
+		if o.Argon2Time == 0 || o.Argon2Time > MaxArgon2Time || o.Argon2Threads == 0 ||
+			o.Argon2Memory < 8*uint32(o.Argon2Threads) || o.Argon2Memory > MaxArgon2Memory {
+			return o, fmt.Errorf("invalid argon2id parameters memory=%d time=%d threads=%d", o.Argon2Memory, o.Argon2Time, o.Argon2Threads)
+		}
+	default:
+		return o, fmt.Errorf("Unsupported KDF: %s", o.KDF)
+	}
+	return o, nil
+}
+
+// deriveKey derives a fresh key from auth with a random salt, returning the
+// key along with the kdfparams needed to derive it again.
+func (o KDFOptions) deriveKey(auth []byte) ([]byte, map[string]interface{}, error) {
+	o, err := o.validate()
+	if err != nil {
+		return nil, nil, err
+	}
+	salt := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		panic("reading from crypto/rand failed: " + err.Error())
+	}
+	params := map[string]interface{}{
+		"dklen": kdfDKLen,
+		"salt":  hex.EncodeToString(salt),
+	}
+	if o.KDF == KDFArgon2id {
+		params["memory"] = o.Argon2Memory
+		params["time"] = o.Argon2Time
+		params["parallelism"] = o.Argon2Threads
+		return argon2.IDKey(auth, salt, o.Argon2Time, o.Argon2Memory, o.Argon2Threads, kdfDKLen), params, nil
+	}
+	params["n"] = o.ScryptN
+	params["r"] = scryptR
+	params["p"] = o.ScryptP
+	key, err := scrypt.Key(auth, salt, o.ScryptN, scryptR, o.ScryptP, kdfDKLen)
+	return key, params, err
+}
+
+// KDFOptionsOf returns the KDF and cipher settings a keystore file was encrypted with,
+// e.g. to decide whether it needs to be migrated.
+func KDFOptionsOf(keyjson []byte) (KDFOptions, error) {
+	cj, err := cryptoJSONOf(keyjson)
+	if err != nil {
+		return KDFOptions{}, err
+	}
+	switch cj.KDF {
+	case KDFScrypt:
+		n, err := kdfParamInt(cj.KDFParams, "n")
+		if err != nil {
+			return KDFOptions{}, err
+		}
+		p, err := kdfParamInt(cj.KDFParams, "p")
+		if err != nil {
+			return KDFOptions{}, err
+		}
+		return ScryptKDF(n, p).WithCipher(cj.Cipher), nil
+	case KDFArgon2id:
+		memory, time, threads, err := argon2Params(cj.KDFParams)
+		if err != nil {
+			return KDFOptions{}, err
+		}
+		return Argon2idKDF(memory, time, threads).WithCipher(cj.Cipher), nil
+	}
+	return KDFOptions{KDF: cj.KDF, Cipher: cj.Cipher}, nil
+}
+
+// argon2Params extracts and range checks the Argon2id kdfparams, rejecting
+// costs above MaxArgon2Memory and MaxArgon2Time.
+func argon2Params(params map[string]interface{}) (memory, time uint32, threads uint8, err error) {
+	m, err := kdfParamInt(params, "memory")
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	t, err := kdfParamInt(params, "time")
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	p, err := kdfParamInt(params, "parallelism")
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if m < 8*p || m > MaxArgon2Memory || t <= 0 || t > MaxArgon2Time || p <= 0 || p > 255 {
+		return 0, 0, 0, fmt.Errorf("invalid argon2id parameters memory=%d time=%d parallelism=%d", m, t, p)
+	}
+	return uint32(m), uint32(t), uint8(p), nil
+}
+
+// kdfParamInt returns the integer kdf parameter name.
+func kdfParamInt(params map[string]interface{}, name string) (int, error) {
+	switch v := params[name].(type) {
+	case float64:
+		if v != float64(int64(v)) {
+			return 0, fmt.Errorf("kdf parameter %s is not an integer", name)
+		}
+		return int(v), nil
+	case int:
+		return v, nil
+	case nil:
+		return 0, fmt.Errorf("missing kdf parameter %s", name)
+	}
+	return 0, fmt.Errorf("invalid kdf parameter %s", name)
+}
+
+var errNoCrypto = errors.New("no crypto section in keystore file")
+
+// cryptoJSONOf extracts the crypto section of a key or seed file.
+func cryptoJSONOf(keyjson []byte) (CryptoJSON, error) {
+	var k struct {
+		Crypto *CryptoJSON `json:"crypto"`
+	}
+	if err := json.Unmarshal(keyjson, &k); err != nil {
+		return CryptoJSON{}, err
+	}
+	if k.Crypto == nil {
+		return CryptoJSON{}, errNoCrypto
+	}
+	return *k.Crypto, nil
+}
@@ -0,0 +1,139 @@
+package keystore
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"testing"
+)
+
+func TestArgon2idKeyRoundTrip(t *testing.T) {
+	key, err := newKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyjson, err := EncryptKey(key, "pw", LightArgon2idKDF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kdf, err := KDFOptionsOf(keyjson)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kdf != LightArgon2idKDF {
+		t.Errorf("KDFOptionsOf = %+v, want %+v", kdf, LightArgon2idKDF)
+	}
+	dec, err := DecryptKey(keyjson, "pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dec.Address != key.Address || dec.PrivateKey.D.Cmp(key.PrivateKey.D) != 0 {
+		t.Errorf("decrypted key mismatch")
+	}
+	if _, err := DecryptKey(keyjson, "wrong"); err != ErrDecrypt {
+		t.Errorf("wrong passphrase: got %v, want %v", err, ErrDecrypt)
+	}
+
+	// Files asking for more than the maximum cost or for other key lengths
+	// are refused up front
+	for _, tt := range []struct {
+		param string
+		value int
+	}{
+		{"memory", MaxArgon2Memory + 1},
+		{"time", MaxArgon2Time + 1},
+		{"dklen", -1},
+		{"dklen", 16},
+		{"dklen", 64},
+	} {
+		var k map[string]interface{}
+		if err := json.Unmarshal(keyjson, &k); err != nil {
+			t.Fatal(err)
+		}
+		k["crypto"].(map[string]interface{})["kdfparams"].(map[string]interface{})[tt.param] = tt.value
+		bad, _ := json.Marshal(k)
+		if _, err := DecryptKey(bad, "pw"); err == nil || err == ErrDecrypt {
+			t.Errorf("%s %d: got %v, want parameter error", tt.param, tt.value, err)
+		}
+	}
+}
+
+func TestInvalidKDFOptions(t *testing.T) {
+	key, err := newKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, kdf := range []KDFOptions{
+		ScryptKDF(3, 1),
+		ScryptKDF(LightScryptN, 0),
+		Argon2idKDF(LightArgon2Memory, 0, 1),
+		Argon2idKDF(4, 1, 1),
+		Argon2idKDF(MaxArgon2Memory+1, 1, 1),
+		Argon2idKDF(LightArgon2Memory, MaxArgon2Time+1, 1),
+		{KDF: "bcrypt"},
+	} {
+		if _, err := EncryptKey(key, "pw", kdf); err == nil {
+			t.Errorf("EncryptKey accepted %+v", kdf)
+		}
+	}
+}
+
+func TestUpdateWithKDF(t *testing.T) {
+	storage := NewMemoryStorage()
+	ks := NewKeyStoreWithStorage(storage, LightScryptN, LightScryptP)
+	a, err := ks.NewAccount("pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.UpdateWithKDF(a, "pw", "new", LightArgon2idKDF); err != nil {
+		t.Fatal(err)
+	}
+	keyjson, err := storage.Read(a.URL.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kdf, err := KDFOptionsOf(keyjson); err != nil || kdf != LightArgon2idKDF {
+		t.Fatalf("KDFOptionsOf = %+v, %v after migration", kdf, err)
+	}
+	if err := ks.Unlock(a, "new"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A plain Update moves the key back to the KDF of the keystore.
+	if err := ks.Update(a, "new", "pw"); err != nil {
+		t.Fatal(err)
+	}
+	keyjson, _ = storage.Read(a.URL.Path)
+	if kdf, _ := KDFOptionsOf(keyjson); kdf != LightScryptKDF {
+		t.Errorf("KDFOptionsOf = %+v after update, want %+v", kdf, LightScryptKDF)
+	}
+}
+
+func TestAEADCiphers(t *testing.T) {
+	key, err := newKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, cipher := range []string{CipherAES256GCM, CipherXChaCha20Poly1305} {
+		kdf := LightScryptKDF.WithCipher(cipher)
+		keyjson, err := EncryptKey(key, "pw", kdf)
+		if err != nil {
+			t.Fatalf("%s: %v", cipher, err)
+		}
+		if have, err := KDFOptionsOf(keyjson); err != nil || have != kdf {
+			t.Errorf("%s: KDFOptionsOf = %+v, %v", cipher, have, err)
+		}
+		dec, err := DecryptKey(keyjson, "pw")
+		if err != nil {
+			t.Fatalf("%s: %v", cipher, err)
+		}
+		if dec.Address != key.Address {
+			t.Errorf("%s: decrypted key mismatch", cipher)
+		}
+		if _, err := DecryptKey(keyjson, "wrong"); err != ErrDecrypt {
+			t.Errorf("%s: wrong passphrase: got %v, want %v", cipher, err, ErrDecrypt)
+		}
+	}
+	if _, err := EncryptKey(key, "pw", LightScryptKDF.WithCipher("des")); err == nil {
+		t.Errorf("EncryptKey accepted unknown cipher")
+	}
+}
@@ -10,7 +10,6 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
-	"strings"
 	"time"
 
 	"github.com/ethereum/go-ethereum/crypto"
@@ -98,7 +97,7 @@ func (k *Key) UnmarshalJSON(j []byte) (err error) {
 	u := new(uuid.UUID)
 	*u = uuid.Parse(keyJSON.ID)
 	k.ID = *u
-	addr, err := hex.DecodeString(keyJSON.Address)
+	addr, err := HexToAddress(keyJSON.Address)
 	if err != nil {
 		return err
 	}
@@ -138,7 +137,7 @@ func NewKeyForDirectICAP(rand io.Reader) *Key {
 		panic("key generation: ecdsa.GenerateKey failed: " + err.Error())
 	}
 	key := NewKeyFromECDSA(privateKeyECDSA)
-	if !strings.HasPrefix(key.Address.Hex(), "0x00") {
+	if key.Address[1] != 0 {
 		return NewKeyForDirectICAP(rand)
 	}
 	return key
@@ -186,6 +185,12 @@ func writeTemporaryKeyFile(file string, content []byte) (string, error) {
 		os.Remove(f.Name())
 		return "", err
 	}
+	// Flush to disk so the rename never replaces a key with an empty file
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", err
+	}
 	f.Close()
 	return f.Name(), nil
 }
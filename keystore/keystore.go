@@ -5,16 +5,15 @@ import (
 	"crypto/ecdsa"
 	crand "crypto/rand"
 	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
 	"runtime"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/bytejedi/tron-sdk-go/proto/core"
-	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/event"
 	"google.golang.org/protobuf/proto"
@@ -25,6 +24,18 @@ var (
 	ErrLocked  = NewAuthNeededError("password or unlock")
 	ErrNoMatch = errors.New("no key for given address or file")
 	ErrDecrypt = errors.New("could not decrypt key with given passphrase")
+
+	// ErrInvalidTx is returned when signing a transaction without contracts
+	// or with malformed signatures.
+	ErrInvalidTx = errors.New("invalid transaction")
+
+	// ErrInvalidTxID is returned when the transaction hash to sign is not the
+	// ID of the given raw data.
+	ErrInvalidTxID = errors.New("invalid transaction id")
+
+	// ErrAlreadySigned is returned when an account signs a transaction that
+	// already carries its signature.
+	ErrAlreadySigned = errors.New("transaction already signed by account")
 )
 
 // KeyStoreScheme is the protocol scheme prefixing account and wallet URLs.
@@ -33,18 +44,31 @@ const KeyStoreScheme = "keystore"
 // Maximum time between wallet refreshes (if filesystem notifications don't work).
 const walletRefreshCycle = 3 * time.Second
 
-// KeyStore manages a key storage directory on disk.
+// KeyStore manages the keys in a key storage directory on disk or in another
+// Storage backend.
 type KeyStore struct {
-	storage  keyStore             // Storage backend, might be cleartext or encrypted
-	cache    *accountCache        // In-memory account cache over the filesystem storage
+	*keyStoreState
+	caller string // Caller recorded by a WithCaller view, empty for the keystore itself
+}
+
+// keyStoreState is the state shared by a KeyStore and its WithCaller views.
+type keyStoreState struct {
+	storage  keyStore             // Key encryption layer, might be cleartext or encrypted
+	backend  Storage              // Backend holding the (encrypted) key blobs
+	cache    *accountCache        // In-memory account cache over the backend
 	changes  chan struct{}        // Channel receiving change notifications from the cache
 	unlocked map[string]*unlocked // Currently unlocked account (decrypted private keys)
+	root     *KeyStore            // The keystore the wallets are bound to
 
 	wallets     []Wallet                // Wallet wrappers around the individual key files
 	updateFeed  event.Feed              // Event feed to notify wallet additions/removals
 	updateScope event.SubscriptionScope // Subscription scope tracking current live listeners
 	updating    bool                    // Whether the event notification loop is running
 
+	auditSink   AuditSink    // Optional sink recording the keystore operations
+	auditCaller string       // Identity of the keystore user in audit records
+	auditMu     sync.RWMutex // Lock protecting the audit settings
+
 	mu sync.RWMutex
 }
 
@@ -55,20 +79,34 @@ type unlocked struct {
 
 // NewKeyStore creates a keystore for the given directory.
 func NewKeyStore(keydir string, scryptN, scryptP int) *KeyStore {
-	keydir, _ = filepath.Abs(keydir)
-	ks := &KeyStore{storage: &keyStorePassphrase{keydir, scryptN, scryptP, false}}
-	ks.init(keydir)
+	return NewKeyStoreWithStorage(NewDirStorage(keydir), scryptN, scryptP)
+}
+
+// NewKeyStoreWithStorage creates a keystore keeping its encrypted keys in the
+// given storage backend.
+func NewKeyStoreWithStorage(storage Storage, scryptN, scryptP int) *KeyStore {
+	return NewKeyStoreWithKDF(storage, ScryptKDF(scryptN, scryptP))
+}
+
+// NewKeyStoreWithKDF creates a keystore keeping its encrypted keys in the
+// given storage backend, encrypting new and updated keys with the given KDF.
+func NewKeyStoreWithKDF(storage Storage, kdf KDFOptions) *KeyStore {
+	ks := &KeyStore{keyStoreState: &keyStoreState{storage: &keyStorePassphrase{storage, kdf, false}, backend: storage}}
+	ks.root = ks
+	ks.init()
+	// Create the initial list of wallets from the cache
+	ks.refreshWallets()
 	return ks
 }
 
-func (ks *KeyStore) init(keydir string) {
+func (ks *KeyStore) init() {
 	// Lock the mutex since the account cache might call back with events
 	ks.mu.Lock()
 	defer ks.mu.Unlock()
 
 	// Initialize the set of unlocked keys and the account cache
 	ks.unlocked = make(map[string]*unlocked)
-	ks.cache, ks.changes = newAccountCache(keydir)
+	ks.cache, ks.changes = newAccountCache(ks.backend)
 
 	// TODO: In order for this finalizer to work, there must be no references
 	// to ks. addressCache doesn't keep a reference but unlocked keys do,
@@ -76,17 +114,11 @@ func (ks *KeyStore) init(keydir string) {
 	runtime.SetFinalizer(ks, func(m *KeyStore) {
 		m.cache.close()
 	})
-	// Create the initial list of wallets from the cache
-	accs := ks.cache.accounts()
-
-	ks.wallets = make([]Wallet, len(accs))
-	for i := 0; i < len(accs); i++ {
-		ks.wallets[i] = &keystoreWallet{account: accs[i], keystore: ks}
-	}
 }
 
-// Wallets implements account.Backend, returning all single-key wallets from the
-// keystore directory.
+// Wallets implements account.Backend, returning all single-key wallets and the
+// HD wallets of the seed files from the keystore directory. The wallets of a
+// WithCaller view record the caller of the view.
 func (ks *KeyStore) Wallets() []Wallet {
 	// Make sure the list of wallets is in sync with the account cache
 	ks.refreshWallets()
@@ -96,43 +128,78 @@ func (ks *KeyStore) Wallets() []Wallet {
 
 	cpy := make([]Wallet, len(ks.wallets))
 	copy(cpy, ks.wallets)
+	if ks != ks.root {
+		for i, w := range cpy {
+			switch w := w.(type) {
+			case *keystoreWallet:
+				cpy[i] = &keystoreWallet{account: w.account, keystore: ks}
+			case *HDWallet:
+				cpy[i] = w.withAudit(ks.audit)
+			}
+		}
+	}
 	return cpy
 }
 
 // refreshWallets retrieves the current account list and based on that does any
 // necessary wallet refreshes.
 func (ks *KeyStore) refreshWallets() {
-	// Retrieve the current list of accounts
+	// Retrieve the current list of accounts and seed files
 	ks.mu.Lock()
 	accs := ks.cache.accounts()
+	seeds := ks.cache.seedFiles()
 
 	// Transform the current list of wallets into the new one
 	var (
-		wallets = make([]Wallet, 0, len(accs))
+		wallets = make([]Wallet, 0, len(accs)+len(seeds))
 		events  []WalletEvent
 	)
 
-	for _, account := range accs {
-		// Drop wallets while they were in front of the next account
-		for len(ks.wallets) > 0 && ks.wallets[0].URL().Cmp(account.URL) < 0 {
+	for len(accs) > 0 || len(seeds) > 0 {
+		// Pick the next key or seed file in URL order
+		var (
+			url     URL
+			account *Account
+			seed    *seedFile
+		)
+		if len(seeds) == 0 || (len(accs) > 0 && accs[0].URL.Cmp(seeds[0].url) < 0) {
+			account, url, accs = &accs[0], accs[0].URL, accs[1:]
+		} else {
+			seed, url, seeds = &seeds[0], seeds[0].url, seeds[1:]
+		}
+		// Drop wallets while they were in front of the next file
+		for len(ks.wallets) > 0 && ks.wallets[0].URL().Cmp(url) < 0 {
 			events = append(events, WalletEvent{Wallet: ks.wallets[0], Kind: WalletDropped})
 			ks.wallets = ks.wallets[1:]
 		}
-		// If there are no more wallets or the account is before the next, wrap new wallet
-		if len(ks.wallets) == 0 || ks.wallets[0].URL().Cmp(account.URL) > 0 {
-			wallet := &keystoreWallet{account: account, keystore: ks}
-
-			events = append(events, WalletEvent{Wallet: wallet, Kind: WalletArrived})
-			wallets = append(wallets, wallet)
-			continue
-		}
-		// If the account is the same as the first wallet, keep it
-		if ks.wallets[0].Accounts()[0].URL == account.URL &&
-			bytes.Equal(ks.wallets[0].Accounts()[0].Address, account.Address) {
-			wallets = append(wallets, ks.wallets[0])
+		// If the file is the same as the first wallet, keep it
+		if len(ks.wallets) > 0 && ks.wallets[0].URL() == url {
+			keep := false
+			switch w := ks.wallets[0].(type) {
+			case *keystoreWallet:
+				keep = account != nil && w.account.Address == account.Address
+			case *HDWallet:
+				if keep = seed != nil; keep {
+					w.setPinned(seed.accounts, seed.paths)
+				}
+			}
+			if keep {
+				wallets = append(wallets, ks.wallets[0])
+				ks.wallets = ks.wallets[1:]
+				continue
+			}
+			events = append(events, WalletEvent{Wallet: ks.wallets[0], Kind: WalletDropped})
 			ks.wallets = ks.wallets[1:]
-			continue
 		}
+		// Otherwise wrap a new wallet
+		var wallet Wallet
+		if account != nil {
+			wallet = &keystoreWallet{account: *account, keystore: ks.root}
+		} else {
+			wallet = ks.newSeedFileWallet(*seed)
+		}
+		events = append(events, WalletEvent{Wallet: wallet, Kind: WalletArrived})
+		wallets = append(wallets, wallet)
 	}
 	// Drop any leftover wallets and set the new batch
 	for _, wallet := range ks.wallets {
@@ -204,6 +271,10 @@ func (ks *KeyStore) Accounts() []Account {
 // Delete deletes the key matched by account if the passphrase is correct.
 // If the account contains no filename, the address must match a unique key.
 func (ks *KeyStore) Delete(a Account, passphrase string) error {
+	return ks.audit(AuditRecord{Op: AuditDelete, Account: a.Address}, ks.delete(a, passphrase))
+}
+
+func (ks *KeyStore) delete(a Account, passphrase string) error {
 	// Decrypting the key isn't really necessary, but we do
 	// it anyway to check the password and zero out the key
 	// immediately afterwards.
@@ -217,7 +288,7 @@ func (ks *KeyStore) Delete(a Account, passphrase string) error {
 	// The order is crucial here. The key is dropped from the
 	// cache after the file is gone so that a reload happening in
 	// between won't insert it into the cache again.
-	err = os.Remove(a.URL.Path)
+	err = ks.backend.Remove(a.URL.Path)
 	if err == nil {
 		ks.cache.delete(a)
 		ks.refreshWallets()
@@ -225,82 +296,171 @@ func (ks *KeyStore) Delete(a Account, passphrase string) error {
 	return err
 }
 
-// SignTx signs the given transaction with the requested account.
+// SignTx signs the given transaction with the requested account, which must
+// be unlocked, and appends the signature to tx. The account stays unlocked, so
+// it can sign further transactions until it is locked or its unlock expires.
+//
+// The signature is made for the permission encoded in the contracts of tx. A
+// partially signed transaction can be signed by further accounts, each only
+// once. The existing signatures are not verified.
 func (ks *KeyStore) SignTx(a Account, tx *core.Transaction) (*core.Transaction, error) {
+	rec := AuditRecord{Op: AuditSignTx, Account: a.Address, Contract: contractSummary(tx.GetRawData())}
+
 	// Look up the key to sign with and abort if it cannot be found
 	ks.mu.RLock()
 	defer ks.mu.RUnlock()
 
 	unlockedKey, found := ks.unlocked[a.Address.String()]
 	if !found {
-		return nil, ErrLocked
+		return nil, ks.audit(rec, ErrLocked)
 	}
-	defer ZeroKey(unlockedKey.PrivateKey)
-
-	rawData, err := proto.Marshal(tx.GetRawData())
-	if err != nil {
-		return nil, err
-	}
-	h256h := sha256.New()
-	h256h.Write(rawData)
-	hash := h256h.Sum(nil)
-
-	signature, err := crypto.Sign(hash, unlockedKey.PrivateKey)
-	if err != nil {
-		return nil, err
-	}
-	tx.Signature = append(tx.Signature, signature)
-	_ = unlockedKey
-	return tx, nil
+	return ks.signTx(rec, tx, unlockedKey.PrivateKey)
 }
 
 // SignHash calculates a ECDSA signature for the given hash. The produced
 // signature is in the [R || S || V] format where V is 0 or 1.
 func (ks *KeyStore) SignHash(a Account, hash []byte) ([]byte, error) {
+	rec := AuditRecord{Op: AuditSignHash, Account: a.Address, Hash: hex.EncodeToString(hash)}
+
 	// Look up the key to sign with and abort if it cannot be found
 	ks.mu.RLock()
 	defer ks.mu.RUnlock()
 
 	unlockedKey, found := ks.unlocked[a.Address.String()]
 	if !found {
-		return nil, ErrLocked
+		return nil, ks.audit(rec, ErrLocked)
 	}
 	// Sign the hash using plain ECDSA operations
-	return crypto.Sign(hash, unlockedKey.PrivateKey)
+	signature, err := crypto.Sign(hash, unlockedKey.PrivateKey)
+	if err = ks.audit(rec, err); err != nil {
+		return nil, err
+	}
+	return signature, nil
 }
 
 // SignHashWithPassphrase signs hash if the private key matching the given address
 // can be decrypted with the given passphrase. The produced signature is in the
 // [R || S || V] format where V is 0 or 1.
 func (ks *KeyStore) SignHashWithPassphrase(a Account, passphrase string, hash []byte) (signature []byte, err error) {
+	rec := AuditRecord{Op: AuditSignHash, Account: a.Address, Hash: hex.EncodeToString(hash)}
+
 	_, key, err := ks.GetDecryptedKey(a, passphrase)
 	if err != nil {
-		return nil, err
+		return nil, ks.audit(rec, err)
 	}
 	defer ZeroKey(key.PrivateKey)
-	return crypto.Sign(hash, key.PrivateKey)
+
+	signature, err = crypto.Sign(hash, key.PrivateKey)
+	if err = ks.audit(rec, err); err != nil {
+		return nil, err
+	}
+	return signature, nil
 }
 
+// SignTxWithPassphrase signs the hex encoded raw data or, if rawData is
+// empty, the hex encoded transaction hash with the account decrypted with
+// passphrase, returning the signature. If both are given the hash must be the
+// transaction ID of the raw data.
 func (ks *KeyStore) SignTxWithPassphrase(a Account, passphrase, rawData, txHash string) ([]byte, error) {
+	rec := AuditRecord{Op: AuditSignTx, Account: a.Address, Contract: rawDataSummary(rawData)}
+
+	hash, err := txHashBytes(rawData, txHash)
+	if err != nil {
+		return nil, ks.audit(rec, err)
+	}
+	rec.TxID = hex.EncodeToString(hash)
+
 	_, key, err := ks.GetDecryptedKey(a, passphrase)
 	if err != nil {
+		return nil, ks.audit(rec, err)
+	}
+	defer ZeroKey(key.PrivateKey)
+
+	signature, err := crypto.Sign(hash, key.PrivateKey)
+	if err = ks.audit(rec, err); err != nil {
 		return nil, err
 	}
+	return signature, nil
+}
+
+// SignTransactionWithPassphrase signs tx like SignTx if the private key of the
+// account can be decrypted with the given passphrase.
+func (ks *KeyStore) SignTransactionWithPassphrase(a Account, passphrase string, tx *core.Transaction) (*core.Transaction, error) {
+	rec := AuditRecord{Op: AuditSignTx, Account: a.Address, Contract: contractSummary(tx.GetRawData())}
+
+	_, key, err := ks.GetDecryptedKey(a, passphrase)
+	if err != nil {
+		return nil, ks.audit(rec, err)
+	}
 	defer ZeroKey(key.PrivateKey)
 
-	var txHashBytes []byte
+	return ks.signTx(rec, tx, key.PrivateKey)
+}
 
-	if rawData != "" {
-		rawDataBytes := common.Hex2Bytes(rawData)
+// signTx validates tx and appends the signature of key, recording the
+// outcome in rec.
+func (ks *KeyStore) signTx(rec AuditRecord, tx *core.Transaction, key *ecdsa.PrivateKey) (*core.Transaction, error) {
+	if len(tx.GetRawData().GetContract()) == 0 {
+		return nil, ks.audit(rec, ErrInvalidTx)
+	}
+	rawData, err := proto.Marshal(tx.GetRawData())
+	if err != nil {
+		return nil, ks.audit(rec, err)
+	}
+	h256h := sha256.New()
+	h256h.Write(rawData)
+	hash := h256h.Sum(nil)
+	rec.TxID = hex.EncodeToString(hash)
+
+	// Recover the signers of the existing signatures to refuse signing twice
+	// with the same key. This doesn't verify them, a signature made for other
+	// raw data recovers to an unrelated address.
+	addr := PubkeyToAddress(key.PublicKey)
+	for i, sig := range tx.GetSignature() {
+		pub, err := crypto.SigToPub(hash, sig)
+		if err != nil {
+			return nil, ks.audit(rec, fmt.Errorf("%w: signature %d: %v", ErrInvalidTx, i, err))
+		}
+		if PubkeyToAddress(*pub) == addr {
+			return nil, ks.audit(rec, ErrAlreadySigned)
+		}
+	}
+	signature, err := crypto.Sign(hash, key)
+	if err = ks.audit(rec, err); err != nil {
+		return nil, err
+	}
+	tx.Signature = append(tx.Signature, signature)
+	return tx, nil
+}
 
+// txHashBytes returns the hash to sign for a transaction given either as hex
+// encoded raw data, which is hashed, or as the hex encoded hash itself. If
+// both are given they must match.
+func txHashBytes(rawData, txHash string) ([]byte, error) {
+	var hash []byte
+	if rawData != "" {
+		data, err := hex.DecodeString(strings.TrimPrefix(rawData, "0x"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid raw data: %v", err)
+		}
 		h256h := sha256.New()
-		h256h.Write(rawDataBytes)
-		txHashBytes = h256h.Sum(nil)
-	} else {
-		txHashBytes = common.Hex2Bytes(txHash)
+		h256h.Write(data)
+		hash = h256h.Sum(nil)
 	}
-
-	return crypto.Sign(txHashBytes, key.PrivateKey)
+	if txHash != "" {
+		id, err := hex.DecodeString(strings.TrimPrefix(txHash, "0x"))
+		if err != nil || len(id) != sha256.Size {
+			return nil, ErrInvalidTxID
+		}
+		if hash != nil && !bytes.Equal(id, hash) {
+			return nil, ErrInvalidTxID
+		}
+		hash = id
+	}
+	if hash == nil {
+		return nil, ErrInvalidTx
+	}
+	return hash, nil
 }
 
 // Unlock unlocks the given account indefinitely.
@@ -328,6 +488,10 @@ func (ks *KeyStore) Lock(addr Address) error {
 // shortens the active unlock timeout. If the address was previously unlocked
 // indefinitely the timeout is not altered.
 func (ks *KeyStore) TimedUnlock(a Account, passphrase string, timeout time.Duration) error {
+	return ks.audit(AuditRecord{Op: AuditUnlock, Account: a.Address}, ks.timedUnlock(a, passphrase, timeout))
+}
+
+func (ks *KeyStore) timedUnlock(a Account, passphrase string, timeout time.Duration) error {
 	a, key, err := ks.GetDecryptedKey(a, passphrase)
 	if err != nil {
 		return err
@@ -411,17 +575,27 @@ func (ks *KeyStore) NewAccount(passphrase string) (Account, error) {
 
 // Export exports as a JSON key, encrypted with newPassphrase.
 func (ks *KeyStore) Export(a Account, passphrase, newPassphrase string) (keyJSON []byte, err error) {
+	rec := AuditRecord{Op: AuditExport, Account: a.Address}
+
 	_, key, err := ks.GetDecryptedKey(a, passphrase)
 	if err != nil {
+		return nil, ks.audit(rec, err)
+	}
+	defer ZeroKey(key.PrivateKey)
+
+	keyJSON, err = EncryptKey(key, newPassphrase, ks.kdf())
+	if err = ks.audit(rec, err); err != nil {
 		return nil, err
 	}
-	var N, P int
+	return keyJSON, nil
+}
+
+// kdf returns the KDF settings keys are encrypted with.
+func (ks *KeyStore) kdf() KDFOptions {
 	if store, ok := ks.storage.(*keyStorePassphrase); ok {
-		N, P = store.scryptN, store.scryptP
-	} else {
-		N, P = StandardScryptN, StandardScryptP
+		return store.kdf
 	}
-	return EncryptKey(key, newPassphrase, N, P)
+	return StandardScryptKDF
 }
 
 // Import stores the given encrypted JSON key into the key directory.
@@ -431,7 +605,7 @@ func (ks *KeyStore) Import(keyJSON []byte, passphrase string) (Account, error) {
 		defer ZeroKey(key.PrivateKey)
 	}
 	if err != nil {
-		return Account{}, err
+		return Account{}, ks.audit(AuditRecord{Op: AuditImport}, err)
 	}
 	return ks.importKey(key, passphrase)
 }
@@ -440,28 +614,46 @@ func (ks *KeyStore) Import(keyJSON []byte, passphrase string) (Account, error) {
 func (ks *KeyStore) ImportECDSA(priv *ecdsa.PrivateKey, passphrase string) (Account, error) {
 	key := NewKeyFromECDSA(priv)
 	if ks.cache.hasAddress(key.Address) {
-		return Account{}, fmt.Errorf("account already exists")
+		return Account{}, ks.audit(AuditRecord{Op: AuditImport, Account: key.Address}, fmt.Errorf("account already exists"))
 	}
 	return ks.importKey(key, passphrase)
 }
 
 func (ks *KeyStore) importKey(key *Key, passphrase string) (Account, error) {
+	rec := AuditRecord{Op: AuditImport, Account: key.Address}
 	a := Account{Address: key.Address, URL: URL{Scheme: KeyStoreScheme, Path: ks.storage.JoinPath(keyFileName(key.Address))}}
 	if err := ks.storage.StoreKey(a.URL.Path, key, passphrase); err != nil {
-		return Account{}, err
+		return Account{}, ks.audit(rec, err)
 	}
 	ks.cache.add(a)
 	ks.refreshWallets()
-	return a, nil
+	return a, ks.audit(rec, nil)
 }
 
-// Update changes the passphrase of an existing account.
+// Update changes the passphrase of an existing account. The key is
+// re-encrypted with the KDF of the keystore, so files written with other
+// settings are migrated along the way.
 func (ks *KeyStore) Update(a Account, passphrase, newPassphrase string) error {
+	return ks.UpdateWithKDF(a, passphrase, newPassphrase, ks.kdf())
+}
+
+// UpdateWithKDF changes the passphrase of an existing account and
+// re-encrypts it with the given KDF, e.g. to migrate it to Argon2id.
+func (ks *KeyStore) UpdateWithKDF(a Account, passphrase, newPassphrase string, kdf KDFOptions) error {
+	return ks.audit(AuditRecord{Op: AuditUpdate, Account: a.Address}, ks.update(a, passphrase, newPassphrase, kdf))
+}
+
+func (ks *KeyStore) update(a Account, passphrase, newPassphrase string, kdf KDFOptions) error {
+	if _, err := kdf.validate(); err != nil {
+		return err
+	}
 	a, key, err := ks.GetDecryptedKey(a, passphrase)
 	if err != nil {
 		return err
 	}
-	return ks.storage.StoreKey(a.URL.Path, key, newPassphrase)
+	defer ZeroKey(key.PrivateKey)
+	store := &keyStorePassphrase{ks.backend, kdf, false}
+	return store.StoreKey(a.URL.Path, key, newPassphrase)
 }
 
 // ZeroKey zeroes a private key in memory.
@@ -0,0 +1,144 @@
+package keystore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bytejedi/tron-sdk-go/proto/core"
+	"github.com/bytejedi/tron-sdk-go/proto/core/contract"
+	"github.com/ethereum/go-ethereum/crypto"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// testTx creates a TRX transfer from owner.
+func testTx(t *testing.T, owner Address, amount int64) *core.Transaction {
+	param, err := anypb.New(&contract.TransferContract{OwnerAddress: owner.Bytes(), ToAddress: owner.Bytes(), Amount: amount})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &core.Transaction{RawData: &core.TransactionRaw{
+		Contract: []*core.Transaction_Contract{{Type: core.Transaction_Contract_TransferContract, Parameter: param}},
+	}}
+}
+
+// txSigners recovers the signers of tx.
+func txSigners(t *testing.T, tx *core.Transaction) []Address {
+	rawData, err := proto.Marshal(tx.GetRawData())
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := sha256.Sum256(rawData)
+	var signers []Address
+	for _, sig := range tx.GetSignature() {
+		pub, err := crypto.SigToPub(hash[:], sig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signers = append(signers, PubkeyToAddress(*pub))
+	}
+	return signers
+}
+
+func TestSignTx(t *testing.T) {
+	ks := NewKeyStoreWithStorage(NewMemoryStorage(), LightScryptN, LightScryptP)
+	a, err := ks.NewAccount("pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ks.NewAccount("pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.TimedUnlock(a, "pw", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	// An unlocked account signs any number of transactions
+	for i := int64(1); i <= 3; i++ {
+		tx, err := ks.SignTx(a, testTx(t, a.Address, i))
+		if err != nil {
+			t.Fatalf("signature %d: %v", i, err)
+		}
+		if signers := txSigners(t, tx); len(signers) != 1 || signers[0] != a.Address {
+			t.Fatalf("signature %d: unexpected signers %v", i, signers)
+		}
+	}
+
+	// Partially signed transactions get further signatures, but only one
+	// per account
+	tx, err := ks.SignTx(a, testTx(t, a.Address, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.SignTx(a, tx); err != ErrAlreadySigned {
+		t.Errorf("second signature: have %v, want %v", err, ErrAlreadySigned)
+	}
+	if _, err := ks.SignTransactionWithPassphrase(b, "wrong", tx); err != ErrDecrypt {
+		t.Errorf("wrong passphrase: have %v, want %v", err, ErrDecrypt)
+	}
+	if tx, err = ks.SignTransactionWithPassphrase(b, "pw", tx); err != nil {
+		t.Fatal(err)
+	}
+	if signers := txSigners(t, tx); len(signers) != 2 || signers[0] != a.Address || signers[1] != b.Address {
+		t.Errorf("unexpected signers %v", signers)
+	}
+	if _, err := ks.SignTransactionWithPassphrase(b, "pw", tx); err != ErrAlreadySigned {
+		t.Errorf("second passphrase signature: have %v, want %v", err, ErrAlreadySigned)
+	}
+
+	// Invalid transactions are refused
+	if _, err := ks.SignTx(a, &core.Transaction{RawData: &core.TransactionRaw{}}); err != ErrInvalidTx {
+		t.Errorf("no contracts: have %v, want %v", err, ErrInvalidTx)
+	}
+	bad := testTx(t, a.Address, 1)
+	bad.Signature = [][]byte{make([]byte, 10)}
+	if _, err := ks.SignTx(a, bad); !errors.Is(err, ErrInvalidTx) {
+		t.Errorf("malformed signature: have %v, want %v", err, ErrInvalidTx)
+	}
+	if err := ks.Lock(a.Address); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.SignTx(a, testTx(t, a.Address, 1)); err != ErrLocked {
+		t.Errorf("locked account: have %v, want %v", err, ErrLocked)
+	}
+}
+
+func TestSignTxWithPassphrase(t *testing.T) {
+	ks := NewKeyStoreWithStorage(NewMemoryStorage(), LightScryptN, LightScryptP)
+	a, err := ks.NewAccount("pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rawData, err := proto.Marshal(testTx(t, a.Address, 1).GetRawData())
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := sha256.Sum256(rawData)
+	id := hash[:]
+
+	for _, tc := range []struct{ rawData, txHash string }{
+		{hex.EncodeToString(rawData), ""},
+		{"", hex.EncodeToString(id)},
+		{"0x" + hex.EncodeToString(rawData), "0x" + hex.EncodeToString(id)},
+	} {
+		sig, err := ks.SignTxWithPassphrase(a, "pw", tc.rawData, tc.txHash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pub, err := crypto.SigToPub(id, sig); err != nil || PubkeyToAddress(*pub) != a.Address {
+			t.Errorf("signature does not recover to the account: %v", err)
+		}
+	}
+	for _, tc := range []struct{ rawData, txHash string }{
+		{hex.EncodeToString(rawData), hex.EncodeToString(make([]byte, 32))},
+		{"", hex.EncodeToString(id[:31])},
+		{"", "zz"},
+	} {
+		if _, err := ks.SignTxWithPassphrase(a, "pw", tc.rawData, tc.txHash); err != ErrInvalidTxID {
+			t.Errorf("%q/%q: have %v, want %v", tc.rawData, tc.txHash, err, ErrInvalidTxID)
+		}
+	}
+}
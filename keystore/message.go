@@ -0,0 +1,73 @@
+package keystore
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// tronMessagePrefix is the TIP-191 header of signed messages.
+const tronMessagePrefix = "\x19TRON Signed Message:\n"
+
+// ErrInvalidSignature is returned when verifying a malformed signature.
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// MessageHashV1 calculates the hash of a message signed with the TIP-191 v1
+// scheme of TronWeb's trx.sign, which always declares a length of 32 bytes:
+//
+//	keccak256("\x19TRON Signed Message:\n32"${message}).
+//
+// The message is the byte form of the hex string passed to TronWeb, usually a
+// 32 byte hash.
+func MessageHashV1(message []byte) []byte {
+	return crypto.Keccak256([]byte(tronMessagePrefix+"32"), message)
+}
+
+// MessageHashV2 calculates the hash of a message signed with the TIP-191 v2
+// scheme of TronWeb's trx.signMessageV2:
+//
+//	keccak256("\x19TRON Signed Message:\n"${message length}${message}).
+func MessageHashV2(message []byte) []byte {
+	return crypto.Keccak256([]byte(fmt.Sprintf("%s%d", tronMessagePrefix, len(message))), message)
+}
+
+// VerifyMessageV1 recovers the base58 address that signed message with the
+// TIP-191 v1 scheme. The signature is valid if it matches the expected signer.
+func VerifyMessageV1(message, signature []byte) (string, error) {
+	return recoverMessageSigner(MessageHashV1(message), signature)
+}
+
+// VerifyMessageV2 recovers the base58 address that signed message with the
+// TIP-191 v2 scheme. The signature is valid if it matches the expected signer.
+func VerifyMessageV2(message, signature []byte) (string, error) {
+	return recoverMessageSigner(MessageHashV2(message), signature)
+}
+
+// recoverMessageSigner recovers the signer of hash from a [R || S || V]
+// signature, accepting V as 0/1 or 27/28.
+func recoverMessageSigner(hash, signature []byte) (string, error) {
+	if len(signature) != crypto.SignatureLength {
+		return "", ErrInvalidSignature
+	}
+	sig := make([]byte, crypto.SignatureLength)
+	copy(sig, signature)
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+	pub, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	return PubkeyToAddress(*pub).String(), nil
+}
+
+// messageSignature converts a signature from crypto.Sign to the format of
+// TronWeb, with V as 27/28.
+func messageSignature(sig []byte, err error) ([]byte, error) {
+	if err != nil {
+		return nil, err
+	}
+	sig[crypto.RecoveryIDOffset] += 27
+	return sig, nil
+}
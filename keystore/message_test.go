@@ -0,0 +1,125 @@
+package keystore
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// TIP-191 signatures of the first account of testMnemonic,
+// TUEZSdKsoDHQMeZwihtdoBiN46zxhGWYdH, in the format of TronWeb.
+var messageTests = []struct {
+	message string
+	v2      bool
+	hash    string
+	sig     string
+}{
+	{
+		message: "0x47173285a8d7341e5e972fc677286384f802f8ef42a5ec5f03bbfa254cb01fad",
+		hash:    "0x66733cf31ee3f133db9561efdca3d65ba930bdf57fb1af3b6cc0ba4966ecc882",
+		sig:     "0xc219457e370d197fd380f0fb1135717422156c86361707bc0cedf1ed453de6f25a23d7147aa1fadc2ec4d25d74395ed3854c309bae4771e95b8a124e904c56991c",
+	},
+	{
+		message: "0x68656c6c6f20776f726c64",
+		hash:    "0x2b260ec73854abf6c236882e2843fd90165ddf6503df167e02670242d383e826",
+		sig:     "0x5cd8ce7b46da2235db2d887bd29da1c31af983e1f11d1a31ae4d1754d1cca6b4487c60c06491370c79becc4fcbe30b590b9d3e33c0932c73663cdeed94a91d9d1c",
+	},
+	{
+		message: "0x68656c6c6f20776f726c64",
+		v2:      true,
+		hash:    "0xcf02daeb2bea196ed5692322a66ed50080ce74ff8cb711199f1b04f3c13bc10d",
+		sig:     "0x00bb67c0ef81d9bec5a055145e93ced601f0699cd3391e2f3506044ca56899145c287d0b82657c7d493f38120e1646bddfe3465ad782d2a9143a89ac33fd47001c",
+	},
+	{
+		// A 32 byte message hashes alike in both schemes
+		message: "0x47173285a8d7341e5e972fc677286384f802f8ef42a5ec5f03bbfa254cb01fad",
+		v2:      true,
+		hash:    "0x66733cf31ee3f133db9561efdca3d65ba930bdf57fb1af3b6cc0ba4966ecc882",
+		sig:     "0xc219457e370d197fd380f0fb1135717422156c86361707bc0cedf1ed453de6f25a23d7147aa1fadc2ec4d25d74395ed3854c309bae4771e95b8a124e904c56991c",
+	},
+}
+
+func TestMessageSignatures(t *testing.T) {
+	const signer = "TUEZSdKsoDHQMeZwihtdoBiN46zxhGWYdH"
+
+	w, _ := NewHDWallet(testMnemonic)
+	if err := w.Open(""); err != nil {
+		t.Fatal(err)
+	}
+	acc, err := w.Derive(DefaultBaseDerivationPath, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, tt := range messageTests {
+		var (
+			message = common.FromHex(tt.message)
+			want    = common.FromHex(tt.sig)
+			hash    []byte
+			sig     []byte
+			addr    string
+		)
+		if tt.v2 {
+			hash = MessageHashV2(message)
+			sig, err = w.SignMessageV2(acc, message)
+			if err == nil {
+				addr, err = VerifyMessageV2(message, want)
+			}
+		} else {
+			hash = MessageHashV1(message)
+			sig, err = w.SignMessageV1(acc, message)
+			if err == nil {
+				addr, err = VerifyMessageV1(message, want)
+			}
+		}
+		if err != nil {
+			t.Fatalf("test %d: %v", i, err)
+		}
+		if !bytes.Equal(hash, common.FromHex(tt.hash)) {
+			t.Errorf("test %d: hash mismatch: have %x, want %s", i, hash, tt.hash)
+		}
+		if !bytes.Equal(sig, want) {
+			t.Errorf("test %d: signature mismatch: have %x, want %s", i, sig, tt.sig)
+		}
+		if addr != signer {
+			t.Errorf("test %d: recovered %s, want %s", i, addr, signer)
+		}
+	}
+}
+
+func TestVerifyMessage(t *testing.T) {
+	ks := NewKeyStoreWithStorage(NewMemoryStorage(), LightScryptN, LightScryptP)
+	a, err := ks.NewAccount("pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.Unlock(a, "pw"); err != nil {
+		t.Fatal(err)
+	}
+	w := ks.Wallets()[0]
+	message := []byte("login nonce 42")
+	sig, err := w.SignMessageV2(a, message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sig[crypto.RecoveryIDOffset] < 27 {
+		t.Errorf("unexpected recovery id %d", sig[crypto.RecoveryIDOffset])
+	}
+	if addr, err := VerifyMessageV2(message, sig); err != nil || addr != a.Address.String() {
+		t.Errorf("recovered %s, %v, want %s", addr, err, a.Address)
+	}
+	// Recovery ids of 0/1 are accepted as well
+	raw := append([]byte{}, sig...)
+	raw[crypto.RecoveryIDOffset] -= 27
+	if addr, err := VerifyMessageV2(message, raw); err != nil || addr != a.Address.String() {
+		t.Errorf("recovered %s, %v, want %s", addr, err, a.Address)
+	}
+	// The schemes are not interchangeable for other lengths than 32
+	if addr, err := VerifyMessageV1(message, sig); err == nil && addr == a.Address.String() {
+		t.Errorf("v2 signature accepted as v1")
+	}
+	if _, err := VerifyMessageV2(message, sig[:64]); err != ErrInvalidSignature {
+		t.Errorf("short signature: have %v, want %v", err, ErrInvalidSignature)
+	}
+}
@@ -9,13 +9,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"io/ioutil"
-	"os"
-	"path/filepath"
 
 	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/pborman/uuid"
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/pbkdf2"
 	"golang.org/x/crypto/scrypt"
 )
@@ -39,14 +37,12 @@ const (
 	// memory and taking approximately 100ms CPU time on a modern processor.
 	LightScryptP = 6
 
-	scryptR     = 8
-	scryptDKLen = 32
+	scryptR = 8
 )
 
 type keyStorePassphrase struct {
-	keysDirPath string
-	scryptN     int
-	scryptP     int
+	storage Storage
+	kdf     KDFOptions
 	// skipKeyFileVerification disables the security-feature which does
 	// reads and decrypts any newly created keyfiles. This should be 'false' in all
 	// cases except tests -- setting this to 'true' is not recommended.
@@ -55,16 +51,21 @@ type keyStorePassphrase struct {
 
 func (ks keyStorePassphrase) GetKey(addr Address, filename, auth string) (*Key, error) {
 	// Load the key from the keystore and decrypt its contents
-	keyjson, err := ioutil.ReadFile(filename)
+	keyjson, err := ks.storage.Read(filename)
 	if err != nil {
 		return nil, err
 	}
+	return decryptStoredKey(keyjson, addr, auth)
+}
+
+// decryptStoredKey decrypts keyjson and makes sure it holds the key of addr.
+func decryptStoredKey(keyjson []byte, addr Address, auth string) (*Key, error) {
 	key, err := DecryptKey(keyjson, auth)
 	if err != nil {
 		return nil, err
 	}
 	// Make sure we're really operating on the requested key (no swap attacks)
-	if !bytes.Equal(key.Address, addr) {
+	if key.Address != addr {
 		return nil, fmt.Errorf("key content mismatch: have account %x, want %x", key.Address, addr)
 	}
 	return key, nil
@@ -72,54 +73,52 @@ func (ks keyStorePassphrase) GetKey(addr Address, filename, auth string) (*Key,
 
 // StoreKey generates a key, encrypts with 'auth' and stores in the given directory
 func StoreKey(dir, auth string, scryptN, scryptP int) (Account, error) {
-	_, a, err := storeNewKey(&keyStorePassphrase{dir, scryptN, scryptP, false}, rand.Reader, auth)
+	_, a, err := storeNewKey(&keyStorePassphrase{NewDirStorage(dir), ScryptKDF(scryptN, scryptP), false}, rand.Reader, auth)
 	return a, err
 }
 
 func (ks keyStorePassphrase) StoreKey(filename string, key *Key, auth string) error {
-	keyjson, err := EncryptKey(key, auth, ks.scryptN, ks.scryptP)
-	if err != nil {
-		return err
-	}
-	// Write into temporary file
-	tmpName, err := writeTemporaryKeyFile(filename, keyjson)
+	keyjson, err := EncryptKey(key, auth, ks.kdf)
 	if err != nil {
 		return err
 	}
 	if !ks.skipKeyFileVerification {
-		// Verify that we can decrypt the file with the given password.
-		_, err = ks.GetKey(key.Address, tmpName, auth)
-		if err != nil {
+		// Verify that we can decrypt the key with the given password before
+		// it replaces anything in the storage.
+		if _, err := decryptStoredKey(keyjson, key.Address, auth); err != nil {
 			msg := "An error was encountered when saving and verifying the keystore file. \n" +
 				"This indicates that the keystore is corrupted. \n" +
-				"The corrupted file is stored at \n%v\n" +
-				"Please file a ticket at:\n\n" +
-				"https://github.com/ethereum/go-ethereum/issues." +
+				"The key was not written to \n%v\n" +
 				"The error was : %s"
-			return fmt.Errorf(msg, tmpName, err)
+			return fmt.Errorf(msg, filename, err)
 		}
 	}
-	return os.Rename(tmpName, filename)
+	return ks.storage.Write(filename, keyjson)
 }
 
 func (ks keyStorePassphrase) JoinPath(filename string) string {
-	if filepath.IsAbs(filename) {
-		return filename
-	}
-	return filepath.Join(ks.keysDirPath, filename)
+	return ks.storage.JoinPath(filename)
 }
 
 // EncryptDataV3 encrypts the data given as 'data' with the password 'auth'.
 func EncryptDataV3(data, auth []byte, scryptN, scryptP int) (CryptoJSON, error) {
+	return EncryptData(data, auth, ScryptKDF(scryptN, scryptP))
+}
 
-	salt := make([]byte, 32)
-	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
-		panic("reading from crypto/rand failed: " + err.Error())
+// EncryptData encrypts the data given as 'data' with the password 'auth',
+// deriving the encryption key with the given KDF.
+func EncryptData(data, auth []byte, kdf KDFOptions) (CryptoJSON, error) {
+	kdf, err := kdf.validate()
+	if err != nil {
+		return CryptoJSON{}, err
 	}
-	derivedKey, err := scrypt.Key(auth, salt, scryptN, scryptR, scryptP, scryptDKLen)
+	derivedKey, kdfParamsJSON, err := kdf.deriveKey(auth)
 	if err != nil {
 		return CryptoJSON{}, err
 	}
+	if kdf.Cipher != CipherAES128CTR {
+		return encryptDataAEAD(data, derivedKey, kdf, kdfParamsJSON)
+	}
 	encryptKey := derivedKey[:16]
 
 	iv := make([]byte, aes.BlockSize) // 16
@@ -132,32 +131,47 @@ func EncryptDataV3(data, auth []byte, scryptN, scryptP int) (CryptoJSON, error)
 	}
 	mac := crypto.Keccak256(derivedKey[16:32], cipherText)
 
-	scryptParamsJSON := make(map[string]interface{}, 5)
-	scryptParamsJSON["n"] = scryptN
-	scryptParamsJSON["r"] = scryptR
-	scryptParamsJSON["p"] = scryptP
-	scryptParamsJSON["dklen"] = scryptDKLen
-	scryptParamsJSON["salt"] = hex.EncodeToString(salt)
 	cipherParamsJSON := cipherparamsJSON{
 		IV: hex.EncodeToString(iv),
 	}
 
 	cryptoStruct := CryptoJSON{
-		Cipher:       "aes-128-ctr",
+		Cipher:       CipherAES128CTR,
 		CipherText:   hex.EncodeToString(cipherText),
 		CipherParams: cipherParamsJSON,
-		KDF:          keyHeaderKDF,
-		KDFParams:    scryptParamsJSON,
+		KDF:          kdf.KDF,
+		KDFParams:    kdfParamsJSON,
 		MAC:          hex.EncodeToString(mac),
 	}
 	return cryptoStruct, nil
 }
 
-// EncryptKey encrypts a key using the specified scrypt parameters into a json
-// blob that can be decrypted later on.
-func EncryptKey(key *Key, auth string, scryptN, scryptP int) ([]byte, error) {
+// encryptDataAEAD encrypts data with the authenticated cipher of kdf, keyed
+// with the whole derived key.
+func encryptDataAEAD(data, derivedKey []byte, kdf KDFOptions, kdfParamsJSON map[string]interface{}) (CryptoJSON, error) {
+	aead, err := newAEAD(kdf.Cipher, derivedKey)
+	if err != nil {
+		return CryptoJSON{}, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		panic("reading from crypto/rand failed: " + err.Error())
+	}
+	cryptoStruct := CryptoJSON{
+		Cipher:       kdf.Cipher,
+		CipherText:   hex.EncodeToString(aead.Seal(nil, nonce, data, nil)),
+		CipherParams: cipherparamsJSON{IV: hex.EncodeToString(nonce)},
+		KDF:          kdf.KDF,
+		KDFParams:    kdfParamsJSON,
+	}
+	return cryptoStruct, nil
+}
+
+// EncryptKey encrypts a key using the specified KDF into a json blob that can
+// be decrypted later on.
+func EncryptKey(key *Key, auth string, kdf KDFOptions) ([]byte, error) {
 	keyBytes := math.PaddedBigBytes(key.PrivateKey.D, 32)
-	cryptoStruct, err := EncryptDataV3(keyBytes, []byte(auth), scryptN, scryptP)
+	cryptoStruct, err := EncryptData(keyBytes, []byte(auth), kdf)
 	if err != nil {
 		return nil, err
 	}
@@ -208,9 +222,14 @@ func DecryptKey(keyjson []byte, auth string) (*Key, error) {
 	}, nil
 }
 
-// DecryptDataV3 ...
+// DecryptDataV3 decrypts the crypto section of a keystore file with the
+// password 'auth', dispatching on its cipher.
 func DecryptDataV3(cj CryptoJSON, auth string) ([]byte, error) {
-	if cj.Cipher != "aes-128-ctr" {
+	switch cj.Cipher {
+	case CipherAES128CTR:
+	case CipherAES256GCM, CipherXChaCha20Poly1305:
+		return decryptDataAEAD(cj, auth)
+	default:
 		return nil, fmt.Errorf("Cipher not supported: %v", cj.Cipher)
 	}
 	mac, err := hex.DecodeString(cj.MAC)
@@ -245,6 +264,38 @@ func DecryptDataV3(cj CryptoJSON, auth string) ([]byte, error) {
 	return plainText, err
 }
 
+// decryptDataAEAD decrypts a crypto section encrypted with one of the
+// authenticated ciphers, a failing tag check means a wrong password.
+func decryptDataAEAD(cj CryptoJSON, auth string) ([]byte, error) {
+	nonce, err := hex.DecodeString(cj.CipherParams.IV)
+	if err != nil {
+		return nil, err
+	}
+	cipherText, err := hex.DecodeString(cj.CipherText)
+	if err != nil {
+		return nil, err
+	}
+	derivedKey, err := getKDFKey(cj, auth)
+	if err != nil {
+		return nil, err
+	}
+	if len(derivedKey) != kdfDKLen {
+		return nil, fmt.Errorf("invalid %s key length %d", cj.Cipher, len(derivedKey))
+	}
+	aead, err := newAEAD(cj.Cipher, derivedKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("invalid %s nonce length %d", cj.Cipher, len(nonce))
+	}
+	plainText, err := aead.Open(nil, nonce, cipherText, nil)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plainText, nil
+}
+
 func decryptKeyV3(keyProtected *encryptedKeyJSONV3, auth string) (keyBytes []byte, keyID []byte, err error) {
 	if keyProtected.Version != version {
 		return nil, nil, fmt.Errorf("Version not supported: %v", keyProtected.Version)
@@ -305,6 +356,17 @@ func getKDFKey(cryptoJSON CryptoJSON, auth string) ([]byte, error) {
 		p := ensureInt(cryptoJSON.KDFParams["p"])
 		return scrypt.Key(authArray, salt, n, r, p, dkLen)
 
+	} else if cryptoJSON.KDF == KDFArgon2id {
+		memory, time, threads, err := argon2Params(cryptoJSON.KDFParams)
+		if err != nil {
+			return nil, err
+		}
+		// Only keys of this size are ever written, the ciphers slice them
+		if dkLen != kdfDKLen {
+			return nil, fmt.Errorf("invalid argon2id key length %d", dkLen)
+		}
+		return argon2.IDKey(authArray, salt, time, memory, threads, kdfDKLen), nil
+
 	} else if cryptoJSON.KDF == "pbkdf2" {
 		c := ensureInt(cryptoJSON.KDFParams["c"])
 		prf := cryptoJSON.KDFParams["prf"].(string)
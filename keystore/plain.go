@@ -1,28 +1,24 @@
 package keystore
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
-	"os"
-	"path/filepath"
 )
 
 type keyStorePlain struct {
-	keysDirPath string
+	storage Storage
 }
 
 func (ks keyStorePlain) GetKey(addr Address, filename, auth string) (*Key, error) {
-	fd, err := os.Open(filename)
+	content, err := ks.storage.Read(filename)
 	if err != nil {
 		return nil, err
 	}
-	defer fd.Close()
 	key := new(Key)
-	if err := json.NewDecoder(fd).Decode(key); err != nil {
+	if err := json.Unmarshal(content, key); err != nil {
 		return nil, err
 	}
-	if !bytes.Equal(key.Address, addr) {
+	if key.Address != addr {
 		return nil, fmt.Errorf("key content mismatch: have address %x, want %x", key.Address, addr)
 	}
 	return key, nil
@@ -33,12 +29,9 @@ func (ks keyStorePlain) StoreKey(filename string, key *Key, auth string) error {
 	if err != nil {
 		return err
 	}
-	return writeKeyFile(filename, content)
+	return ks.storage.Write(filename, content)
 }
 
 func (ks keyStorePlain) JoinPath(filename string) string {
-	if filepath.IsAbs(filename) {
-		return filename
-	}
-	return filepath.Join(ks.keysDirPath, filename)
+	return ks.storage.JoinPath(filename)
 }
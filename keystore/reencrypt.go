@@ -0,0 +1,218 @@
+package keystore
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+)
+
+// ErrNoBackup is returned by Reencrypt when no backup storage is given.
+var ErrNoBackup = errors.New("re-encryption requires a backup storage")
+
+// ReencryptOptions configures KeyStore.Reencrypt.
+type ReencryptOptions struct {
+	// KDF selects the new key derivation function, its parameters and the
+	// cipher. The zero value means scrypt with the standard parameters.
+	KDF KDFOptions
+
+	// Backup receives the original contents of every file before it is
+	// rewritten, named after the base name of the file. It must not be the
+	// storage of the keystore. Pass it to RollbackReencrypt to undo the
+	// re-encryption.
+	Backup Storage
+
+	// Progress, if set, is called after each file with the number of files
+	// processed so far, the total number of files and the URL of the file.
+	Progress func(done, total int, url URL)
+}
+
+// Reencrypt re-encrypts every key and seed file of the keystore with the
+// new passphrase and the KDF settings of opts, e.g. to move keys created with
+// LightScryptN to StandardScryptN. Every file is backed up, atomically
+// replaced and then read back and decrypted to verify it. A file that fails
+// the verification is restored and aborts the run.
+//
+// Files already encrypted with the new settings and passphrase are skipped,
+// so an interrupted run can be resumed by calling Reencrypt again with the
+// same arguments. Keys created afterwards still use the KDF the keystore was
+// constructed with. Accounts must not be added, updated or deleted while
+// Reencrypt runs.
+func (ks *KeyStore) Reencrypt(passphrase, newPassphrase string, opts ReencryptOptions) error {
+	return ks.audit(AuditRecord{Op: AuditReencrypt}, ks.reencrypt(passphrase, newPassphrase, opts))
+}
+
+func (ks *KeyStore) reencrypt(passphrase, newPassphrase string, opts ReencryptOptions) error {
+	if opts.Backup == nil {
+		return ErrNoBackup
+	}
+	kdf, err := opts.KDF.validate()
+	if err != nil {
+		return err
+	}
+	var (
+		accounts = ks.cache.accounts()
+		seeds    = ks.cache.seedFiles()
+		total    = len(accounts) + len(seeds)
+		done     = 0
+	)
+	report := func(url URL) {
+		done++
+		if opts.Progress != nil {
+			opts.Progress(done, total, url)
+		}
+	}
+	for _, a := range accounts {
+		if err := ks.reencryptKey(a, passphrase, newPassphrase, kdf, opts.Backup); err != nil {
+			return fmt.Errorf("re-encrypting %s: %w", a.URL, err)
+		}
+		report(a.URL)
+	}
+	for _, sf := range seeds {
+		if err := ks.reencryptSeed(sf, passphrase, newPassphrase, kdf, opts.Backup); err != nil {
+			return fmt.Errorf("re-encrypting %s: %w", sf.url, err)
+		}
+		report(sf.url)
+	}
+	return nil
+}
+
+// RollbackReencrypt restores all files saved in backup by Reencrypt, undoing
+// a complete or interrupted re-encryption. The backup is left untouched.
+func (ks *KeyStore) RollbackReencrypt(backup Storage) error {
+	return ks.audit(AuditRecord{Op: AuditRollback}, ks.rollbackReencrypt(backup))
+}
+
+func (ks *KeyStore) rollbackReencrypt(backup Storage) error {
+	entries, err := backup.List()
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		data, err := backup.Read(e.Path)
+		if err != nil {
+			return err
+		}
+		if err := ks.backend.Write(ks.backend.JoinPath(filepath.Base(e.Path)), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reencryptKey re-encrypts the key file of account a.
+func (ks *KeyStore) reencryptKey(a Account, passphrase, newPassphrase string, kdf KDFOptions, backup Storage) error {
+	old, err := ks.backend.Read(a.URL.Path)
+	if err != nil {
+		return err
+	}
+	verify := func(keyjson []byte) error {
+		key, err := decryptStoredKey(keyjson, a.Address, newPassphrase)
+		if err != nil {
+			return err
+		}
+		ZeroKey(key.PrivateKey)
+		return nil
+	}
+	if reencrypted(old, kdf) && verify(old) == nil {
+		return nil
+	}
+	key, err := decryptStoredKey(old, a.Address, passphrase)
+	if err != nil {
+		return err
+	}
+	defer ZeroKey(key.PrivateKey)
+
+	data, err := EncryptKey(key, newPassphrase, kdf)
+	if err != nil {
+		return err
+	}
+	return ks.replaceFile(a.URL.Path, old, data, backup, verify)
+}
+
+// reencryptSeed re-encrypts the seed file sf, keeping its pinned accounts.
+func (ks *KeyStore) reencryptSeed(sf seedFile, passphrase, newPassphrase string, kdf KDFOptions, backup Storage) error {
+	path := sf.url.Path
+	old, err := ks.backend.Read(path)
+	if err != nil {
+		return err
+	}
+	sj, err := decodeSeedFile(path, old)
+	if err != nil {
+		return err
+	}
+	if reencrypted(old, kdf) {
+		if plain, err := DecryptDataV3(sj.Crypto, newPassphrase); err == nil {
+			for i := range plain {
+				plain[i] = 0
+			}
+			return nil
+		}
+	}
+	plain, err := DecryptDataV3(sj.Crypto, passphrase)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for i := range plain {
+			plain[i] = 0
+		}
+	}()
+	if sj.Crypto, err = EncryptData(plain, []byte(newPassphrase), kdf); err != nil {
+		return err
+	}
+	data, err := json.Marshal(sj)
+	if err != nil {
+		return err
+	}
+	return ks.replaceFile(path, old, data, backup, func(stored []byte) error {
+		sj, err := decodeSeedFile(path, stored)
+		if err != nil {
+			return err
+		}
+		have, err := DecryptDataV3(sj.Crypto, newPassphrase)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			for i := range have {
+				have[i] = 0
+			}
+		}()
+		if !bytes.Equal(have, plain) {
+			return errors.New("seed content mismatch")
+		}
+		return nil
+	})
+}
+
+// reencrypted reports whether the file contents are already encrypted with
+// the KDF settings kdf.
+func reencrypted(data []byte, kdf KDFOptions) bool {
+	have, err := KDFOptionsOf(data)
+	return err == nil && have == kdf
+}
+
+// replaceFile saves old to backup, atomically replaces the file at path with
+// data and checks the written file with verify. The original contents are
+// restored if the verification fails.
+func (ks *KeyStore) replaceFile(path string, old, data []byte, backup Storage, verify func([]byte) error) error {
+	if err := backup.Write(backup.JoinPath(filepath.Base(path)), old); err != nil {
+		return fmt.Errorf("backup failed: %w", err)
+	}
+	if err := ks.backend.Write(path, data); err != nil {
+		return err
+	}
+	stored, err := ks.backend.Read(path)
+	if err == nil {
+		err = verify(stored)
+	}
+	if err != nil {
+		if rerr := ks.backend.Write(path, old); rerr != nil {
+			return fmt.Errorf("verification failed: %v, restoring the original failed: %v", err, rerr)
+		}
+		return fmt.Errorf("verification failed: %w", err)
+	}
+	return nil
+}
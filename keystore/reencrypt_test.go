@@ -0,0 +1,100 @@
+package keystore
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestReencrypt(t *testing.T) {
+	storage := NewMemoryStorage()
+	ks := NewKeyStoreWithStorage(storage, LightScryptN, LightScryptP)
+
+	var accounts []Account
+	for i := 0; i < 3; i++ {
+		a, err := ks.NewAccount("old")
+		if err != nil {
+			t.Fatal(err)
+		}
+		accounts = append(accounts, a)
+	}
+	w, err := ks.ImportMnemonic(testMnemonic, "", "old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.Reencrypt("old", "new", ReencryptOptions{KDF: LightArgon2idKDF}); err != ErrNoBackup {
+		t.Errorf("missing backup: have %v, want %v", err, ErrNoBackup)
+	}
+
+	// Simulate an interrupted run that already migrated the first account.
+	if err := ks.UpdateWithKDF(accounts[0], "old", "new", LightArgon2idKDF); err != nil {
+		t.Fatal(err)
+	}
+	backup := NewMemoryStorage()
+	var progress []int
+	opts := ReencryptOptions{
+		KDF:    LightArgon2idKDF,
+		Backup: backup,
+		Progress: func(done, total int, url URL) {
+			if total != 4 {
+				t.Errorf("total = %d, want 4", total)
+			}
+			progress = append(progress, done)
+		},
+	}
+	if err := ks.Reencrypt("old", "new", opts); err != nil {
+		t.Fatal(err)
+	}
+	if len(progress) != 4 || progress[3] != 4 {
+		t.Errorf("unexpected progress %v", progress)
+	}
+	if entries, _ := backup.List(); len(entries) != 3 {
+		t.Errorf("have %d backups, want 3", len(entries))
+	}
+	for _, e := range mustList(t, storage) {
+		data, _ := storage.Read(e.Path)
+		if kdf, err := KDFOptionsOf(data); err != nil || kdf != LightArgon2idKDF {
+			t.Errorf("%s: KDFOptionsOf = %+v, %v", e.Path, kdf, err)
+		}
+	}
+	for _, a := range accounts {
+		if err := ks.Unlock(a, "new"); err != nil {
+			t.Errorf("unlock %s: %v", a.Address, err)
+		}
+	}
+	if err := w.Open("new"); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	// Running again is a no-op, rolling back restores the old passphrase
+	// everywhere except for the account migrated before the backup.
+	if err := ks.Reencrypt("old", "new", opts); err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.RollbackReencrypt(backup); err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range accounts[1:] {
+		if err := ks.Unlock(a, "old"); err != nil {
+			t.Errorf("unlock %s after rollback: %v", a.Address, err)
+		}
+	}
+	if err := w.Open("old"); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	// A wrong passphrase aborts without touching the file.
+	err = ks.Reencrypt("wrong", "new", ReencryptOptions{KDF: LightScryptKDF, Backup: NewMemoryStorage()})
+	if !errors.Is(err, ErrDecrypt) {
+		t.Errorf("wrong passphrase: have %v, want %v", err, ErrDecrypt)
+	}
+}
+
+func mustList(t *testing.T, s Storage) []StorageEntry {
+	entries, err := s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
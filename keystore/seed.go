@@ -0,0 +1,303 @@
+package keystore
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pborman/uuid"
+)
+
+// seedFileType marks keystore files holding an encrypted HD wallet seed
+// rather than a single private key.
+const seedFileType = "hd"
+
+// ErrNoMnemonic is returned when exporting the mnemonic of an HD wallet that
+// was imported from a raw seed.
+var ErrNoMnemonic = errors.New("wallet was imported without a mnemonic")
+
+// encryptedSeedJSON is the on-disk format of a seed file. The secret is
+// encrypted exactly like a V3 key, the pinned accounts are public and kept in
+// the clear so the account cache can list them without the passphrase.
+type encryptedSeedJSON struct {
+	Type     string            `json:"type"`
+	Crypto   CryptoJSON        `json:"crypto"`
+	Accounts []seedAccountJSON `json:"accounts"`
+	ID       string            `json:"id"`
+	Version  int               `json:"version"`
+}
+
+type seedAccountJSON struct {
+	Address string `json:"address"`
+	Path    string `json:"path"`
+}
+
+// seedSecretJSON is the plaintext encrypted into a seed file.
+type seedSecretJSON struct {
+	Mnemonic string `json:"mnemonic,omitempty"`
+	Seed     string `json:"seed"`
+}
+
+// seedFile is the public part of a seed file as tracked by the account cache.
+type seedFile struct {
+	url      URL
+	accounts []Account
+	paths    []DerivationPath
+}
+
+// parseSeedFile converts the pinned account list of a seed file at path.
+func parseSeedFile(path string, accounts []seedAccountJSON) (seedFile, error) {
+	sf := seedFile{url: URL{Scheme: KeyStoreScheme, Path: path}}
+	for i, a := range accounts {
+		addr, err := HexToAddress(a.Address)
+		if err != nil {
+			return seedFile{}, fmt.Errorf("account %d: %v", i, err)
+		}
+		p, err := ParseDerivationPath(a.Path)
+		if err != nil {
+			return seedFile{}, fmt.Errorf("account %d: %v", i, err)
+		}
+		sf.accounts = append(sf.accounts, Account{Address: addr, URL: hdAccountURL(sf.url, p)})
+		sf.paths = append(sf.paths, p)
+	}
+	return sf, nil
+}
+
+// seedFileName implements the naming convention for seed files:
+// UTC--<created_at UTC ISO8601>--hd-<id>
+func seedFileName(id uuid.UUID) string {
+	return fmt.Sprintf("UTC--%s--hd-%s", toISO8601(time.Now().UTC()), id)
+}
+
+// encryptSeed encrypts secret into a new seed file without pinned accounts.
+func encryptSeed(secret seedSecretJSON, auth string, kdf KDFOptions) ([]byte, error) {
+	plain, err := json.Marshal(secret)
+	if err != nil {
+		return nil, err
+	}
+	cryptoStruct, err := EncryptData(plain, []byte(auth), kdf)
+	for i := range plain {
+		plain[i] = 0
+	}
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(encryptedSeedJSON{
+		Type:     seedFileType,
+		Crypto:   cryptoStruct,
+		Accounts: []seedAccountJSON{},
+		ID:       uuid.NewRandom().String(),
+		Version:  version,
+	})
+}
+
+// readSeedFile loads the seed file at path without decrypting it.
+func readSeedFile(storage Storage, path string) (*encryptedSeedJSON, error) {
+	data, err := storage.Read(path)
+	if err != nil {
+		return nil, err
+	}
+	return decodeSeedFile(path, data)
+}
+
+// decodeSeedFile parses the contents of the seed file at path.
+func decodeSeedFile(path string, data []byte) (*encryptedSeedJSON, error) {
+	sj := new(encryptedSeedJSON)
+	if err := json.Unmarshal(data, sj); err != nil {
+		return nil, err
+	}
+	if sj.Type != seedFileType {
+		return nil, fmt.Errorf("%s is not a seed file", path)
+	}
+	if sj.Version != version {
+		return nil, fmt.Errorf("Version not supported: %v", sj.Version)
+	}
+	return sj, nil
+}
+
+// decryptSeedFile loads and decrypts the secret of the seed file at path.
+func decryptSeedFile(storage Storage, path, auth string) (*seedSecretJSON, error) {
+	sj, err := readSeedFile(storage, path)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := DecryptDataV3(sj.Crypto, auth)
+	if err != nil {
+		return nil, err
+	}
+	secret := new(seedSecretJSON)
+	err = json.Unmarshal(plain, secret)
+	for i := range plain {
+		plain[i] = 0
+	}
+	if err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// storeSeedAccounts rewrites the pinned account list of the seed file at path.
+func storeSeedAccounts(storage Storage, path string, accounts []Account, paths []DerivationPath) error {
+	sj, err := readSeedFile(storage, path)
+	if err != nil {
+		return err
+	}
+	sj.Accounts = make([]seedAccountJSON, len(accounts))
+	for i := range accounts {
+		sj.Accounts[i] = seedAccountJSON{
+			Address: hex.EncodeToString(accounts[i].Address[:]),
+			Path:    paths[i].String(),
+		}
+	}
+	data, err := json.Marshal(sj)
+	if err != nil {
+		return err
+	}
+	return storage.Write(path, data)
+}
+
+// newSeedFileWallet creates the HD wallet backed by the seed file sf. Open
+// decrypts the file with the passphrase, pinning an account rewrites it.
+func (ks *KeyStore) newSeedFileWallet(sf seedFile) *HDWallet {
+	path := sf.url.Path
+	w := newHDWallet(sf.url, func(passphrase string) ([]byte, error) {
+		secret, err := decryptSeedFile(ks.backend, path, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		return hex.DecodeString(secret.Seed)
+	})
+	w.persist = func(accounts []Account, paths []DerivationPath) error {
+		if err := storeSeedAccounts(ks.backend, path, accounts, paths); err != nil {
+			return err
+		}
+		// Update the cache right away, so a wallet refresh before the file
+		// change is noticed doesn't revert the pinned accounts.
+		ks.cache.addSeed(seedFile{url: sf.url, accounts: accounts, paths: paths})
+		return nil
+	}
+	w.audit = ks.root.audit
+	w.setPinned(sf.accounts, sf.paths)
+	return w
+}
+
+// NewHDWallet generates a new 24 word mnemonic and stores it in the key
+// directory, encrypted with passphrase. The mnemonic is returned once so it
+// can be backed up, afterwards it is only available through ExportMnemonic.
+func (ks *KeyStore) NewHDWallet(passphrase string) (*HDWallet, string, error) {
+	mnemonic, err := NewMnemonic(MnemonicEntropy24Words)
+	if err != nil {
+		return nil, "", err
+	}
+	w, err := ks.ImportMnemonic(mnemonic, "", passphrase)
+	if err != nil {
+		return nil, "", err
+	}
+	return w, mnemonic, nil
+}
+
+// ImportMnemonic stores a BIP39 mnemonic, together with the seed derived with
+// the optional BIP39 password, in the key directory encrypted with passphrase.
+// The returned wallet is closed, open it with passphrase to derive accounts.
+func (ks *KeyStore) ImportMnemonic(mnemonic, password, passphrase string) (*HDWallet, error) {
+	seed, err := NewSeed(mnemonic, password)
+	if err != nil {
+		return nil, err
+	}
+	return ks.importSeed(seedSecretJSON{Mnemonic: mnemonic, Seed: hex.EncodeToString(seed)}, passphrase)
+}
+
+// ImportSeed stores a raw BIP32 seed in the key directory encrypted with
+// passphrase.
+func (ks *KeyStore) ImportSeed(seed []byte, passphrase string) (*HDWallet, error) {
+	if len(seed) < 16 || len(seed) > 64 {
+		return nil, ErrInvalidSeed
+	}
+	return ks.importSeed(seedSecretJSON{Seed: hex.EncodeToString(seed)}, passphrase)
+}
+
+func (ks *KeyStore) importSeed(secret seedSecretJSON, passphrase string) (*HDWallet, error) {
+	data, err := encryptSeed(secret, passphrase, ks.kdf())
+	if err != nil {
+		return nil, ks.audit(AuditRecord{Op: AuditImport}, err)
+	}
+	path := ks.storage.JoinPath(seedFileName(uuid.NewRandom()))
+	sf := seedFile{url: URL{Scheme: KeyStoreScheme, Path: path}}
+	rec := AuditRecord{Op: AuditImport, Wallet: sf.url.String()}
+	if err := ks.backend.Write(path, data); err != nil {
+		return nil, ks.audit(rec, err)
+	}
+	ks.cache.addSeed(sf)
+	ks.refreshWallets()
+	w, err := ks.hdWallet(sf.url)
+	if err = ks.audit(rec, err); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// hdWallet returns the tracked HD wallet with the given URL, recording the
+// caller of a WithCaller view.
+func (ks *KeyStore) hdWallet(url URL) (*HDWallet, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	for _, w := range ks.wallets {
+		if hd, ok := w.(*HDWallet); ok && hd.URL() == url {
+			if ks != ks.root {
+				hd = hd.withAudit(ks.audit)
+			}
+			return hd, nil
+		}
+	}
+	return nil, ErrUnknownWallet
+}
+
+// ExportMnemonic decrypts and returns the mnemonic of a stored HD wallet.
+func (ks *KeyStore) ExportMnemonic(w *HDWallet, passphrase string) (string, error) {
+	mnemonic, err := ks.exportMnemonic(w, passphrase)
+	if err = ks.audit(AuditRecord{Op: AuditExport, Wallet: w.URL().String()}, err); err != nil {
+		return "", err
+	}
+	return mnemonic, nil
+}
+
+func (ks *KeyStore) exportMnemonic(w *HDWallet, passphrase string) (string, error) {
+	if _, err := ks.hdWallet(w.URL()); err != nil {
+		return "", err
+	}
+	secret, err := decryptSeedFile(ks.backend, w.URL().Path, passphrase)
+	if err != nil {
+		return "", err
+	}
+	if secret.Mnemonic == "" {
+		return "", ErrNoMnemonic
+	}
+	return secret.Mnemonic, nil
+}
+
+// DeleteHDWallet removes the seed file of a stored HD wallet if the
+// passphrase is correct.
+func (ks *KeyStore) DeleteHDWallet(w *HDWallet, passphrase string) error {
+	return ks.audit(AuditRecord{Op: AuditDelete, Wallet: w.URL().String()}, ks.deleteHDWallet(w, passphrase))
+}
+
+func (ks *KeyStore) deleteHDWallet(w *HDWallet, passphrase string) error {
+	if _, err := ks.hdWallet(w.URL()); err != nil {
+		return err
+	}
+	if _, err := decryptSeedFile(ks.backend, w.URL().Path, passphrase); err != nil {
+		return err
+	}
+	w.Close() // Wipe the master key if the wallet is open
+
+	path := w.URL().Path
+	if err := ks.backend.Remove(path); err != nil {
+		return err
+	}
+	ks.cache.deleteByFile(path)
+	ks.refreshWallets()
+	return nil
+}
@@ -0,0 +1,75 @@
+package keystore
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestSeedFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tron-keystore-seed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ks := NewKeyStore(dir, LightScryptN, LightScryptP)
+	if _, err := ks.NewAccount("pw"); err != nil {
+		t.Fatal(err)
+	}
+	w, err := ks.ImportMnemonic(testMnemonic, "", "pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ks.Wallets()) != 2 || len(ks.Accounts()) != 1 {
+		t.Fatalf("have %d wallets and %d accounts, want 2 and 1", len(ks.Wallets()), len(ks.Accounts()))
+	}
+	if err := w.Open("wrong"); err != ErrDecrypt {
+		t.Errorf("open with wrong passphrase: have %v, want %v", err, ErrDecrypt)
+	}
+	if err := w.Open("pw"); err != nil {
+		t.Fatal(err)
+	}
+	acc, err := w.Derive(DefaultBaseDerivationPath, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acc.Address.String() != "TUEZSdKsoDHQMeZwihtdoBiN46zxhGWYdH" {
+		t.Errorf("unexpected derived address %s", acc.Address)
+	}
+	if m, err := ks.ExportMnemonic(w, "pw"); err != nil || m != testMnemonic {
+		t.Errorf("export mnemonic: have %q, %v", m, err)
+	}
+
+	// A fresh keystore over the same directory finds the wallet and its pinned account
+	ks2 := NewKeyStore(dir, LightScryptN, LightScryptP)
+	var found *HDWallet
+	for _, wallet := range ks2.Wallets() {
+		if hd, ok := wallet.(*HDWallet); ok {
+			found = hd
+		}
+	}
+	if found == nil || found.URL() != w.URL() {
+		t.Fatalf("seed file wallet not discovered")
+	}
+	if accs := found.Accounts(); len(accs) != 1 || accs[0] != acc {
+		t.Fatalf("pinned accounts not restored: %v", accs)
+	}
+	if status, _ := found.Status(); status != "Closed" {
+		t.Errorf("discovered wallet should be closed, have %s", status)
+	}
+	sig, err := found.SignDataWithPassphrase(acc, "pw", "", []byte("hello"))
+	if err != nil || len(sig) != 65 {
+		t.Errorf("sign with passphrase: %v", err)
+	}
+
+	if err := ks.DeleteHDWallet(w, "wrong"); err != ErrDecrypt {
+		t.Errorf("delete with wrong passphrase: have %v, want %v", err, ErrDecrypt)
+	}
+	if err := ks.DeleteHDWallet(w, "pw"); err != nil {
+		t.Fatal(err)
+	}
+	if len(ks.Wallets()) != 1 {
+		t.Errorf("have %d wallets after delete, want 1", len(ks.Wallets()))
+	}
+}
@@ -0,0 +1,87 @@
+package keystore
+
+import (
+	"crypto/rand"
+	"errors"
+)
+
+// Shamir's secret sharing over GF(2^8) with the AES reduction polynomial
+// x^8 + x^4 + x^3 + x + 1, splitting every byte of a secret independently.
+// The field operations avoid lookup tables and branches on secret data.
+
+// gfMul multiplies two field elements.
+func gfMul(a, b byte) byte {
+	var p byte
+	for i := 0; i < 8; i++ {
+		p ^= -(b & 1) & a
+		a = (a << 1) ^ (-(a >> 7) & 0x1b)
+		b >>= 1
+	}
+	return p
+}
+
+// gfInv returns the multiplicative inverse a^254 of a, mapping 0 to 0.
+func gfInv(a byte) byte {
+	r := byte(1)
+	for i := 0; i < 7; i++ {
+		a = gfMul(a, a)
+		r = gfMul(r, a)
+	}
+	return r
+}
+
+// shamirSplit splits secret into n shares, any threshold of which recover it.
+// Share i is the evaluation of a random polynomial of degree threshold-1 at
+// x = i+1.
+func shamirSplit(secret []byte, threshold, n int) ([][]byte, error) {
+	if threshold < 2 || threshold > n || n > 255 {
+		return nil, errors.New("invalid share threshold")
+	}
+	coeffs := make([]byte, (threshold-1)*len(secret))
+	if _, err := rand.Read(coeffs); err != nil {
+		return nil, err
+	}
+	defer zeroBytes(coeffs)
+
+	shares := make([][]byte, n)
+	for i := range shares {
+		x := byte(i + 1)
+		shares[i] = make([]byte, len(secret))
+		for j, s := range secret {
+			// Horner's rule, highest coefficient first
+			var y byte
+			for k := threshold - 2; k >= 0; k-- {
+				y = gfMul(y, x) ^ coeffs[k*len(secret)+j]
+			}
+			shares[i][j] = gfMul(y, x) ^ s
+		}
+	}
+	return shares, nil
+}
+
+// shamirInterpolate evaluates the polynomial through the points (xs[i], ys[i])
+// at x. The xs must be distinct and the ys of equal length. Interpolating at
+// x = 0 recovers the secret.
+func shamirInterpolate(xs []byte, ys [][]byte, x byte) []byte {
+	out := make([]byte, len(ys[0]))
+	for i := range xs {
+		// Lagrange basis polynomial i at x
+		l := byte(1)
+		for j := range xs {
+			if i != j {
+				l = gfMul(l, gfMul(x^xs[j], gfInv(xs[i]^xs[j])))
+			}
+		}
+		for k := range out {
+			out[k] ^= gfMul(ys[i][k], l)
+		}
+	}
+	return out
+}
+
+// zeroBytes clears a buffer that held secret material.
+func zeroBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
@@ -0,0 +1,307 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pborman/uuid"
+	"github.com/tyler-smith/go-bip39"
+)
+
+const (
+	// ShareTypeKey marks shares of a private key.
+	ShareTypeKey = "key"
+
+	// ShareTypeMnemonic marks shares of a BIP39 mnemonic.
+	ShareTypeMnemonic = "mnemonic"
+
+	shareVersion = 1
+
+	// shareDigestLen is the length of the digest split together with the
+	// secret, verifying the reconstruction.
+	shareDigestLen = 8
+)
+
+var (
+	// ErrInvalidShare is returned for malformed or corrupted shares.
+	ErrInvalidShare = errors.New("invalid share")
+
+	// ErrTooFewShares is returned when combining less shares than the
+	// threshold of the split.
+	ErrTooFewShares = errors.New("not enough shares")
+
+	// ErrSharesMismatch is returned when shares of different splits are
+	// combined or the secret can't be reconstructed from the shares.
+	ErrSharesMismatch = errors.New("shares do not match")
+)
+
+// Share is one of the shares a key or mnemonic is split into with Shamir's
+// secret sharing, serialized as JSON for backups. Any Threshold shares of a
+// split restore the secret, fewer reveal nothing about it.
+//
+// Every share carries a checksum catching transcription errors and the secret
+// is split together with a digest, so combining wrong shares is detected
+// rather than producing a wrong key.
+type Share struct {
+	Version   int         `json:"version"`
+	ID        string      `json:"id"`   // Random identifier shared by all shares of a split
+	Type      string      `json:"type"` // ShareTypeKey or ShareTypeMnemonic
+	Threshold int         `json:"threshold"`
+	Index     int         `json:"index"`            // Share number, 1 to 255
+	Value     string      `json:"value,omitempty"`  // Hex encoded share unless encrypted
+	Crypto    *CryptoJSON `json:"crypto,omitempty"` // Share encrypted with a passphrase
+	Checksum  string      `json:"checksum"`
+}
+
+// ShareOptions configures the split of a secret into shares.
+type ShareOptions struct {
+	Threshold int // Number of shares needed to restore the secret, at least 2
+	Shares    int // Number of shares, at most 255
+
+	// Passphrases optionally encrypts the shares, one passphrase per share.
+	// Shares with an empty passphrase are left unencrypted.
+	Passphrases []string
+
+	// KDF is used to encrypt the shares, the zero value selects the standard
+	// scrypt parameters.
+	KDF KDFOptions
+}
+
+// SplitKey splits a private key into shares.
+func SplitKey(key *ecdsa.PrivateKey, opts ShareOptions) ([]*Share, error) {
+	secret := crypto.FromECDSA(key)
+	defer zeroBytes(secret)
+	return splitSecret(ShareTypeKey, secret, opts)
+}
+
+// SplitMnemonic splits the entropy of a BIP39 mnemonic into shares.
+func SplitMnemonic(mnemonic string, opts ShareOptions) ([]*Share, error) {
+	entropy, err := bip39.EntropyFromMnemonic(mnemonic)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMnemonic, err)
+	}
+	defer zeroBytes(entropy)
+	return splitSecret(ShareTypeMnemonic, entropy, opts)
+}
+
+// CombineKey restores a private key from shares, which must be decrypted.
+func CombineKey(shares []*Share) (*ecdsa.PrivateKey, error) {
+	secret, err := combineShares(ShareTypeKey, shares)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(secret)
+	return crypto.ToECDSA(secret)
+}
+
+// CombineMnemonic restores a mnemonic from shares, which must be decrypted.
+func CombineMnemonic(shares []*Share) (string, error) {
+	entropy, err := combineShares(ShareTypeMnemonic, shares)
+	if err != nil {
+		return "", err
+	}
+	defer zeroBytes(entropy)
+	return bip39.NewMnemonic(entropy)
+}
+
+// BackupKey splits the key of an account into shares.
+func (ks *KeyStore) BackupKey(a Account, passphrase string, opts ShareOptions) ([]*Share, error) {
+	rec := AuditRecord{Op: AuditExport, Account: a.Address}
+
+	_, key, err := ks.GetDecryptedKey(a, passphrase)
+	if err != nil {
+		return nil, ks.audit(rec, err)
+	}
+	defer ZeroKey(key.PrivateKey)
+
+	shares, err := SplitKey(key.PrivateKey, opts)
+	if err = ks.audit(rec, err); err != nil {
+		return nil, err
+	}
+	return shares, nil
+}
+
+// RestoreKey combines decrypted key shares and imports the key, encrypted
+// with passphrase.
+func (ks *KeyStore) RestoreKey(shares []*Share, passphrase string) (Account, error) {
+	key, err := CombineKey(shares)
+	if err != nil {
+		return Account{}, ks.audit(AuditRecord{Op: AuditImport}, err)
+	}
+	defer ZeroKey(key)
+	return ks.ImportECDSA(key, passphrase)
+}
+
+// RestoreMnemonic combines decrypted mnemonic shares and imports the
+// mnemonic like ImportMnemonic.
+func (ks *KeyStore) RestoreMnemonic(shares []*Share, password, passphrase string) (*HDWallet, error) {
+	mnemonic, err := CombineMnemonic(shares)
+	if err != nil {
+		return nil, ks.audit(AuditRecord{Op: AuditImport}, err)
+	}
+	return ks.ImportMnemonic(mnemonic, password, passphrase)
+}
+
+// Encrypted reports whether the share is encrypted with a passphrase.
+func (s *Share) Encrypted() bool {
+	return s.Crypto != nil
+}
+
+// Decrypt returns a copy of the share decrypted with passphrase, or the share
+// itself if it isn't encrypted.
+func (s *Share) Decrypt(passphrase string) (*Share, error) {
+	if err := s.Verify(); err != nil {
+		return nil, err
+	}
+	if !s.Encrypted() {
+		return s, nil
+	}
+	value, err := DecryptDataV3(*s.Crypto, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(value)
+
+	plain := *s
+	plain.Crypto, plain.Value = nil, hex.EncodeToString(value)
+	plain.Checksum = plain.checksum()
+	return &plain, nil
+}
+
+// Verify checks the fields and the checksum of the share.
+func (s *Share) Verify() error {
+	switch {
+	case s.Version != shareVersion:
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidShare, s.Version)
+	case s.Type != ShareTypeKey && s.Type != ShareTypeMnemonic:
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidShare, s.Type)
+	case s.Threshold < 2 || s.Index < 1 || s.Index > 255:
+		return fmt.Errorf("%w: threshold %d, index %d", ErrInvalidShare, s.Threshold, s.Index)
+	case (s.Value == "") == (s.Crypto == nil):
+		return fmt.Errorf("%w: need either a value or crypto", ErrInvalidShare)
+	case s.Checksum != s.checksum():
+		return fmt.Errorf("%w: checksum mismatch", ErrInvalidShare)
+	}
+	return nil
+}
+
+// checksum computes the checksum of the share, the first 4 bytes of the
+// SHA256 of all other fields.
+func (s *Share) checksum() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%d:%s:%s:%d:%d:%s:", s.Version, s.ID, s.Type, s.Threshold, s.Index, s.Value)
+	if s.Crypto != nil {
+		cj, _ := json.Marshal(s.Crypto)
+		h.Write(cj)
+	}
+	return hex.EncodeToString(h.Sum(nil)[:4])
+}
+
+// shareDigest computes the digest protecting a secret of the given split.
+func shareDigest(id, typ string, secret []byte) []byte {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s:%s:", id, typ)
+	h.Write(secret)
+	return h.Sum(nil)[:shareDigestLen]
+}
+
+// splitSecret splits secret, extended by its digest, into shares.
+func splitSecret(typ string, secret []byte, opts ShareOptions) ([]*Share, error) {
+	if len(opts.Passphrases) != 0 && len(opts.Passphrases) != opts.Shares {
+		return nil, fmt.Errorf("need %d share passphrases, have %d", opts.Shares, len(opts.Passphrases))
+	}
+	id := uuid.NewRandom().String()
+	payload := append(append([]byte{}, secret...), shareDigest(id, typ, secret)...)
+	defer zeroBytes(payload)
+
+	values, err := shamirSplit(payload, opts.Threshold, opts.Shares)
+	if err != nil {
+		return nil, err
+	}
+	shares := make([]*Share, len(values))
+	for i, v := range values {
+		s := &Share{Version: shareVersion, ID: id, Type: typ, Threshold: opts.Threshold, Index: i + 1}
+		if len(opts.Passphrases) != 0 && opts.Passphrases[i] != "" {
+			cj, err := EncryptData(v, []byte(opts.Passphrases[i]), opts.KDF)
+			if err != nil {
+				return nil, err
+			}
+			s.Crypto = &cj
+		} else {
+			s.Value = hex.EncodeToString(v)
+		}
+		zeroBytes(v)
+		s.Checksum = s.checksum()
+		shares[i] = s
+	}
+	return shares, nil
+}
+
+// combineShares restores the secret of a split from its decrypted shares.
+// Shares beyond the threshold must be consistent with the others.
+func combineShares(typ string, shares []*Share) ([]byte, error) {
+	if len(shares) == 0 {
+		return nil, ErrTooFewShares
+	}
+	var (
+		first = shares[0]
+		xs    = make([]byte, 0, len(shares))
+		ys    = make([][]byte, 0, len(shares))
+		seen  = make(map[int]bool)
+	)
+	defer func() {
+		for _, y := range ys {
+			zeroBytes(y)
+		}
+	}()
+	for _, s := range shares {
+		if err := s.Verify(); err != nil {
+			return nil, err
+		}
+		if s.Encrypted() {
+			return nil, fmt.Errorf("%w: share %d is encrypted", ErrInvalidShare, s.Index)
+		}
+		if s.ID != first.ID || s.Type != first.Type || s.Threshold != first.Threshold {
+			return nil, ErrSharesMismatch
+		}
+		if seen[s.Index] {
+			return nil, fmt.Errorf("%w: duplicate share %d", ErrInvalidShare, s.Index)
+		}
+		seen[s.Index] = true
+
+		y, err := hex.DecodeString(s.Value)
+		if err != nil || len(y) <= shareDigestLen || (len(ys) > 0 && len(y) != len(ys[0])) {
+			return nil, fmt.Errorf("%w: share %d has an invalid value", ErrInvalidShare, s.Index)
+		}
+		xs, ys = append(xs, byte(s.Index)), append(ys, y)
+	}
+	if first.Type != typ {
+		return nil, fmt.Errorf("%w: have %s shares, want %s", ErrInvalidShare, first.Type, typ)
+	}
+	t := first.Threshold
+	if len(shares) < t {
+		return nil, fmt.Errorf("%w: have %d, need %d", ErrTooFewShares, len(shares), t)
+	}
+	// Additional shares must lie on the polynomial of the first ones
+	for i := t; i < len(xs); i++ {
+		y := shamirInterpolate(xs[:t], ys[:t], xs[i])
+		ok := bytes.Equal(y, ys[i])
+		zeroBytes(y)
+		if !ok {
+			return nil, ErrSharesMismatch
+		}
+	}
+	payload := shamirInterpolate(xs[:t], ys[:t], 0)
+	secret, digest := payload[:len(payload)-shareDigestLen], payload[len(payload)-shareDigestLen:]
+	if !bytes.Equal(digest, shareDigest(first.ID, typ, secret)) {
+		zeroBytes(payload)
+		return nil, ErrSharesMismatch
+	}
+	return secret, nil
+}
@@ -0,0 +1,156 @@
+package keystore
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestGF256(t *testing.T) {
+	for a := 1; a < 256; a++ {
+		if p := gfMul(byte(a), gfInv(byte(a))); p != 1 {
+			t.Fatalf("%d * inverse = %d", a, p)
+		}
+	}
+}
+
+// roundTrip serializes shares like a backup would.
+func roundTrip(t *testing.T, shares []*Share) []*Share {
+	data, err := json.Marshal(shares)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []*Share
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+// flipHex changes the first digit of a hex string.
+func flipHex(s string) string {
+	if s[0] == '0' {
+		return "1" + s[1:]
+	}
+	return "0" + s[1:]
+}
+
+// withValue returns a copy of a share with the value replaced, keeping the
+// checksum valid.
+func withValue(s *Share, value string) *Share {
+	c := *s
+	c.Value = value
+	c.Checksum = c.checksum()
+	return &c
+}
+
+func TestKeyShares(t *testing.T) {
+	ks := NewKeyStoreWithStorage(NewMemoryStorage(), LightScryptN, LightScryptP)
+	a, err := ks.NewAccount("pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	shares, err := ks.BackupKey(a, "pw", ShareOptions{
+		Threshold:   3,
+		Shares:      5,
+		Passphrases: []string{"one", "", "three", "", ""},
+		KDF:         LightScryptKDF,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	shares = roundTrip(t, shares)
+	if !shares[0].Encrypted() || shares[1].Encrypted() {
+		t.Fatalf("unexpected encryption of shares")
+	}
+	if _, err := shares[0].Decrypt("wrong"); err != ErrDecrypt {
+		t.Errorf("wrong share passphrase: have %v, want %v", err, ErrDecrypt)
+	}
+	if _, err := CombineKey(shares[:3]); !errors.Is(err, ErrInvalidShare) {
+		t.Errorf("combined encrypted share: %v", err)
+	}
+	plain := make([]*Share, len(shares))
+	for i, s := range shares {
+		if plain[i], err = s.Decrypt([]string{"one", "", "three", "", ""}[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Any three shares restore the key, more are checked for consistency
+	for _, set := range [][]*Share{plain[:3], plain[2:], {plain[4], plain[0], plain[2]}, plain} {
+		key, err := CombineKey(set)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if addr := PubkeyToAddress(key.PublicKey); addr != a.Address {
+			t.Errorf("restored %s, want %s", addr, a.Address)
+		}
+	}
+	other := NewKeyStoreWithStorage(NewMemoryStorage(), LightScryptN, LightScryptP)
+	restored, err := other.RestoreKey(plain[1:4], "new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Address != a.Address {
+		t.Errorf("restored account %s, want %s", restored.Address, a.Address)
+	}
+
+	// Reconstruction errors are detected
+	if _, err := CombineKey(plain[:2]); !errors.Is(err, ErrTooFewShares) {
+		t.Errorf("two shares: have %v, want %v", err, ErrTooFewShares)
+	}
+	if _, err := CombineKey([]*Share{plain[0], plain[0], plain[1]}); !errors.Is(err, ErrInvalidShare) {
+		t.Errorf("duplicate share: have %v, want %v", err, ErrInvalidShare)
+	}
+	corrupt := *plain[1]
+	corrupt.Value = flipHex(corrupt.Value)
+	if _, err := CombineKey([]*Share{plain[0], &corrupt, plain[2]}); !errors.Is(err, ErrInvalidShare) {
+		t.Errorf("corrupted share: have %v, want %v", err, ErrInvalidShare)
+	}
+	forged := withValue(plain[1], flipHex(plain[1].Value))
+	if _, err := CombineKey([]*Share{plain[0], forged, plain[2]}); err != ErrSharesMismatch {
+		t.Errorf("forged share: have %v, want %v", err, ErrSharesMismatch)
+	}
+	extra := withValue(plain[3], flipHex(plain[3].Value))
+	if _, err := CombineKey([]*Share{plain[0], plain[1], plain[2], extra}); err != ErrSharesMismatch {
+		t.Errorf("inconsistent extra share: have %v, want %v", err, ErrSharesMismatch)
+	}
+	priv, _ := crypto.GenerateKey()
+	foreign, err := SplitKey(priv, ShareOptions{Threshold: 3, Shares: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CombineKey([]*Share{plain[0], plain[1], foreign[2]}); err != ErrSharesMismatch {
+		t.Errorf("shares of different keys: have %v, want %v", err, ErrSharesMismatch)
+	}
+	if _, err := CombineMnemonic(plain[:3]); !errors.Is(err, ErrInvalidShare) {
+		t.Errorf("key shares combined into mnemonic: %v", err)
+	}
+	if _, err := SplitKey(priv, ShareOptions{Threshold: 1, Shares: 3}); err == nil {
+		t.Errorf("threshold of one accepted")
+	}
+}
+
+func TestMnemonicShares(t *testing.T) {
+	shares, err := SplitMnemonic(testMnemonic, ShareOptions{Threshold: 2, Shares: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	shares = roundTrip(t, shares)
+	if m, err := CombineMnemonic([]*Share{shares[2], shares[0]}); err != nil || m != testMnemonic {
+		t.Fatalf("restored %q, %v", m, err)
+	}
+	ks := NewKeyStoreWithStorage(NewMemoryStorage(), LightScryptN, LightScryptP)
+	w, err := ks.RestoreMnemonic(shares[1:], "", "pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m, err := ks.ExportMnemonic(w, "pw"); err != nil || m != testMnemonic {
+		t.Errorf("exported %q, %v", m, err)
+	}
+	if _, err := SplitMnemonic("not a mnemonic", ShareOptions{Threshold: 2, Shares: 3}); !errors.Is(err, ErrInvalidMnemonic) {
+		t.Errorf("invalid mnemonic: have %v, want %v", err, ErrInvalidMnemonic)
+	}
+}
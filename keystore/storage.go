@@ -0,0 +1,231 @@
+package keystore
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rjeczalik/notify"
+	"go.uber.org/zap"
+)
+
+// Storage is a backend holding the encrypted key and seed blobs of a KeyStore.
+// Blobs are addressed by path, the path of a key blob is also the path of the
+// account URL. Implementations must be safe for concurrent use.
+//
+// Storages that can signal changes made by other processes should also
+// implement StorageNotifier, otherwise the KeyStore polls List.
+type Storage interface {
+	// JoinPath returns the path of the blob with the given name. Paths that
+	// were already joined must be returned unchanged.
+	JoinPath(name string) string
+
+	// List returns all blobs with the time they were last written.
+	List() ([]StorageEntry, error)
+
+	// Read returns the contents of the blob at path. Missing blobs are
+	// reported with an error wrapping os.ErrNotExist.
+	Read(path string) ([]byte, error)
+
+	// Write atomically creates or replaces the blob at path.
+	Write(path string, data []byte) error
+
+	// Remove deletes the blob at path.
+	Remove(path string) error
+}
+
+// StorageEntry describes a blob in a Storage.
+type StorageEntry struct {
+	Path    string
+	ModTime time.Time
+}
+
+// StorageNotifier is implemented by storages that can report changes.
+type StorageNotifier interface {
+	// Watch starts sending on changes whenever blobs are added, written or
+	// removed, until the returned stop function is called. Sends must not
+	// block, a pending notification is enough.
+	Watch(changes chan<- struct{}) (stop func(), err error)
+}
+
+// dirStorage is the default Storage, keeping one file per blob in a directory.
+type dirStorage struct {
+	dir string
+}
+
+// NewDirStorage returns a Storage keeping one file per blob in dir, as used
+// by NewKeyStore.
+func NewDirStorage(dir string) Storage {
+	dir, _ = filepath.Abs(dir)
+	return &dirStorage{dir: dir}
+}
+
+func (s *dirStorage) JoinPath(name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(s.dir, name)
+}
+
+func (s *dirStorage) List() ([]StorageEntry, error) {
+	files, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]StorageEntry, 0, len(files))
+	for _, fi := range files {
+		// Skip any non-key files from the folder
+		if nonKeyFile(fi) {
+			zap.L().Info("Ignoring file on account scan", zap.String("path", filepath.Join(s.dir, fi.Name())))
+			continue
+		}
+		entries = append(entries, StorageEntry{Path: filepath.Join(s.dir, fi.Name()), ModTime: fi.ModTime()})
+	}
+	return entries, nil
+}
+
+func (s *dirStorage) Read(path string) ([]byte, error) {
+	return ioutil.ReadFile(path)
+}
+
+func (s *dirStorage) Write(path string, data []byte) error {
+	return writeKeyFile(path, data)
+}
+
+func (s *dirStorage) Remove(path string) error {
+	return os.Remove(path)
+}
+
+func (s *dirStorage) Watch(changes chan<- struct{}) (func(), error) {
+	ev := make(chan notify.EventInfo, 10)
+	if err := notify.Watch(s.dir, ev, notify.All); err != nil {
+		return nil, err
+	}
+	quit := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-quit:
+				return
+			case <-ev:
+				select {
+				case changes <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+	return func() {
+		notify.Stop(ev)
+		close(quit)
+	}, nil
+}
+
+// MemoryStorage is a Storage keeping the blobs in memory, e.g. for tests or
+// for wrapping a remote store that is synchronized separately.
+type MemoryStorage struct {
+	blobs    map[string]memoryBlob
+	watchers map[chan<- struct{}]struct{}
+	mu       sync.RWMutex
+}
+
+type memoryBlob struct {
+	data    []byte
+	modTime time.Time
+}
+
+// NewMemoryStorage creates an empty in-memory Storage.
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{
+		blobs:    make(map[string]memoryBlob),
+		watchers: make(map[chan<- struct{}]struct{}),
+	}
+}
+
+// JoinPath implements Storage, blob names are used as paths.
+func (s *MemoryStorage) JoinPath(name string) string {
+	return name
+}
+
+// List implements Storage.
+func (s *MemoryStorage) List() ([]StorageEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries := make([]StorageEntry, 0, len(s.blobs))
+	for path, blob := range s.blobs {
+		entries = append(entries, StorageEntry{Path: path, ModTime: blob.modTime})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	return entries, nil
+}
+
+// Read implements Storage.
+func (s *MemoryStorage) Read(path string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	blob, ok := s.blobs[path]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", path, os.ErrNotExist)
+	}
+	return append([]byte(nil), blob.data...), nil
+}
+
+// Write implements Storage.
+func (s *MemoryStorage) Write(path string, data []byte) error {
+	s.mu.Lock()
+	modTime := time.Now()
+	if old, ok := s.blobs[path]; ok && !modTime.After(old.modTime) {
+		// Make sure every write is seen as a modification
+		modTime = old.modTime.Add(time.Nanosecond)
+	}
+	s.blobs[path] = memoryBlob{data: append([]byte(nil), data...), modTime: modTime}
+	s.mu.Unlock()
+
+	s.notify()
+	return nil
+}
+
+// Remove implements Storage.
+func (s *MemoryStorage) Remove(path string) error {
+	s.mu.Lock()
+	_, ok := s.blobs[path]
+	delete(s.blobs, path)
+	s.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("%s: %w", path, os.ErrNotExist)
+	}
+	s.notify()
+	return nil
+}
+
+// Watch implements StorageNotifier.
+func (s *MemoryStorage) Watch(changes chan<- struct{}) (func(), error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.watchers[changes] = struct{}{}
+	return func() {
+		s.mu.Lock()
+		delete(s.watchers, changes)
+		s.mu.Unlock()
+	}, nil
+}
+
+func (s *MemoryStorage) notify() {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for ch := range s.watchers {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package keystore
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMemoryStorageKeyStore(t *testing.T) {
+	storage := NewMemoryStorage()
+	ks := NewKeyStoreWithStorage(storage, LightScryptN, LightScryptP)
+
+	events := make(chan WalletEvent, 4)
+	sub := ks.Subscribe(events)
+	defer sub.Unsubscribe()
+
+	a, err := ks.NewAccount("pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if accs := ks.Accounts(); len(accs) != 1 || accs[0] != a {
+		t.Fatalf("unexpected accounts %v", accs)
+	}
+	if _, err := ks.SignHashWithPassphrase(a, "pw", make([]byte, 32)); err != nil {
+		t.Fatal(err)
+	}
+
+	// A key written by another keystore sharing the storage shows up via the
+	// change notifications.
+	other := NewKeyStoreWithStorage(storage, LightScryptN, LightScryptP)
+	b, err := other.NewAccount("pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.After(5 * time.Second)
+	for arrived := 0; arrived < 2; {
+		select {
+		case ev := <-events:
+			if ev.Kind == WalletArrived {
+				arrived++
+			}
+		case <-deadline:
+			t.Fatalf("missing wallet arrival events, have %d", arrived)
+		}
+	}
+	if !ks.HasAddress(b.Address) {
+		t.Errorf("account of other keystore not found")
+	}
+
+	if err := ks.Delete(a, "pw"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := storage.Read(a.URL.Path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("deleted blob still readable: %v", err)
+	}
+	if ks.HasAddress(a.Address) {
+		t.Errorf("deleted account still listed")
+	}
+}
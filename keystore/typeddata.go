@@ -0,0 +1,463 @@
+package keystore
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	ethcmn "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Chain ids of the public Tron networks, the last 4 bytes of their genesis
+// block ids, as used in TIP-712 domains.
+const (
+	ChainIDMainnet = 0x2b6653dc
+	ChainIDShasta  = 0x94a9059e
+	ChainIDNile    = 0xcd8690dc
+)
+
+// typedDataDomainType is the name of the type of the domain.
+const typedDataDomainType = "EIP712Domain"
+
+// ErrInvalidTypedData is returned when typed data can't be encoded.
+var ErrInvalidTypedData = errors.New("invalid typed data")
+
+// TypedData is TIP-712 typed structured data, EIP-712 with Tron addresses, in
+// the JSON format of eth_signTypedData_v4 and TronLink.
+//
+// Values of the message follow the JSON decoding of the format: structs are
+// maps, arrays are slices, integers are numbers or decimal or hex strings, and
+// bytes are hex strings. Addresses are accepted in base58, the 21 byte hex
+// form and the 20 byte evm form and are all encoded alike. The trcToken type
+// is encoded as uint256.
+type TypedData struct {
+	Types       TypedDataTypes         `json:"types"`
+	PrimaryType string                 `json:"primaryType"`
+	Domain      TypedDataDomain        `json:"domain"`
+	Message     map[string]interface{} `json:"message"`
+}
+
+// TypedDataTypes maps the names of struct types to their fields. The domain
+// type EIP712Domain is derived from the domain unless given.
+type TypedDataTypes map[string][]TypedDataField
+
+// TypedDataField is a field of a struct type.
+type TypedDataField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// TypedDataDomain separates the typed data of different applications and
+// networks. Empty fields are left out of the domain.
+type TypedDataDomain struct {
+	Name              string   `json:"name,omitempty"`
+	Version           string   `json:"version,omitempty"`
+	ChainID           *big.Int `json:"chainId,omitempty"`
+	VerifyingContract string   `json:"verifyingContract,omitempty"`
+	Salt              string   `json:"salt,omitempty"`
+}
+
+// UnmarshalJSON accepts the chain id as number or as decimal or hex string.
+func (d *TypedDataDomain) UnmarshalJSON(input []byte) error {
+	type domain TypedDataDomain
+	var dec struct {
+		domain
+		ChainID interface{} `json:"chainId,omitempty"`
+	}
+	decoder := json.NewDecoder(bytes.NewReader(input))
+	decoder.UseNumber()
+	if err := decoder.Decode(&dec); err != nil {
+		return err
+	}
+	*d = TypedDataDomain(dec.domain)
+	if dec.ChainID != nil {
+		id, err := typedDataInteger(dec.ChainID)
+		if err != nil {
+			return fmt.Errorf("chainId: %v", err)
+		}
+		d.ChainID = id
+	}
+	return nil
+}
+
+// types returns the fields of the domain type and the domain as message.
+func (d *TypedDataDomain) types() ([]TypedDataField, map[string]interface{}) {
+	var (
+		fields []TypedDataField
+		values = make(map[string]interface{})
+	)
+	if d.Name != "" {
+		fields = append(fields, TypedDataField{"name", "string"})
+		values["name"] = d.Name
+	}
+	if d.Version != "" {
+		fields = append(fields, TypedDataField{"version", "string"})
+		values["version"] = d.Version
+	}
+	if d.ChainID != nil {
+		fields = append(fields, TypedDataField{"chainId", "uint256"})
+		values["chainId"] = d.ChainID
+	}
+	if d.VerifyingContract != "" {
+		fields = append(fields, TypedDataField{"verifyingContract", "address"})
+		values["verifyingContract"] = d.VerifyingContract
+	}
+	if d.Salt != "" {
+		fields = append(fields, TypedDataField{"salt", "bytes32"})
+		values["salt"] = d.Salt
+	}
+	return fields, values
+}
+
+// Hash computes the hash to sign for the typed data,
+//
+//	keccak256("\x19\x01" || DomainSeparator() || HashStruct(primaryType, message)).
+func (td *TypedData) Hash() ([]byte, error) {
+	domain, err := td.DomainSeparator()
+	if err != nil {
+		return nil, err
+	}
+	message, err := td.HashStruct(td.PrimaryType, td.Message)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.Keccak256([]byte{0x19, 0x01}, domain, message), nil
+}
+
+// DomainSeparator computes the hash of the domain.
+func (td *TypedData) DomainSeparator() ([]byte, error) {
+	fields, values := td.Domain.types()
+	types := td.Types
+	if _, ok := types[typedDataDomainType]; !ok {
+		types = make(TypedDataTypes, len(td.Types)+1)
+		for name, f := range td.Types {
+			types[name] = f
+		}
+		types[typedDataDomainType] = fields
+	}
+	return (&TypedData{Types: types}).HashStruct(typedDataDomainType, values)
+}
+
+// HashStruct computes the hash of data as struct of the given type.
+func (td *TypedData) HashStruct(primaryType string, data map[string]interface{}) ([]byte, error) {
+	enc, err := td.encodeData(primaryType, data, 1)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.Keccak256(enc), nil
+}
+
+// TypeHash computes the hash of the encoding of a struct type.
+func (td *TypedData) TypeHash(primaryType string) ([]byte, error) {
+	enc, err := td.EncodeType(primaryType)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.Keccak256([]byte(enc)), nil
+}
+
+// EncodeType encodes a struct type with its fields, followed by all struct
+// types it references in alphabetical order, e.g.
+//
+//	Mail(Person from,Person to,string contents)Person(string name,address wallet)
+func (td *TypedData) EncodeType(primaryType string) (string, error) {
+	if _, ok := td.Types[primaryType]; !ok {
+		return "", fmt.Errorf("%w: unknown type %q", ErrInvalidTypedData, primaryType)
+	}
+	deps := td.dependencies(primaryType, nil)
+	sort.Strings(deps[1:])
+
+	var b strings.Builder
+	for _, dep := range deps {
+		b.WriteString(dep + "(")
+		for i, f := range td.Types[dep] {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			b.WriteString(f.Type + " " + f.Name)
+		}
+		b.WriteString(")")
+	}
+	return b.String(), nil
+}
+
+// dependencies returns primaryType followed by all struct types it
+// references, directly or indirectly.
+func (td *TypedData) dependencies(primaryType string, found []string) []string {
+	primaryType = typedDataBaseType(primaryType)
+	for _, f := range found {
+		if f == primaryType {
+			return found
+		}
+	}
+	if _, ok := td.Types[primaryType]; !ok {
+		return found
+	}
+	found = append(found, primaryType)
+	for _, field := range td.Types[primaryType] {
+		found = td.dependencies(field.Type, found)
+	}
+	return found
+}
+
+// typedDataBaseType strips the array dimensions from a type.
+func typedDataBaseType(typ string) string {
+	if i := strings.IndexByte(typ, '['); i >= 0 {
+		return typ[:i]
+	}
+	return typ
+}
+
+// maxTypedDataDepth limits the nesting of structs and arrays.
+const maxTypedDataDepth = 32
+
+// encodeData encodes data as struct of the given type: the type hash followed
+// by the encoded fields. All fields must be given and no others.
+func (td *TypedData) encodeData(primaryType string, data map[string]interface{}, depth int) ([]byte, error) {
+	fields, ok := td.Types[primaryType]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown type %q", ErrInvalidTypedData, primaryType)
+	}
+	if depth > maxTypedDataDepth {
+		return nil, fmt.Errorf("%w: nesting too deep", ErrInvalidTypedData)
+	}
+	if len(data) > len(fields) {
+		return nil, fmt.Errorf("%w: %s has undeclared fields", ErrInvalidTypedData, primaryType)
+	}
+	typeHash, err := td.TypeHash(primaryType)
+	if err != nil {
+		return nil, err
+	}
+	buf := bytes.NewBuffer(typeHash)
+	for _, f := range fields {
+		v, ok := data[f.Name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s.%s is missing", ErrInvalidTypedData, primaryType, f.Name)
+		}
+		enc, err := td.encodeValue(f.Type, v, depth)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", primaryType, f.Name, err)
+		}
+		buf.Write(enc)
+	}
+	return buf.Bytes(), nil
+}
+
+var (
+	typedDataArray  = regexp.MustCompile(`^(.+)\[([0-9]*)\]$`)
+	typedDataInt    = regexp.MustCompile(`^(u?)int([0-9]+)$`)
+	typedDataBytesN = regexp.MustCompile(`^bytes([0-9]+)$`)
+)
+
+// encodeValue encodes a value of the given type into 32 bytes.
+func (td *TypedData) encodeValue(typ string, v interface{}, depth int) ([]byte, error) {
+	if m := typedDataArray.FindStringSubmatch(typ); m != nil {
+		return td.encodeArray(m[1], m[2], v, depth)
+	}
+	if _, ok := td.Types[typ]; ok {
+		data, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%w: %s is not a struct", ErrInvalidTypedData, typ)
+		}
+		enc, err := td.encodeData(typ, data, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		return crypto.Keccak256(enc), nil
+	}
+	switch typ {
+	case "address":
+		addr, err := typedDataAddress(v)
+		if err != nil {
+			return nil, err
+		}
+		return ethcmn.LeftPadBytes(addr.EVM().Bytes(), 32), nil
+	case "bool":
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%w: %v is not a bool", ErrInvalidTypedData, v)
+		}
+		if b {
+			return math.U256Bytes(big.NewInt(1)), nil
+		}
+		return make([]byte, 32), nil
+	case "string":
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: %v is not a string", ErrInvalidTypedData, v)
+		}
+		return crypto.Keccak256([]byte(s)), nil
+	case "bytes":
+		b, err := typedDataBytes(v)
+		if err != nil {
+			return nil, err
+		}
+		return crypto.Keccak256(b), nil
+	case "trcToken":
+		return typedDataEncodeInt(false, 256, v)
+	}
+	if m := typedDataBytesN.FindStringSubmatch(typ); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		if n < 1 || n > 32 {
+			return nil, fmt.Errorf("%w: unknown type %q", ErrInvalidTypedData, typ)
+		}
+		b, err := typedDataBytes(v)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) != n {
+			return nil, fmt.Errorf("%w: have %d bytes for %s", ErrInvalidTypedData, len(b), typ)
+		}
+		return ethcmn.RightPadBytes(b, 32), nil
+	}
+	if m := typedDataInt.FindStringSubmatch(typ); m != nil {
+		bits, _ := strconv.Atoi(m[2])
+		if bits < 8 || bits > 256 || bits%8 != 0 {
+			return nil, fmt.Errorf("%w: unknown type %q", ErrInvalidTypedData, typ)
+		}
+		return typedDataEncodeInt(m[1] == "", bits, v)
+	}
+	return nil, fmt.Errorf("%w: unknown type %q", ErrInvalidTypedData, typ)
+}
+
+// encodeArray encodes an array of elem values, with the given length unless
+// it is dynamic, as the hash of the concatenated encoded elements.
+func (td *TypedData) encodeArray(elem, length string, v interface{}, depth int) ([]byte, error) {
+	rv := reflect.ValueOf(v)
+	if v == nil || (rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array) {
+		return nil, fmt.Errorf("%w: %v is not an array", ErrInvalidTypedData, v)
+	}
+	if length != "" {
+		if n, err := strconv.Atoi(length); err != nil || n != rv.Len() {
+			return nil, fmt.Errorf("%w: have %d array elements, want %s", ErrInvalidTypedData, rv.Len(), length)
+		}
+	}
+	if depth > maxTypedDataDepth {
+		return nil, fmt.Errorf("%w: nesting too deep", ErrInvalidTypedData)
+	}
+	var buf bytes.Buffer
+	for i := 0; i < rv.Len(); i++ {
+		enc, err := td.encodeValue(elem, rv.Index(i).Interface(), depth+1)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(enc)
+	}
+	return crypto.Keccak256(buf.Bytes()), nil
+}
+
+// typedDataAddress converts an address value.
+func typedDataAddress(v interface{}) (Address, error) {
+	switch v := v.(type) {
+	case Address:
+		return v, nil
+	case ethcmn.Address:
+		return EVMToAddress(v), nil
+	case []byte:
+		return BytesToAddress(v)
+	case string:
+		return ParseAddress(v)
+	}
+	return Address{}, fmt.Errorf("%w: %v is not an address", ErrInvalidTypedData, v)
+}
+
+// typedDataBytes converts a bytes value, given as hex string or byte slice.
+func typedDataBytes(v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		b, err := hex.DecodeString(strings.TrimPrefix(v, "0x"))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q is not hex: %v", ErrInvalidTypedData, v, err)
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("%w: %v is not bytes", ErrInvalidTypedData, v)
+}
+
+// typedDataEncodeInt encodes an integer of the given size in two's complement.
+func typedDataEncodeInt(signed bool, bits int, v interface{}) ([]byte, error) {
+	x, err := typedDataInteger(v)
+	if err != nil {
+		return nil, err
+	}
+	var min, max *big.Int
+	if signed {
+		max = new(big.Int).Lsh(big.NewInt(1), uint(bits-1))
+		min = new(big.Int).Neg(max)
+	} else {
+		max = new(big.Int).Lsh(big.NewInt(1), uint(bits))
+		min = new(big.Int)
+	}
+	if x.Cmp(min) < 0 || x.Cmp(max) >= 0 {
+		return nil, fmt.Errorf("%w: %v out of range", ErrInvalidTypedData, x)
+	}
+	return math.U256Bytes(new(big.Int).Set(x)), nil
+}
+
+// typedDataInteger converts an integer value, given as Go integer, JSON
+// number or decimal or hex string.
+func typedDataInteger(v interface{}) (*big.Int, error) {
+	switch v := v.(type) {
+	case *big.Int:
+		if v != nil {
+			return v, nil
+		}
+	case int:
+		return big.NewInt(int64(v)), nil
+	case int64:
+		return big.NewInt(v), nil
+	case uint64:
+		return new(big.Int).SetUint64(v), nil
+	case float64:
+		// JSON numbers decoded into interface{}, exact up to 2^53
+		if v == float64(int64(v)) && v < 1<<53 && v > -(1<<53) {
+			return big.NewInt(int64(v)), nil
+		}
+		return nil, fmt.Errorf("%w: %v is not an exact integer", ErrInvalidTypedData, v)
+	case json.Number:
+		return typedDataInteger(string(v))
+	case string:
+		neg := strings.HasPrefix(v, "-")
+		x, ok := math.ParseBig256(strings.TrimPrefix(v, "-"))
+		if !ok {
+			return nil, fmt.Errorf("%w: %q is not an integer", ErrInvalidTypedData, v)
+		}
+		if neg {
+			x.Neg(x)
+		}
+		return x, nil
+	}
+	return nil, fmt.Errorf("%w: %v is not an integer", ErrInvalidTypedData, v)
+}
+
+// VerifyTypedData recovers the base58 address that signed the typed data. The
+// signature is valid if it matches the expected signer.
+func VerifyTypedData(td *TypedData, signature []byte) (string, error) {
+	hash, err := td.Hash()
+	if err != nil {
+		return "", err
+	}
+	return recoverMessageSigner(hash, signature)
+}
+
+// SignTypedData signs the TIP-712 hash of td with the unlocked account. The
+// signature has V as 27 or 28 like the ones of TronWeb.
+func (ks *KeyStore) SignTypedData(a Account, td *TypedData) ([]byte, error) {
+	hash, err := td.Hash()
+	if err != nil {
+		return nil, err
+	}
+	return messageSignature(ks.SignHash(a, hash))
+}
@@ -0,0 +1,190 @@
+package keystore
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// The Mail example of EIP-712 with Tron addresses, which must hash like the
+// original.
+const mailTypedData = `{
+	"types": {
+		"EIP712Domain": [
+			{"name": "name", "type": "string"},
+			{"name": "version", "type": "string"},
+			{"name": "chainId", "type": "uint256"},
+			{"name": "verifyingContract", "type": "address"}
+		],
+		"Person": [
+			{"name": "name", "type": "string"},
+			{"name": "wallet", "type": "address"}
+		],
+		"Mail": [
+			{"name": "from", "type": "Person"},
+			{"name": "to", "type": "Person"},
+			{"name": "contents", "type": "string"}
+		]
+	},
+	"primaryType": "Mail",
+	"domain": {
+		"name": "Ether Mail",
+		"version": "1",
+		"chainId": 1,
+		"verifyingContract": "TUe6BwpA7sVTDKaJQoia7FWZpC9sK8WM2t"
+	},
+	"message": {
+		"from": {"name": "Cow", "wallet": "41cd2a3d9f938e13cd947ec05abc7fe734df8dd826"},
+		"to": {"name": "Bob", "wallet": "0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB"},
+		"contents": "Hello, Bob!"
+	}
+}`
+
+func parseTypedData(t *testing.T, s string) *TypedData {
+	var td TypedData
+	if err := json.Unmarshal([]byte(s), &td); err != nil {
+		t.Fatal(err)
+	}
+	return &td
+}
+
+func TestTypedDataHash(t *testing.T) {
+	td := parseTypedData(t, mailTypedData)
+
+	if enc, err := td.EncodeType("Mail"); err != nil || enc != "Mail(Person from,Person to,string contents)Person(string name,address wallet)" {
+		t.Errorf("unexpected type encoding %s, %v", enc, err)
+	}
+	if h, err := td.TypeHash("Mail"); err != nil || !bytes.Equal(h, common.FromHex("0xa0cedeb2dc280ba39b857546d74f5549c3a1d7bdc2dd96bf881f76108e23dac2")) {
+		t.Errorf("unexpected type hash %x, %v", h, err)
+	}
+	if _, err := td.EncodeType("Letter"); !errors.Is(err, ErrInvalidTypedData) {
+		t.Errorf("unknown type encoded: %v", err)
+	}
+	if _, err := td.TypeHash("Letter"); !errors.Is(err, ErrInvalidTypedData) {
+		t.Errorf("unknown type hashed: %v", err)
+	}
+	unknown := *td
+	unknown.PrimaryType = "Letter"
+	if _, err := unknown.Hash(); !errors.Is(err, ErrInvalidTypedData) {
+		t.Errorf("unknown primary type hashed: %v", err)
+	}
+	h, err := td.HashStruct("Mail", td.Message)
+	if err != nil || !bytes.Equal(h, common.FromHex("0xc52c0ee5d84264471806290a3f2c4cecfc5490626bf912d01f240d7a274b371e")) {
+		t.Errorf("unexpected struct hash %x, %v", h, err)
+	}
+	domain, err := td.DomainSeparator()
+	if err != nil || !bytes.Equal(domain, common.FromHex("0xf2cee375fa42b42143804025fc449deafd50cc031ca257e0b194a650a912090f")) {
+		t.Errorf("unexpected domain separator %x, %v", domain, err)
+	}
+	want := common.FromHex("0xbe609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2")
+	if h, err := td.Hash(); err != nil || !bytes.Equal(h, want) {
+		t.Errorf("unexpected hash %x, %v", h, err)
+	}
+	// The domain type is derived from the domain if left out
+	delete(td.Types, "EIP712Domain")
+	if h, err := td.Hash(); err != nil || !bytes.Equal(h, want) {
+		t.Errorf("unexpected hash with derived domain type %x, %v", h, err)
+	}
+}
+
+func TestSignTypedData(t *testing.T) {
+	td := parseTypedData(t, mailTypedData)
+
+	ks := NewKeyStoreWithStorage(NewMemoryStorage(), LightScryptN, LightScryptP)
+	key, _ := crypto.ToECDSA(crypto.Keccak256([]byte("cow")))
+	a, err := ks.ImportECDSA(key, "pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.Unlock(a, "pw"); err != nil {
+		t.Fatal(err)
+	}
+	sig, err := ks.SignTypedData(a, td)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := common.FromHex("0x4355c47d63924e8a72e509b65029052eb6c299d53a04e167c5775fd466751c9d07299936d304c153f6443dfa05f40ff007d72911b6f72307f996231605b915621c")
+	if !bytes.Equal(sig, want) {
+		t.Errorf("unexpected signature %x", sig)
+	}
+	if wsig, err := ks.Wallets()[0].SignTypedData(a, td); err != nil || !bytes.Equal(wsig, want) {
+		t.Errorf("unexpected wallet signature %x, %v", wsig, err)
+	}
+	if addr, err := VerifyTypedData(td, sig); err != nil || addr != "TUg28KYvCXWW81EqMUeZvCZmZw2BChk1HQ" {
+		t.Errorf("recovered %s, %v", addr, err)
+	}
+	// Any change of the message changes the signer
+	td.Message["contents"] = "Hello, Alice!"
+	if addr, err := VerifyTypedData(td, sig); err == nil && addr == a.Address.String() {
+		t.Errorf("signature valid for modified message")
+	}
+}
+
+func TestTypedDataValues(t *testing.T) {
+	td := parseTypedData(t, `{
+		"types": {
+			"Order": [
+				{"name": "maker", "type": "address"},
+				{"name": "token", "type": "trcToken"},
+				{"name": "amount", "type": "uint256"},
+				{"name": "delta", "type": "int8"},
+				{"name": "salt", "type": "bytes32"},
+				{"name": "data", "type": "bytes"},
+				{"name": "ok", "type": "bool"},
+				{"name": "tags", "type": "string[2]"}
+			]
+		},
+		"primaryType": "Order",
+		"domain": {"name": "Exchange", "chainId": "0x2b6653dc"},
+		"message": {
+			"maker": "TUEZSdKsoDHQMeZwihtdoBiN46zxhGWYdH",
+			"token": 1002000,
+			"amount": "100000000000000000000",
+			"delta": -128,
+			"salt": "0x0000000000000000000000000000000000000000000000000000000000000001",
+			"data": "0xdeadbeef",
+			"ok": true,
+			"tags": ["a", "b"]
+		}
+	}`)
+	if td.Domain.ChainID.Int64() != ChainIDMainnet {
+		t.Fatalf("unexpected chain id %v", td.Domain.ChainID)
+	}
+	if _, err := td.Hash(); err != nil {
+		t.Fatal(err)
+	}
+	invalid := []struct {
+		field string
+		value interface{}
+	}{
+		{"maker", "TNotAnAddress"},
+		{"delta", 128},
+		{"amount", -1},
+		{"amount", 1e300},
+		{"salt", "0x01"},
+		{"data", "0xzz"},
+		{"ok", "true"},
+		{"tags", []interface{}{"a"}},
+	}
+	for _, tt := range invalid {
+		orig := td.Message[tt.field]
+		td.Message[tt.field] = tt.value
+		if _, err := td.Hash(); !errors.Is(err, ErrInvalidTypedData) && !errors.Is(err, ErrInvalidAddress) {
+			t.Errorf("%s = %v: have %v, want %v", tt.field, tt.value, err, ErrInvalidTypedData)
+		}
+		td.Message[tt.field] = orig
+	}
+	td.Message["extra"] = 1
+	if _, err := td.Hash(); !errors.Is(err, ErrInvalidTypedData) {
+		t.Errorf("undeclared field: have %v, want %v", err, ErrInvalidTypedData)
+	}
+	delete(td.Message, "extra")
+	delete(td.Message, "ok")
+	if _, err := td.Hash(); !errors.Is(err, ErrInvalidTypedData) {
+		t.Errorf("missing field: have %v, want %v", err, ErrInvalidTypedData)
+	}
+}
@@ -1,8 +1,6 @@
 package keystore
 
 import (
-	"bytes"
-
 	"github.com/ethereum/go-ethereum/crypto"
 )
 
@@ -47,7 +45,7 @@ func (w *keystoreWallet) Accounts() []Account {
 // Contains implements Wallet, returning whether a particular account is
 // or is not wrapped by this wallet instance.
 func (w *keystoreWallet) Contains(account Account) bool {
-	return bytes.Equal(account.Address, w.account.Address) && (account.URL == (URL{}) || account.URL == w.account.URL)
+	return account.Address == w.account.Address && (account.URL == (URL{}) || account.URL == w.account.URL)
 }
 
 // Derive implements Wallet, but is a noop for plain wallets since there
@@ -98,3 +96,25 @@ func (w *keystoreWallet) SignTxWithPassphrase(acc Account, passphrase, rawData,
 	// Account seems valid, request the keystore to sign
 	return w.keystore.SignTxWithPassphrase(acc, passphrase, rawData, txHash)
 }
+
+// SignMessageV1 implements Wallet, signing message with the TIP-191 v1 scheme
+// using the unlocked account.
+func (w *keystoreWallet) SignMessageV1(acc Account, message []byte) ([]byte, error) {
+	return messageSignature(w.signHash(acc, MessageHashV1(message)))
+}
+
+// SignMessageV2 implements Wallet, signing message with the TIP-191 v2 scheme
+// using the unlocked account.
+func (w *keystoreWallet) SignMessageV2(acc Account, message []byte) ([]byte, error) {
+	return messageSignature(w.signHash(acc, MessageHashV2(message)))
+}
+
+// SignTypedData implements Wallet, signing the TIP-712 hash of data using the
+// unlocked account.
+func (w *keystoreWallet) SignTypedData(acc Account, data *TypedData) ([]byte, error) {
+	hash, err := data.Hash()
+	if err != nil {
+		return nil, err
+	}
+	return messageSignature(w.signHash(acc, hash))
+}
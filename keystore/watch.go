@@ -1,24 +1,24 @@
 package keystore
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/ethereum/go-ethereum/log"
-	"github.com/rjeczalik/notify"
 )
 
 type watcher struct {
 	ac       *accountCache
 	starting bool
 	running  bool
-	ev       chan notify.EventInfo
+	ev       chan struct{}
 	quit     chan struct{}
 }
 
 func newWatcher(ac *accountCache) *watcher {
 	return &watcher{
 		ac:   ac,
-		ev:   make(chan notify.EventInfo, 10),
+		ev:   make(chan struct{}, 1),
 		quit: make(chan struct{}),
 	}
 }
@@ -45,15 +45,21 @@ func (w *watcher) loop() {
 		w.starting = false
 		w.ac.mu.Unlock()
 	}()
-	logger := log.New("path", w.ac.keydir)
+	// Storages without change notifications are polled by maybeReload
+	notifier, ok := w.ac.storage.(StorageNotifier)
+	if !ok {
+		return
+	}
+	logger := log.New("storage", fmt.Sprintf("%T", w.ac.storage))
 
-	if err := notify.Watch(w.ac.keydir, w.ev, notify.All); err != nil {
-		logger.Trace("Failed to watch keystore folder", "err", err)
+	stop, err := notifier.Watch(w.ev)
+	if err != nil {
+		logger.Trace("Failed to watch keystore storage", "err", err)
 		return
 	}
-	defer notify.Stop(w.ev)
-	logger.Trace("Started watching keystore folder")
-	defer logger.Trace("Stopped watching keystore folder")
+	defer stop()
+	logger.Trace("Started watching keystore storage")
+	defer logger.Trace("Stopped watching keystore storage")
 
 	w.ac.mu.Lock()
 	w.running = true
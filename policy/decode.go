@@ -0,0 +1,216 @@
+package policy
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/bytejedi/tron-sdk-go/keystore"
+	"github.com/bytejedi/tron-sdk-go/proto/core"
+	"github.com/bytejedi/tron-sdk-go/proto/core/contract"
+)
+
+// TRC20 method selectors moving or granting tokens.
+var (
+	selectorTransfer     = []byte{0xa9, 0x05, 0x9c, 0xbb} // transfer(address,uint256)
+	selectorTransferFrom = []byte{0x23, 0xb8, 0x72, 0xdd} // transferFrom(address,address,uint256)
+	selectorApprove      = []byte{0x09, 0x5e, 0xa7, 0xb3} // approve(address,uint256)
+)
+
+// evaluable lists the contract types decode can check. Other types may move
+// value or hand over control of the account in ways the rules don't cover,
+// so policies can't allow them.
+var evaluable = map[core.Transaction_Contract_ContractType]bool{
+	core.Transaction_Contract_TransferContract:              true,
+	core.Transaction_Contract_TransferAssetContract:         true,
+	core.Transaction_Contract_TriggerSmartContract:          true,
+	core.Transaction_Contract_ParticipateAssetIssueContract: true,
+	core.Transaction_Contract_FreezeBalanceContract:         true,
+	core.Transaction_Contract_UnfreezeBalanceContract:       true,
+}
+
+// Transfer is a movement of value decoded from a transaction contract.
+type Transfer struct {
+	Owner  keystore.Address // Account signing the contract
+	To     keystore.Address // Recipient, or spender of a TRC20 approval
+	Asset  string           // Asset identifier as used in limits
+	Amount *big.Int         // Amount in the smallest unit of the asset
+}
+
+// decode checks a contract against the destination and method rules and
+// returns the transfers it makes, including the TRC20 allowances it grants.
+func (e *Engine) decode(c *core.Transaction_Contract) ([]Transfer, error) {
+	switch c.GetType() {
+	case core.Transaction_Contract_TransferContract:
+		tc := new(contract.TransferContract)
+		if err := c.GetParameter().UnmarshalTo(tc); err != nil {
+			return nil, err
+		}
+		t, err := newTransfer(tc.GetOwnerAddress(), tc.GetToAddress(), AssetTRX, big.NewInt(tc.GetAmount()))
+		if err != nil {
+			return nil, err
+		}
+		return []Transfer{t}, e.checkDestination(t.To)
+
+	case core.Transaction_Contract_TransferAssetContract:
+		tc := new(contract.TransferAssetContract)
+		if err := c.GetParameter().UnmarshalTo(tc); err != nil {
+			return nil, err
+		}
+		t, err := newTransfer(tc.GetOwnerAddress(), tc.GetToAddress(), AssetTRC10+string(tc.GetAssetName()), big.NewInt(tc.GetAmount()))
+		if err != nil {
+			return nil, err
+		}
+		return []Transfer{t}, e.checkDestination(t.To)
+
+	case core.Transaction_Contract_TriggerSmartContract:
+		tc := new(contract.TriggerSmartContract)
+		if err := c.GetParameter().UnmarshalTo(tc); err != nil {
+			return nil, err
+		}
+		return e.decodeTrigger(tc)
+
+	case core.Transaction_Contract_ParticipateAssetIssueContract:
+		// Buys TRC10 tokens from the issuer, paying in TRX
+		pc := new(contract.ParticipateAssetIssueContract)
+		if err := c.GetParameter().UnmarshalTo(pc); err != nil {
+			return nil, err
+		}
+		t, err := newTransfer(pc.GetOwnerAddress(), pc.GetToAddress(), AssetTRX, big.NewInt(pc.GetAmount()))
+		if err != nil {
+			return nil, err
+		}
+		return []Transfer{t}, e.checkDestination(t.To)
+
+	case core.Transaction_Contract_FreezeBalanceContract:
+		// The frozen TRX stays with the owner, but the resource may be
+		// delegated to another account
+		fc := new(contract.FreezeBalanceContract)
+		if err := c.GetParameter().UnmarshalTo(fc); err != nil {
+			return nil, err
+		}
+		return nil, e.checkReceiver(fc.GetReceiverAddress())
+
+	case core.Transaction_Contract_UnfreezeBalanceContract:
+		uc := new(contract.UnfreezeBalanceContract)
+		if err := c.GetParameter().UnmarshalTo(uc); err != nil {
+			return nil, err
+		}
+		return nil, e.checkReceiver(uc.GetReceiverAddress())
+	}
+	return nil, fmt.Errorf("%w: contract type %s can't be evaluated", ErrDenied, c.GetType())
+}
+
+// checkReceiver enforces the destination allowlist on the optional receiver
+// of a resource delegation.
+func (e *Engine) checkReceiver(receiver []byte) error {
+	if len(receiver) == 0 {
+		return nil
+	}
+	addr, err := keystore.BytesToAddress(receiver)
+	if err != nil {
+		return fmt.Errorf("%w: invalid receiver address: %v", ErrDenied, err)
+	}
+	return e.checkDestination(addr)
+}
+
+// decodeTrigger checks a smart contract call against the method rules and
+// decodes the TRX and TRC10 call value and the TRC20 transfers.
+func (e *Engine) decodeTrigger(tc *contract.TriggerSmartContract) ([]Transfer, error) {
+	addr, err := keystore.BytesToAddress(tc.GetContractAddress())
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid contract address: %v", ErrDenied, err)
+	}
+	data := tc.GetData()
+	if len(data) < 4 {
+		return nil, fmt.Errorf("%w: call to %s without method", ErrDenied, addr)
+	}
+	selector := string(data[:4])
+	if !e.methods[addr][selector] {
+		return nil, fmt.Errorf("%w: method %x of %s not allowed", ErrDenied, data[:4], addr)
+	}
+	var transfers []Transfer
+	if v := tc.GetCallValue(); v != 0 {
+		t, err := newTransfer(tc.GetOwnerAddress(), addr[:], AssetTRX, big.NewInt(v))
+		if err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, t)
+	}
+	if v := tc.GetCallTokenValue(); v != 0 {
+		t, err := newTransfer(tc.GetOwnerAddress(), addr[:], AssetTRC10+strconv.FormatInt(tc.GetTokenId(), 10), big.NewInt(v))
+		if err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, t)
+	}
+
+	// Decode the TRC20 token movements, the arguments are 32 byte words
+	var (
+		asset = AssetTRC20 + addr.String()
+		args  = data[4:]
+		word  = func(i int) []byte { return args[32*i : 32*(i+1)] }
+	)
+	switch {
+	case bytes.Equal(data[:4], selectorTransfer) && len(args) >= 64:
+		t, err := newTransfer(tc.GetOwnerAddress(), word(0)[12:], asset, new(big.Int).SetBytes(word(1)))
+		if err != nil {
+			return nil, err
+		}
+		if err := e.checkDestination(t.To); err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, t)
+
+	case bytes.Equal(data[:4], selectorTransferFrom) && len(args) >= 96:
+		t, err := newTransfer(tc.GetOwnerAddress(), word(1)[12:], asset, new(big.Int).SetBytes(word(2)))
+		if err != nil {
+			return nil, err
+		}
+		if err := e.checkDestination(t.To); err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, t)
+
+	case bytes.Equal(data[:4], selectorApprove) && len(args) >= 64:
+		// The spender can move the allowance with transferFrom at any
+		// time, so granting it counts as spending it
+		t, err := newTransfer(tc.GetOwnerAddress(), word(0)[12:], asset, new(big.Int).SetBytes(word(1)))
+		if err != nil {
+			return nil, err
+		}
+		if err := e.checkDestination(t.To); err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, t)
+
+	case bytes.Equal(data[:4], selectorTransfer), bytes.Equal(data[:4], selectorTransferFrom), bytes.Equal(data[:4], selectorApprove):
+		return nil, fmt.Errorf("%w: truncated call data for method %x", ErrDenied, data[:4])
+	}
+	return transfers, nil
+}
+
+// newTransfer builds a transfer from the raw owner and recipient addresses.
+func newTransfer(owner, to []byte, asset string, amount *big.Int) (Transfer, error) {
+	if amount.Sign() < 0 {
+		return Transfer{}, fmt.Errorf("%w: negative amount", ErrDenied)
+	}
+	o, err := keystore.BytesToAddress(owner)
+	if err != nil {
+		return Transfer{}, fmt.Errorf("%w: invalid owner address: %v", ErrDenied, err)
+	}
+	t, err := keystore.BytesToAddress(to)
+	if err != nil {
+		return Transfer{}, fmt.Errorf("%w: invalid recipient address: %v", ErrDenied, err)
+	}
+	return Transfer{Owner: o, To: t, Asset: asset, Amount: amount}, nil
+}
+
+// checkDestination enforces the destination allowlist.
+func (e *Engine) checkDestination(to keystore.Address) error {
+	if len(e.destinations) > 0 && !e.destinations[to] {
+		return fmt.Errorf("%w: destination %s not allowed", ErrDenied, to)
+	}
+	return nil
+}
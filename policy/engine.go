@@ -0,0 +1,362 @@
+package policy
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bytejedi/tron-sdk-go/abi"
+	"github.com/bytejedi/tron-sdk-go/keystore"
+	"github.com/bytejedi/tron-sdk-go/proto/core"
+)
+
+var (
+	// ErrDenied is returned for transactions violating the policy.
+	ErrDenied = errors.New("denied by signing policy")
+
+	// ErrLimitExceeded is returned when a transaction would exceed a daily
+	// spend limit.
+	ErrLimitExceeded = errors.New("daily spend limit exceeded")
+
+	// ErrNotApproved is returned when a transaction needing approval was
+	// rejected or there is no approver.
+	ErrNotApproved = errors.New("transaction not approved")
+)
+
+// Request is a transaction awaiting approval.
+type Request struct {
+	Tx        *core.Transaction
+	Transfers []Transfer // Decoded transfers of the transaction
+	Reason    string     // The limits that require the approval
+}
+
+// Approver decides about transactions spending more than the approval
+// threshold, e.g. by asking a human operator.
+type Approver interface {
+	Approve(req *Request) (bool, error)
+}
+
+// ApproverFunc adapts a function to the Approver interface.
+type ApproverFunc func(req *Request) (bool, error)
+
+// Approve implements Approver.
+func (f ApproverFunc) Approve(req *Request) (bool, error) {
+	return f(req)
+}
+
+// limit is the compiled form of Limit.
+type limit struct {
+	daily, approvalAbove *big.Int
+}
+
+// spendKey identifies the daily spending of an account in an asset.
+type spendKey struct {
+	owner keystore.Address
+	asset string
+}
+
+// Engine authorizes transactions according to a Policy. Spending is tracked
+// in memory, so the daily limits start over when the engine is created.
+type Engine struct {
+	contracts    map[core.Transaction_Contract_ContractType]bool
+	destinations map[keystore.Address]bool
+	limits       map[string]limit
+	methods      map[keystore.Address]map[string]bool
+	approver     Approver
+
+	now   func() time.Time
+	day   string
+	spent map[spendKey]*big.Int
+	mu    sync.Mutex
+}
+
+// NewEngine compiles policy p, failing if it allows contract types the engine
+// can't evaluate. Transactions above an approval threshold are
+// passed to approver, if it is nil they are rejected.
+func NewEngine(p *Policy, approver Approver) (*Engine, error) {
+	e := &Engine{
+		contracts:    make(map[core.Transaction_Contract_ContractType]bool),
+		destinations: make(map[keystore.Address]bool),
+		limits:       make(map[string]limit),
+		methods:      make(map[keystore.Address]map[string]bool),
+		approver:     approver,
+		now:          time.Now,
+		spent:        make(map[spendKey]*big.Int),
+	}
+	for _, name := range p.Contracts {
+		ty, ok := core.Transaction_Contract_ContractType_value[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown contract type %q", name)
+		}
+		if !evaluable[core.Transaction_Contract_ContractType(ty)] {
+			return nil, fmt.Errorf("contract type %s is not supported by policies", name)
+		}
+		e.contracts[core.Transaction_Contract_ContractType(ty)] = true
+	}
+	for _, s := range p.Destinations {
+		addr, err := keystore.ParseAddress(s)
+		if err != nil {
+			return nil, fmt.Errorf("destination %q: %v", s, err)
+		}
+		e.destinations[addr] = true
+	}
+	for _, l := range p.Limits {
+		asset, err := canonicalAsset(l.Asset)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := e.limits[asset]; ok {
+			return nil, fmt.Errorf("duplicate limit for %s", asset)
+		}
+		var cl limit
+		if l.Daily != nil {
+			cl.daily = l.Daily.Int()
+		}
+		if l.ApprovalAbove != nil {
+			cl.approvalAbove = l.ApprovalAbove.Int()
+		}
+		e.limits[asset] = cl
+	}
+	for _, rule := range p.Methods {
+		addr, err := keystore.ParseAddress(rule.Contract)
+		if err != nil {
+			return nil, fmt.Errorf("contract %q: %v", rule.Contract, err)
+		}
+		if e.methods[addr] == nil {
+			e.methods[addr] = make(map[string]bool)
+		}
+		for _, m := range rule.Allow {
+			selector, err := parseSelector(m)
+			if err != nil {
+				return nil, fmt.Errorf("contract %s: %v", addr, err)
+			}
+			e.methods[addr][string(selector)] = true
+		}
+	}
+	return e, nil
+}
+
+// canonicalAsset normalizes the address of TRC20 asset identifiers.
+func canonicalAsset(asset string) (string, error) {
+	switch {
+	case asset == AssetTRX:
+		return asset, nil
+	case strings.HasPrefix(asset, AssetTRC10) && len(asset) > len(AssetTRC10):
+		return asset, nil
+	case strings.HasPrefix(asset, AssetTRC20):
+		addr, err := keystore.ParseAddress(strings.TrimPrefix(asset, AssetTRC20))
+		if err != nil {
+			return "", fmt.Errorf("asset %q: %v", asset, err)
+		}
+		return AssetTRC20 + addr.String(), nil
+	}
+	return "", fmt.Errorf("unknown asset %q", asset)
+}
+
+// parseSelector returns the selector of a method signature or hex selector.
+func parseSelector(m string) ([]byte, error) {
+	if !strings.Contains(m, "(") {
+		selector, err := hex.DecodeString(strings.TrimPrefix(m, "0x"))
+		if err != nil || len(selector) != 4 {
+			return nil, fmt.Errorf("invalid method selector %q", m)
+		}
+		return selector, nil
+	}
+	method, err := abi.ParseMethod(m)
+	if err != nil {
+		return nil, err
+	}
+	return method.ID, nil
+}
+
+// Authorize checks tx against the policy and, if it is allowed, reserves its
+// spending against the daily limits. The reservation has to be committed once
+// the transaction is signed, or released if signing fails. Transactions
+// above an approval threshold are passed to the approver first. The returned
+// errors wrap ErrDenied, ErrLimitExceeded or ErrNotApproved.
+func (e *Engine) Authorize(tx *core.Transaction) (*Reservation, error) {
+	contracts := tx.GetRawData().GetContract()
+	if len(contracts) == 0 {
+		return nil, fmt.Errorf("%w: transaction without contract", ErrDenied)
+	}
+	var transfers []Transfer
+	for _, c := range contracts {
+		if !e.contracts[c.GetType()] {
+			return nil, fmt.Errorf("%w: contract type %s not allowed", ErrDenied, c.GetType())
+		}
+		ts, err := e.decode(c)
+		if err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, ts...)
+	}
+	keys, totals := sumTransfers(transfers)
+
+	e.mu.Lock()
+	reason, err := e.check(keys, totals)
+	if err != nil || reason == "" {
+		defer e.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		return e.reserve(keys, totals), nil
+	}
+	e.mu.Unlock()
+
+	// Don't hold the lock while waiting for a human
+	if e.approver == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotApproved, reason)
+	}
+	ok, err := e.approver.Approve(&Request{Tx: tx, Transfers: transfers, Reason: reason})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotApproved, err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotApproved, reason)
+	}
+	// Other transactions may have been authorized in the meantime
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, err := e.check(keys, totals); err != nil {
+		return nil, err
+	}
+	return e.reserve(keys, totals), nil
+}
+
+// SignTx authorizes tx and signs it with the unlocked account a of ks. The
+// spending only counts against the limits if the signing succeeds.
+func (e *Engine) SignTx(ks *keystore.KeyStore, a keystore.Account, tx *core.Transaction) (*core.Transaction, error) {
+	res, err := e.Authorize(tx)
+	if err != nil {
+		return nil, err
+	}
+	signed, err := ks.SignTx(a, tx)
+	if err != nil {
+		res.Release()
+		return nil, err
+	}
+	res.Commit()
+	return signed, nil
+}
+
+// Reservation is the spending of an authorized transaction, held against the
+// daily limits until it is committed or released.
+type Reservation struct {
+	e      *Engine
+	day    string // Day the spending was reserved on
+	keys   []spendKey
+	totals map[spendKey]*big.Int
+	done   bool
+}
+
+// reserve adds the totals to the daily spending. The lock must be held.
+func (e *Engine) reserve(keys []spendKey, totals map[spendKey]*big.Int) *Reservation {
+	for _, key := range keys {
+		e.spent[key] = new(big.Int).Add(e.spentOf(key), totals[key])
+	}
+	return &Reservation{e: e, day: e.day, keys: keys, totals: totals}
+}
+
+// Commit keeps the reserved spending, to be called once the transaction is
+// signed.
+func (r *Reservation) Commit() {
+	r.e.mu.Lock()
+	defer r.e.mu.Unlock()
+	r.done = true
+}
+
+// Release gives back the reserved spending, to be called if the transaction
+// could not be signed. It does nothing after Commit.
+func (r *Reservation) Release() {
+	r.e.mu.Lock()
+	defer r.e.mu.Unlock()
+
+	if r.done {
+		return
+	}
+	r.done = true
+	if r.e.day != r.day {
+		return // The spending started over anyway
+	}
+	for _, key := range r.keys {
+		r.e.spent[key] = new(big.Int).Sub(r.e.spentOf(key), r.totals[key])
+	}
+}
+
+// sumTransfers adds up the transfers per owner and asset, keeping the order
+// in which they first appear.
+func sumTransfers(transfers []Transfer) ([]spendKey, map[spendKey]*big.Int) {
+	var (
+		keys   []spendKey
+		totals = make(map[spendKey]*big.Int)
+	)
+	for _, t := range transfers {
+		key := spendKey{t.Owner, t.Asset}
+		if totals[key] == nil {
+			keys = append(keys, key)
+			totals[key] = new(big.Int)
+		}
+		totals[key].Add(totals[key], t.Amount)
+	}
+	return keys, totals
+}
+
+// check verifies the totals against the limits, returning why an approval
+// is needed, if so. The lock must be held.
+func (e *Engine) check(keys []spendKey, totals map[spendKey]*big.Int) (string, error) {
+	e.rollDay()
+
+	var reasons []string
+	for _, key := range keys {
+		amount := totals[key]
+		if amount.Sign() == 0 {
+			continue
+		}
+		l, ok := e.limits[key.asset]
+		if !ok {
+			return "", fmt.Errorf("%w: no limit for %s", ErrDenied, key.asset)
+		}
+		if l.daily != nil {
+			spent := new(big.Int).Add(e.spentOf(key), amount)
+			if spent.Cmp(l.daily) > 0 {
+				return "", fmt.Errorf("%w: %s of %s would spend %s, limit %s", ErrLimitExceeded, key.owner, key.asset, spent, l.daily)
+			}
+		}
+		if l.approvalAbove != nil && amount.Cmp(l.approvalAbove) > 0 {
+			reasons = append(reasons, fmt.Sprintf("%s %s above %s", amount, key.asset, l.approvalAbove))
+		}
+	}
+	return strings.Join(reasons, ", "), nil
+}
+
+func (e *Engine) spentOf(key spendKey) *big.Int {
+	if spent := e.spent[key]; spent != nil {
+		return spent
+	}
+	return new(big.Int)
+}
+
+// rollDay resets the spending when a new UTC day starts.
+func (e *Engine) rollDay() {
+	if day := e.now().UTC().Format("2006-01-02"); day != e.day {
+		e.day = day
+		e.spent = make(map[spendKey]*big.Int)
+	}
+}
+
+// Spent returns how much owner spent of asset today.
+func (e *Engine) Spent(owner keystore.Address, asset string) *big.Int {
+	asset, err := canonicalAsset(asset)
+	if err != nil {
+		return new(big.Int)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.rollDay()
+	return new(big.Int).Set(e.spentOf(spendKey{owner, asset}))
+}
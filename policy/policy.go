@@ -0,0 +1,154 @@
+// Package policy implements declarative guardrails for transaction signing.
+//
+// A Policy, written in YAML or JSON, lists the contract types that may be
+// signed, the allowed destinations, per-asset daily spend limits, the smart
+// contract methods that may be called and the amounts above which a human has
+// to approve the transaction. An Engine compiled from the policy decodes the
+// contracts of a transaction and authorizes it before it is signed:
+//
+//	contracts: [TransferContract, TriggerSmartContract]
+//	destinations: [TJRabPrwbZy45sbavfcjinPJC18kjpRTv8]
+//	limits:
+//	  - asset: TRX
+//	    daily: 1000000000      # sun
+//	    approvalAbove: 100000000
+//	  - asset: TRC20:TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t
+//	    daily: "5000000000"
+//	methods:
+//	  - contract: TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t
+//	    allow: ["transfer(address,uint256)"]
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Asset identifiers used in limits. TRC10 and TRC20 assets are suffixed with
+// the token id and the contract address, e.g. "TRC10:1002000".
+const (
+	AssetTRX   = "TRX"
+	AssetTRC10 = "TRC10:"
+	AssetTRC20 = "TRC20:"
+)
+
+// Policy is the declarative form of the signing rules. Everything not
+// explicitly allowed is denied.
+type Policy struct {
+	// Contracts lists the contract types that may be signed by name, as in
+	// core.Transaction_Contract_ContractType, e.g. TransferContract. Only
+	// TransferContract, TransferAssetContract, TriggerSmartContract,
+	// ParticipateAssetIssueContract, FreezeBalanceContract and
+	// UnfreezeBalanceContract are supported.
+	Contracts []string `json:"contracts" yaml:"contracts"`
+
+	// Destinations, if not empty, restricts the recipients of TRX, TRC10 and
+	// TRC20 transfers, the spenders of TRC20 approvals and the receivers of
+	// delegated resources.
+	Destinations []string `json:"destinations,omitempty" yaml:"destinations,omitempty"`
+
+	// Limits caps the spending of every owner account per asset. Assets
+	// without a limit can't be spent at all. TRC20 approvals count as
+	// spending the approved amount.
+	Limits []Limit `json:"limits,omitempty" yaml:"limits,omitempty"`
+
+	// Methods lists the contracts TriggerSmartContract may call together
+	// with their allowed methods.
+	Methods []MethodRule `json:"methods,omitempty" yaml:"methods,omitempty"`
+}
+
+// Limit caps the spending of an asset.
+type Limit struct {
+	// Asset is AssetTRX, AssetTRC10 followed by the token id or AssetTRC20
+	// followed by the contract address.
+	Asset string `json:"asset" yaml:"asset"`
+
+	// Daily is the most an account may spend per UTC day in the smallest
+	// unit of the asset. Unset means unlimited.
+	Daily *Amount `json:"daily,omitempty" yaml:"daily,omitempty"`
+
+	// ApprovalAbove requires approval of transactions spending more than
+	// this amount. Unset means no approval is needed.
+	ApprovalAbove *Amount `json:"approvalAbove,omitempty" yaml:"approvalAbove,omitempty"`
+}
+
+// MethodRule allows calling methods of a contract.
+type MethodRule struct {
+	// Contract is the address of the contract.
+	Contract string `json:"contract" yaml:"contract"`
+
+	// Allow lists the callable methods, either as signatures such as
+	// transfer(address,uint256) or as hex encoded 4 byte selectors.
+	Allow []string `json:"allow" yaml:"allow"`
+}
+
+// Amount is a token amount in the smallest unit of the asset. It can be
+// written as a number or, for amounts that don't fit, as a decimal string.
+type Amount big.Int
+
+// NewAmount returns the amount x.
+func NewAmount(x int64) *Amount {
+	return (*Amount)(big.NewInt(x))
+}
+
+// Int returns the amount as a big integer.
+func (a *Amount) Int() *big.Int {
+	return (*big.Int)(a)
+}
+
+// String implements fmt.Stringer.
+func (a *Amount) String() string {
+	return a.Int().String()
+}
+
+// MarshalJSON implements json.Marshaler, amounts are written as strings.
+func (a *Amount) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (a *Amount) UnmarshalJSON(input []byte) error {
+	return a.set(strings.Trim(string(input), `"`))
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (a *Amount) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	return a.set(s)
+}
+
+func (a *Amount) set(s string) error {
+	x, ok := new(big.Int).SetString(strings.TrimSpace(s), 10)
+	if !ok || x.Sign() < 0 {
+		return fmt.Errorf("invalid amount %q", s)
+	}
+	*a = Amount(*x)
+	return nil
+}
+
+// Parse parses a YAML or JSON policy. Unknown fields are rejected, so typos
+// don't silently weaken the policy.
+func Parse(data []byte) (*Policy, error) {
+	p := new(Policy)
+	if err := yaml.UnmarshalStrict(data, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// LoadFile reads and parses the YAML or JSON policy in file.
+func LoadFile(file string) (*Policy, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
@@ -0,0 +1,303 @@
+package policy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/bytejedi/tron-sdk-go/keystore"
+	"github.com/bytejedi/tron-sdk-go/proto/core"
+	"github.com/bytejedi/tron-sdk-go/proto/core/contract"
+	"github.com/bytejedi/tron-sdk-go/transaction"
+	"github.com/ethereum/go-ethereum/common/math"
+	"google.golang.org/protobuf/proto"
+)
+
+func testAddress(b byte) keystore.Address {
+	addr, _ := keystore.BytesToAddress(bytes.Repeat([]byte{b}, keystore.EVMAddressLength))
+	return addr
+}
+
+var (
+	owner    = testAddress(1)
+	friend   = testAddress(2)
+	stranger = testAddress(3)
+	token    = testAddress(4)
+)
+
+var testPolicy = fmt.Sprintf(`
+contracts: [TransferContract, TransferAssetContract, TriggerSmartContract]
+destinations: [%s]
+limits:
+  - asset: TRX
+    daily: 1000
+    approvalAbove: 500
+  - asset: TRC20:%s
+    daily: "100000000000000000000"
+methods:
+  - contract: %s
+    allow: ["transfer(address,uint256)", "0x095ea7b3"]
+`, friend, token.Hex(), token)
+
+func newTestEngine(t *testing.T, approver Approver) *Engine {
+	p, err := Parse([]byte(testPolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := NewEngine(p, approver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func newTx(t *testing.T, ty core.Transaction_Contract_ContractType, c proto.Message) *core.Transaction {
+	tx, err := transaction.New(ty, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+func trxTransfer(t *testing.T, to keystore.Address, amount int64) *core.Transaction {
+	return newTx(t, core.Transaction_Contract_TransferContract, &contract.TransferContract{
+		OwnerAddress: owner.Bytes(),
+		ToAddress:    to.Bytes(),
+		Amount:       amount,
+	})
+}
+
+func trc20Call(t *testing.T, selector []byte, to keystore.Address, amount *big.Int) *core.Transaction {
+	data := append([]byte{}, selector...)
+	data = append(data, math.PaddedBigBytes(new(big.Int).SetBytes(to.EVM().Bytes()), 32)...)
+	data = append(data, math.PaddedBigBytes(amount, 32)...)
+	return newTx(t, core.Transaction_Contract_TriggerSmartContract, &contract.TriggerSmartContract{
+		OwnerAddress:    owner.Bytes(),
+		ContractAddress: token.Bytes(),
+		Data:            data,
+	})
+}
+
+func TestParse(t *testing.T) {
+	p, err := Parse([]byte(testPolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Limits) != 2 || p.Limits[0].Daily.Int().Int64() != 1000 || p.Limits[1].Daily.String() != "100000000000000000000" {
+		t.Errorf("unexpected limits %+v", p.Limits)
+	}
+	// JSON is accepted as well
+	p, err = Parse([]byte(`{"contracts": ["TransferContract"], "limits": [{"asset": "TRX", "daily": 5}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Contracts) != 1 || p.Limits[0].Daily.Int().Int64() != 5 {
+		t.Errorf("unexpected policy %+v", p)
+	}
+	if _, err := Parse([]byte("contracts: [TransferContract]\nlimit: []\n")); err == nil {
+		t.Errorf("unknown field accepted")
+	}
+	if _, err := NewEngine(&Policy{Contracts: []string{"NoSuchContract"}}, nil); err == nil {
+		t.Errorf("unknown contract type accepted")
+	}
+	for _, ty := range []string{"CreateSmartContract", "AccountPermissionUpdateContract"} {
+		if _, err := NewEngine(&Policy{Contracts: []string{ty}}, nil); err == nil {
+			t.Errorf("contract type %s accepted", ty)
+		}
+	}
+}
+
+func TestEngineLimits(t *testing.T) {
+	e := newTestEngine(t, nil)
+	now := time.Date(2020, 8, 1, 12, 0, 0, 0, time.UTC)
+	e.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if _, err := e.Authorize(trxTransfer(t, friend, 400)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := e.Authorize(trxTransfer(t, friend, 400)); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("over daily limit: have %v, want %v", err, ErrLimitExceeded)
+	}
+	if spent := e.Spent(owner, AssetTRX); spent.Int64() != 800 {
+		t.Errorf("spent %v, want 800", spent)
+	}
+	if _, err := e.Authorize(trxTransfer(t, stranger, 1)); !errors.Is(err, ErrDenied) {
+		t.Errorf("unknown destination: have %v, want %v", err, ErrDenied)
+	}
+
+	// The limit starts over the next day
+	now = now.Add(24 * time.Hour)
+	if _, err := e.Authorize(trxTransfer(t, friend, 400)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Assets and contract types without rules are denied
+	trc10 := newTx(t, core.Transaction_Contract_TransferAssetContract, &contract.TransferAssetContract{
+		AssetName:    []byte("1002000"),
+		OwnerAddress: owner.Bytes(),
+		ToAddress:    friend.Bytes(),
+		Amount:       1,
+	})
+	if _, err := e.Authorize(trc10); !errors.Is(err, ErrDenied) {
+		t.Errorf("asset without limit: have %v, want %v", err, ErrDenied)
+	}
+	freeze := newTx(t, core.Transaction_Contract_FreezeBalanceContract, &contract.FreezeBalanceContract{
+		OwnerAddress:  owner.Bytes(),
+		FrozenBalance: 1,
+	})
+	if _, err := e.Authorize(freeze); !errors.Is(err, ErrDenied) {
+		t.Errorf("contract type not allowed: have %v, want %v", err, ErrDenied)
+	}
+}
+
+func TestEngineMethods(t *testing.T) {
+	e := newTestEngine(t, nil)
+	transfer := []byte{0xa9, 0x05, 0x9c, 0xbb}
+	approve := []byte{0x09, 0x5e, 0xa7, 0xb3}
+	transferFrom := []byte{0x23, 0xb8, 0x72, 0xdd}
+
+	amount, _ := new(big.Int).SetString("60000000000000000000", 10)
+	if _, err := e.Authorize(trc20Call(t, transfer, friend, amount)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.Authorize(trc20Call(t, transfer, friend, amount)); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("over TRC20 limit: have %v, want %v", err, ErrLimitExceeded)
+	}
+	if _, err := e.Authorize(trc20Call(t, transfer, stranger, big.NewInt(1))); !errors.Is(err, ErrDenied) {
+		t.Errorf("TRC20 to unknown destination: have %v, want %v", err, ErrDenied)
+	}
+	if _, err := e.Authorize(trc20Call(t, approve, stranger, big.NewInt(1))); !errors.Is(err, ErrDenied) {
+		t.Errorf("approving unknown spender: have %v, want %v", err, ErrDenied)
+	}
+	if _, err := e.Authorize(trc20Call(t, approve, friend, big.NewInt(1))); err != nil {
+		t.Errorf("approving allowed spender: %v", err)
+	}
+	if _, err := e.Authorize(trc20Call(t, transferFrom, friend, big.NewInt(1))); !errors.Is(err, ErrDenied) {
+		t.Errorf("method not allowed: have %v, want %v", err, ErrDenied)
+	}
+
+	// Approvals count against the limits, unlimited ones can't pass
+	spent := e.Spent(owner, "TRC20:"+token.String())
+	if want := new(big.Int).Add(amount, big.NewInt(1)); spent.Cmp(want) != 0 {
+		t.Errorf("spent %v, want %v", spent, want)
+	}
+	unlimited := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+	if _, err := newTestEngine(t, nil).Authorize(trc20Call(t, approve, friend, unlimited)); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("unlimited approval: have %v, want %v", err, ErrLimitExceeded)
+	}
+}
+
+func TestEngineApproval(t *testing.T) {
+	if _, err := newTestEngine(t, nil).Authorize(trxTransfer(t, friend, 600)); !errors.Is(err, ErrNotApproved) {
+		t.Errorf("no approver: have %v, want %v", err, ErrNotApproved)
+	}
+	var requests []*Request
+	approve := false
+	e := newTestEngine(t, ApproverFunc(func(req *Request) (bool, error) {
+		requests = append(requests, req)
+		return approve, nil
+	}))
+	if _, err := e.Authorize(trxTransfer(t, friend, 600)); !errors.Is(err, ErrNotApproved) {
+		t.Errorf("rejected approval: have %v, want %v", err, ErrNotApproved)
+	}
+	if spent := e.Spent(owner, AssetTRX); spent.Sign() != 0 {
+		t.Errorf("rejected transaction counted, spent %v", spent)
+	}
+	approve = true
+	if _, err := e.Authorize(trxTransfer(t, friend, 600)); err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 2 || len(requests[1].Transfers) != 1 || requests[1].Transfers[0].To != friend {
+		t.Fatalf("unexpected approval requests %+v", requests)
+	}
+	if spent := e.Spent(owner, AssetTRX); spent.Int64() != 600 {
+		t.Errorf("spent %v, want 600", spent)
+	}
+	// Amounts below the threshold need no approval
+	if _, err := e.Authorize(trxTransfer(t, friend, 300)); err != nil || len(requests) != 2 {
+		t.Errorf("small transfer: %v, %d approval requests", err, len(requests))
+	}
+}
+
+func TestEngineReservation(t *testing.T) {
+	e := newTestEngine(t, nil)
+	res, err := e.Authorize(trxTransfer(t, friend, 400))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Spending is held while the transaction is being signed
+	if spent := e.Spent(owner, AssetTRX); spent.Int64() != 400 {
+		t.Errorf("spent %v while reserved, want 400", spent)
+	}
+	res.Release()
+	res.Release()
+	if spent := e.Spent(owner, AssetTRX); spent.Sign() != 0 {
+		t.Errorf("spent %v after release, want 0", spent)
+	}
+	for i := 0; i < 2; i++ {
+		res, err := e.Authorize(trxTransfer(t, friend, 500))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Commit()
+		res.Release()
+	}
+	if spent := e.Spent(owner, AssetTRX); spent.Int64() != 1000 {
+		t.Errorf("spent %v after commit, want 1000", spent)
+	}
+}
+
+func TestEngineContractTypes(t *testing.T) {
+	p, err := Parse([]byte(fmt.Sprintf(`
+contracts: [ParticipateAssetIssueContract, FreezeBalanceContract]
+destinations: [%s]
+limits: [{asset: TRX, daily: 1000}]
+`, friend)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := NewEngine(p, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	participate := func(to keystore.Address, amount int64) *core.Transaction {
+		return newTx(t, core.Transaction_Contract_ParticipateAssetIssueContract, &contract.ParticipateAssetIssueContract{
+			OwnerAddress: owner.Bytes(),
+			ToAddress:    to.Bytes(),
+			AssetName:    []byte("1002000"),
+			Amount:       amount,
+		})
+	}
+	if _, err := e.Authorize(participate(friend, 600)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.Authorize(participate(friend, 600)); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("buying over limit: have %v, want %v", err, ErrLimitExceeded)
+	}
+	if _, err := e.Authorize(participate(stranger, 1)); !errors.Is(err, ErrDenied) {
+		t.Errorf("buying from unknown issuer: have %v, want %v", err, ErrDenied)
+	}
+
+	freeze := func(receiver []byte) *core.Transaction {
+		return newTx(t, core.Transaction_Contract_FreezeBalanceContract, &contract.FreezeBalanceContract{
+			OwnerAddress:    owner.Bytes(),
+			FrozenBalance:   1000000,
+			FrozenDuration:  3,
+			ReceiverAddress: receiver,
+		})
+	}
+	if _, err := e.Authorize(freeze(nil)); err != nil {
+		t.Errorf("freezing for the owner: %v", err)
+	}
+	if _, err := e.Authorize(freeze(friend.Bytes())); err != nil {
+		t.Errorf("delegating to allowed receiver: %v", err)
+	}
+	if _, err := e.Authorize(freeze(stranger.Bytes())); !errors.Is(err, ErrDenied) {
+		t.Errorf("delegating to unknown receiver: have %v, want %v", err, ErrDenied)
+	}
+}
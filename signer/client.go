@@ -0,0 +1,158 @@
+package signer
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/bytejedi/tron-sdk-go/keystore"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"go.uber.org/zap"
+)
+
+var _ keystore.Wallet = (*Client)(nil)
+
+// Client is a keystore.Wallet whose accounts live in a remote signer. No key
+// material is ever held by the client.
+type Client struct {
+	endpoint string
+	client   *rpc.Client
+}
+
+// Dial connects to the signer at a unix:///path or https://host:port
+// endpoint. The TLS configuration must hold the client certificate, as done
+// by NewClientTLSConfig. For Unix sockets the server certificate is checked
+// against tlsConfig.ServerName.
+func Dial(endpoint string, tlsConfig *tls.Config) (*Client, error) {
+	if tlsConfig == nil || len(tlsConfig.Certificates) == 0 {
+		return nil, ErrNoClientAuth
+	}
+	network, addr, err := parseEndpoint(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	transport := &http.Transport{TLSClientConfig: tlsConfig}
+	url := endpoint
+	if network == "unix" {
+		var dialer net.Dialer
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, addr)
+		}
+		url = httpsPrefix + "signer"
+	}
+	client, err := rpc.DialHTTPWithClient(url, &http.Client{Transport: transport})
+	if err != nil {
+		return nil, err
+	}
+	return &Client{endpoint: endpoint, client: client}, nil
+}
+
+// URL implements keystore.Wallet, returning the endpoint of the signer.
+func (c *Client) URL() keystore.URL {
+	return keystore.URL{Scheme: WalletScheme, Path: c.endpoint}
+}
+
+// Status implements keystore.Wallet, reporting whether the signer can be
+// reached and its protocol version.
+func (c *Client) Status() (string, error) {
+	var version string
+	if err := c.client.Call(&version, namespace+"_version"); err != nil {
+		return "Unreachable", err
+	}
+	return fmt.Sprintf("ok [version=%s]", version), nil
+}
+
+// Open implements keystore.Wallet, but is a noop since the accounts are
+// opened or unlocked on the signer side.
+func (c *Client) Open(passphrase string) error { return nil }
+
+// Close implements keystore.Wallet, but is a noop since the connection is
+// kept for the lifetime of the client.
+func (c *Client) Close() error { return nil }
+
+// Accounts implements keystore.Wallet, fetching the accounts of the signer.
+// An unreachable signer yields no accounts.
+func (c *Client) Accounts() []keystore.Account {
+	var addrs []keystore.Address
+	if err := c.client.Call(&addrs, namespace+"_accounts"); err != nil {
+		zap.L().Warn("Failed to list signer accounts", zap.String("endpoint", c.endpoint), zap.Error(err))
+		return nil
+	}
+	url := c.URL()
+	accounts := make([]keystore.Account, len(addrs))
+	for i, addr := range addrs {
+		accounts[i] = keystore.Account{Address: addr, URL: url}
+	}
+	return accounts
+}
+
+// Contains implements keystore.Wallet, returning whether the signer holds
+// the account.
+func (c *Client) Contains(account keystore.Account) bool {
+	if account.URL != (keystore.URL{}) && account.URL != c.URL() {
+		return false
+	}
+	for _, acc := range c.Accounts() {
+		if acc.Address == account.Address {
+			return true
+		}
+	}
+	return false
+}
+
+// Derive implements keystore.Wallet, but is not supported by remote signers.
+func (c *Client) Derive(path keystore.DerivationPath, pin bool) (keystore.Account, error) {
+	return keystore.Account{}, keystore.ErrNotSupported
+}
+
+// SignData requests a signature of keccak256(data) from the signer. The
+// account must be unlocked there.
+func (c *Client) SignData(acc keystore.Account, mimeType string, data []byte) ([]byte, error) {
+	var sig hexutil.Bytes
+	err := c.client.Call(&sig, namespace+"_signData", acc.Address, mimeType, hexutil.Bytes(data))
+	return sig, remoteError(err)
+}
+
+// SignText requests a signature of the TextHash of text from the signer. The
+// account must be unlocked there.
+func (c *Client) SignText(acc keystore.Account, text []byte) ([]byte, error) {
+	var sig hexutil.Bytes
+	err := c.client.Call(&sig, namespace+"_signText", acc.Address, hexutil.Bytes(text))
+	return sig, remoteError(err)
+}
+
+// SignTxWithPassphrase implements keystore.Wallet, requesting a signature of
+// the hex encoded raw data or, if rawData is empty, of the hex encoded
+// transaction hash.
+func (c *Client) SignTxWithPassphrase(acc keystore.Account, passphrase, rawData, txHash string) ([]byte, error) {
+	var sig hexutil.Bytes
+	err := c.client.Call(&sig, namespace+"_signTxWithPassphrase", acc.Address, passphrase, rawData, txHash)
+	return sig, remoteError(err)
+}
+
+// SignMessageV1 implements keystore.Wallet, requesting a TIP-191 v1 signature
+// of message from the signer. The account must be unlocked there.
+func (c *Client) SignMessageV1(acc keystore.Account, message []byte) ([]byte, error) {
+	var sig hexutil.Bytes
+	err := c.client.Call(&sig, namespace+"_signMessageV1", acc.Address, hexutil.Bytes(message))
+	return sig, remoteError(err)
+}
+
+// SignMessageV2 implements keystore.Wallet, requesting a TIP-191 v2 signature
+// of message from the signer. The account must be unlocked there.
+func (c *Client) SignMessageV2(acc keystore.Account, message []byte) ([]byte, error) {
+	var sig hexutil.Bytes
+	err := c.client.Call(&sig, namespace+"_signMessageV2", acc.Address, hexutil.Bytes(message))
+	return sig, remoteError(err)
+}
+
+// SignTypedData implements keystore.Wallet, requesting a TIP-712 signature of
+// data from the signer. The account must be unlocked there.
+func (c *Client) SignTypedData(acc keystore.Account, data *keystore.TypedData) ([]byte, error) {
+	var sig hexutil.Bytes
+	err := c.client.Call(&sig, namespace+"_signTypedData", acc.Address, data)
+	return sig, remoteError(err)
+}
@@ -0,0 +1,260 @@
+package signer
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/bytejedi/tron-sdk-go/keystore"
+	"github.com/bytejedi/tron-sdk-go/policy"
+	"github.com/bytejedi/tron-sdk-go/proto/core"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// errHashOnly is returned when a policy is in force and a transaction is
+// given by its hash only, which can't be checked.
+var errHashOnly = errors.New("signing policy requires the raw transaction data")
+
+// errPolicyData is returned when a policy is in force and data, text, a
+// message or typed data is to be signed, which the policy has no rules for.
+var errPolicyData = errors.New("signing policy only allows transactions")
+
+// dataSigner is implemented by the keystore wallets able to sign arbitrary
+// data and text.
+type dataSigner interface {
+	keystore.Wallet
+	SignData(acc keystore.Account, mimeType string, data []byte) ([]byte, error)
+	SignText(acc keystore.Account, text []byte) ([]byte, error)
+}
+
+// Server serves the signer protocol for the accounts of a KeyStore. Signing
+// without a passphrase requires the account to be unlocked, or its HD wallet
+// to be open, in the keystore.
+type Server struct {
+	rpc  *rpc.Server
+	http *http.Server
+}
+
+// NewServer creates a signer server for the wallets of ks.
+func NewServer(ks *keystore.KeyStore) *Server {
+	return NewServerWithPolicy(ks, nil)
+}
+
+// NewServerWithPolicy creates a signer server for the wallets of ks that
+// authorizes every transaction with engine before signing it.
+func NewServerWithPolicy(ks *keystore.KeyStore, engine *policy.Engine) *Server {
+	srv := rpc.NewServer()
+	if err := srv.RegisterName(namespace, &signerAPI{ks, engine}); err != nil {
+		panic(err) // Only fails for invalid API types
+	}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		srv.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), callerKey{}, clientName(r))))
+	})
+	return &Server{rpc: srv, http: &http.Server{Handler: handler}}
+}
+
+// callerKey is the context key of the client name of a request.
+type callerKey struct{}
+
+// clientName identifies the client of a request in the audit records of the
+// keystore by the common name of its certificate.
+func clientName(r *http.Request) string {
+	if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+		if cn := r.TLS.PeerCertificates[0].Subject.CommonName; cn != "" {
+			return cn
+		}
+	}
+	return r.RemoteAddr
+}
+
+// Listen opens the listener of a unix:///path or https://host:port endpoint.
+// Unix sockets are created accessible to the current user only.
+func Listen(endpoint string) (net.Listener, error) {
+	network, addr, err := parseEndpoint(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	l, err := net.Listen(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	if network == "unix" {
+		if err := os.Chmod(addr, 0600); err != nil {
+			l.Close()
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// Serve accepts connections on l until the server is closed. The TLS
+// configuration must require and verify client certificates, as done by
+// NewServerTLSConfig.
+func (s *Server) Serve(l net.Listener, tlsConfig *tls.Config) error {
+	if tlsConfig == nil || tlsConfig.ClientAuth != tls.RequireAndVerifyClientCert || tlsConfig.ClientCAs == nil {
+		return ErrNoClientAuth
+	}
+	err := s.http.Serve(tls.NewListener(l, tlsConfig))
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+// ListenAndServe listens on endpoint and serves requests until the server is
+// closed.
+func (s *Server) ListenAndServe(endpoint string, tlsConfig *tls.Config) error {
+	l, err := Listen(endpoint)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l, tlsConfig)
+}
+
+// Close stops serving and drops all connections.
+func (s *Server) Close() error {
+	err := s.http.Close()
+	s.rpc.Stop()
+	return err
+}
+
+// signerAPI is the signer_ namespace of the JSON-RPC server.
+type signerAPI struct {
+	ks     *keystore.KeyStore
+	policy *policy.Engine // Optional signing policy
+}
+
+// Version returns the protocol version.
+func (api *signerAPI) Version() string {
+	return Version
+}
+
+// Accounts returns the addresses of all accounts the server can sign for.
+func (api *signerAPI) Accounts() []keystore.Address {
+	addrs := make([]keystore.Address, 0)
+	for _, w := range api.ks.Wallets() {
+		for _, acc := range w.Accounts() {
+			addrs = append(addrs, acc.Address)
+		}
+	}
+	return addrs
+}
+
+// SignData signs keccak256(data) with the unlocked account addr. It is
+// refused when a policy is in force, as are the other data, message and
+// typed data signing methods.
+func (api *signerAPI) SignData(ctx context.Context, addr keystore.Address, mimeType string, data hexutil.Bytes) (hexutil.Bytes, error) {
+	if api.policy != nil {
+		return nil, errPolicyData
+	}
+	w, acc, err := api.find(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	return w.SignData(acc, mimeType, data)
+}
+
+// SignText signs the TextHash of text with the unlocked account addr.
+func (api *signerAPI) SignText(ctx context.Context, addr keystore.Address, text hexutil.Bytes) (hexutil.Bytes, error) {
+	if api.policy != nil {
+		return nil, errPolicyData
+	}
+	w, acc, err := api.find(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	return w.SignText(acc, text)
+}
+
+// SignMessageV1 signs message with the TIP-191 v1 scheme with the unlocked
+// account addr.
+func (api *signerAPI) SignMessageV1(ctx context.Context, addr keystore.Address, message hexutil.Bytes) (hexutil.Bytes, error) {
+	if api.policy != nil {
+		return nil, errPolicyData
+	}
+	w, acc, err := api.find(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	return w.SignMessageV1(acc, message)
+}
+
+// SignMessageV2 signs message with the TIP-191 v2 scheme with the unlocked
+// account addr.
+func (api *signerAPI) SignMessageV2(ctx context.Context, addr keystore.Address, message hexutil.Bytes) (hexutil.Bytes, error) {
+	if api.policy != nil {
+		return nil, errPolicyData
+	}
+	w, acc, err := api.find(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	return w.SignMessageV2(acc, message)
+}
+
+// SignTypedData signs the TIP-712 hash of data with the unlocked account addr.
+func (api *signerAPI) SignTypedData(ctx context.Context, addr keystore.Address, data keystore.TypedData) (hexutil.Bytes, error) {
+	// Typed data such as permits can grant allowances the policy can't check
+	if api.policy != nil {
+		return nil, errPolicyData
+	}
+	w, acc, err := api.find(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	return w.SignTypedData(acc, &data)
+}
+
+// SignTxWithPassphrase signs the hex encoded raw data or, if rawData is
+// empty, the hex encoded transaction hash with account addr. With a policy
+// only raw data is accepted and the transaction must pass the policy, its
+// spending only counting once it is signed.
+func (api *signerAPI) SignTxWithPassphrase(ctx context.Context, addr keystore.Address, passphrase, rawData, txHash string) (hexutil.Bytes, error) {
+	w, acc, err := api.find(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	if api.policy == nil {
+		return w.SignTxWithPassphrase(acc, passphrase, rawData, txHash)
+	}
+	if rawData == "" {
+		return nil, errHashOnly
+	}
+	raw := new(core.TransactionRaw)
+	if err := proto.Unmarshal(common.FromHex(rawData), raw); err != nil {
+		return nil, err
+	}
+	res, err := api.policy.Authorize(&core.Transaction{RawData: raw})
+	if err != nil {
+		return nil, err
+	}
+	sig, err := w.SignTxWithPassphrase(acc, passphrase, rawData, txHash)
+	if err != nil {
+		res.Release()
+		return nil, err
+	}
+	res.Commit()
+	return sig, nil
+}
+
+// find returns the wallet holding the account addr, recording the client of
+// the request as caller in the audit records.
+func (api *signerAPI) find(ctx context.Context, addr keystore.Address) (dataSigner, keystore.Account, error) {
+	ks := api.ks
+	if caller, ok := ctx.Value(callerKey{}).(string); ok {
+		ks = ks.WithCaller(caller)
+	}
+	acc := keystore.Account{Address: addr}
+	for _, w := range ks.Wallets() {
+		if ds, ok := w.(dataSigner); ok && w.Contains(acc) {
+			return ds, acc, nil
+		}
+	}
+	return nil, acc, keystore.ErrUnknownAccount
+}
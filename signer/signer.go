@@ -0,0 +1,134 @@
+// Package signer implements a small JSON-RPC protocol for requesting
+// signatures from a separate signer process, so services never have to load
+// key material themselves.
+//
+// The Server exposes the accounts of a keystore.KeyStore, the Client
+// implements keystore.Wallet on top of the remote accounts. Requests are sent
+// over HTTPS, either on a TCP port or on a Unix socket, and both sides
+// authenticate with TLS certificates.
+package signer
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/bytejedi/tron-sdk-go/keystore"
+)
+
+const (
+	// WalletScheme is the URL scheme of wallets backed by a remote signer.
+	WalletScheme = "signer"
+
+	// Version is the version of the signer protocol.
+	Version = "1.0.0"
+
+	// namespace prefixes the JSON-RPC methods of the protocol.
+	namespace = "signer"
+
+	unixPrefix  = "unix://"
+	httpsPrefix = "https://"
+)
+
+var (
+	// ErrNoClientAuth is returned when a server or client is set up without
+	// the TLS configuration needed for mutual authentication.
+	ErrNoClientAuth = errors.New("signer requires mutual TLS authentication")
+
+	// ErrInvalidEndpoint is returned for endpoints that are neither
+	// unix:///path nor https://host:port.
+	ErrInvalidEndpoint = errors.New("signer endpoint must be unix:///path or https://host:port")
+)
+
+// NewServerTLSConfig loads the PEM encoded certificate and key of the server
+// and the CA certificates client certificates must be issued by.
+func NewServerTLSConfig(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := loadCertPool(clientCAFile)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// NewClientTLSConfig loads the PEM encoded certificate and key of the client
+// and the CA certificates the server certificate must be issued by. The
+// server certificate must be valid for serverName.
+func NewClientTLSConfig(certFile, keyFile, serverCAFile, serverName string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := loadCertPool(serverCAFile)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      pool,
+		ServerName:   serverName,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// loadCertPool reads the PEM encoded certificates in file.
+func loadCertPool(file string) (*x509.CertPool, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in %s", file)
+	}
+	return pool, nil
+}
+
+// parseEndpoint splits endpoint into the network and address to listen on
+// or dial.
+func parseEndpoint(endpoint string) (network, addr string, err error) {
+	switch {
+	case strings.HasPrefix(endpoint, unixPrefix) && len(endpoint) > len(unixPrefix):
+		return "unix", strings.TrimPrefix(endpoint, unixPrefix), nil
+	case strings.HasPrefix(endpoint, httpsPrefix) && len(endpoint) > len(httpsPrefix):
+		return "tcp", strings.TrimPrefix(endpoint, httpsPrefix), nil
+	}
+	return "", "", ErrInvalidEndpoint
+}
+
+// knownErrors are the keystore errors restored by the client from the error
+// message, so callers can keep comparing against them.
+var knownErrors = []error{
+	keystore.ErrLocked,
+	keystore.ErrUnknownAccount,
+	keystore.ErrNoMatch,
+	keystore.ErrDecrypt,
+	keystore.ErrNotSupported,
+	keystore.ErrWalletClosed,
+	keystore.ErrInvalidPassphrase,
+}
+
+// remoteError maps an error returned by the server back to the keystore
+// error it stands for.
+func remoteError(err error) error {
+	if err == nil {
+		return nil
+	}
+	for _, known := range knownErrors {
+		if err.Error() == known.Error() {
+			return known
+		}
+	}
+	return err
+}
@@ -0,0 +1,341 @@
+package signer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bytejedi/tron-sdk-go/keystore"
+	"github.com/bytejedi/tron-sdk-go/policy"
+	"github.com/bytejedi/tron-sdk-go/proto/core"
+	"github.com/bytejedi/tron-sdk-go/proto/core/contract"
+	"github.com/bytejedi/tron-sdk-go/transaction"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"google.golang.org/protobuf/proto"
+)
+
+// testCA issues certificates for the tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	dir  string
+}
+
+func newTestCA(t *testing.T, dir, name string) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	ca := &testCA{cert: cert, key: key, dir: dir}
+	writePEM(t, ca.path(name+".crt"), "CERTIFICATE", der)
+	return ca
+}
+
+func (ca *testCA) path(name string) string {
+	return filepath.Join(ca.dir, name)
+}
+
+// issue writes a certificate and key for name, returning their paths.
+func (ca *testCA) issue(t *testing.T, name string, usage x509.ExtKeyUsage) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePEM(t, ca.path(name+".crt"), "CERTIFICATE", der)
+	writePEM(t, ca.path(name+".key"), "EC PRIVATE KEY", keyDER)
+	return ca.path(name + ".crt"), ca.path(name + ".key")
+}
+
+func writePEM(t *testing.T, path, typ string, der []byte) {
+	data := pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der})
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRemoteSigner(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tron-signer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca := newTestCA(t, dir, "ca")
+	serverCert, serverKey := ca.issue(t, "signer", x509.ExtKeyUsageServerAuth)
+	clientCert, clientKey := ca.issue(t, "client", x509.ExtKeyUsageClientAuth)
+	serverTLS, err := NewServerTLSConfig(serverCert, serverKey, ca.path("ca.crt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientTLS, err := NewClientTLSConfig(clientCert, clientKey, ca.path("ca.crt"), "signer")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ks := keystore.NewKeyStoreWithStorage(keystore.NewMemoryStorage(), keystore.LightScryptN, keystore.LightScryptP)
+	unlocked, err := ks.NewAccount("pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.Unlock(unlocked, "pw"); err != nil {
+		t.Fatal(err)
+	}
+	locked, err := ks.NewAccount("pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink := new(auditRecorder)
+	ks.SetAuditSink(sink, "server")
+
+	srv := NewServer(ks)
+	defer srv.Close()
+	if err := srv.Serve(nil, nil); err != ErrNoClientAuth {
+		t.Errorf("serving without client auth: have %v, want %v", err, ErrNoClientAuth)
+	}
+	endpoints := []string{"unix://" + filepath.Join(dir, "signer.ipc"), "https://127.0.0.1:0"}
+	for i, endpoint := range endpoints {
+		l, err := Listen(endpoint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if l.Addr().Network() == "tcp" {
+			endpoints[i] = "https://" + l.Addr().String()
+		}
+		go srv.Serve(l, serverTLS)
+	}
+
+	for _, endpoint := range endpoints {
+		c, err := Dial(endpoint, clientTLS)
+		if err != nil {
+			t.Fatalf("%s: %v", endpoint, err)
+		}
+		if _, err := c.Status(); err != nil {
+			t.Fatalf("%s: %v", endpoint, err)
+		}
+		if accs := c.Accounts(); len(accs) != 2 {
+			t.Fatalf("%s: have %d accounts, want 2", endpoint, len(accs))
+		}
+		if !c.Contains(keystore.Account{Address: locked.Address}) {
+			t.Errorf("%s: account not found", endpoint)
+		}
+
+		data := []byte("remote signing")
+		typedData := &keystore.TypedData{
+			Types:       keystore.TypedDataTypes{"Login": {{Name: "nonce", Type: "uint256"}, {Name: "account", Type: "address"}}},
+			PrimaryType: "Login",
+			Domain:      keystore.TypedDataDomain{Name: "signer test", ChainID: big.NewInt(keystore.ChainIDNile)},
+			Message:     map[string]interface{}{"nonce": "42", "account": unlocked.Address.String()},
+		}
+		sig, err := c.SignData(unlocked, "text/plain", data)
+		if err != nil {
+			t.Fatalf("%s: %v", endpoint, err)
+		}
+		checkSigner(t, crypto.Keccak256(data), sig, unlocked.Address)
+
+		sig, err = c.SignText(unlocked, data)
+		if err != nil {
+			t.Fatalf("%s: %v", endpoint, err)
+		}
+		checkSigner(t, keystore.TextHash(data), sig, unlocked.Address)
+
+		sig, err = c.SignMessageV2(unlocked, data)
+		if err != nil {
+			t.Fatalf("%s: %v", endpoint, err)
+		}
+		if addr, err := keystore.VerifyMessageV2(data, sig); err != nil || addr != unlocked.Address.String() {
+			t.Errorf("%s: message signed by %s, %v, want %s", endpoint, addr, err, unlocked.Address)
+		}
+		sig, err = c.SignTypedData(unlocked, typedData)
+		if err != nil {
+			t.Fatalf("%s: %v", endpoint, err)
+		}
+		if addr, err := keystore.VerifyTypedData(typedData, sig); err != nil || addr != unlocked.Address.String() {
+			t.Errorf("%s: typed data signed by %s, %v, want %s", endpoint, addr, err, unlocked.Address)
+		}
+
+		if _, err := c.SignData(locked, "text/plain", data); err != keystore.ErrLocked {
+			t.Errorf("%s: signing with locked account: have %v, want %v", endpoint, err, keystore.ErrLocked)
+		}
+		hash := crypto.Keccak256([]byte("tx"))
+		sig, err = c.SignTxWithPassphrase(locked, "pw", "", hex.EncodeToString(hash))
+		if err != nil {
+			t.Fatalf("%s: %v", endpoint, err)
+		}
+		checkSigner(t, hash, sig, locked.Address)
+		if _, err := c.SignTxWithPassphrase(locked, "wrong", "", hex.EncodeToString(hash)); err != keystore.ErrDecrypt {
+			t.Errorf("%s: wrong passphrase: have %v, want %v", endpoint, err, keystore.ErrDecrypt)
+		}
+	}
+
+	// Signatures are audited as made by the client of the request
+	sink.mu.Lock()
+	for i, rec := range sink.records {
+		if rec.Caller != "client" {
+			t.Errorf("audit record %d: caller %q, want client", i, rec.Caller)
+		}
+	}
+	if len(sink.records) == 0 {
+		t.Error("no audit records")
+	}
+	sink.mu.Unlock()
+
+	// Clients with a certificate of another CA are rejected.
+	other := newTestCA(t, dir, "other")
+	otherCert, otherKey := other.issue(t, "intruder", x509.ExtKeyUsageClientAuth)
+	otherTLS, err := NewClientTLSConfig(otherCert, otherKey, ca.path("ca.crt"), "signer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := Dial(endpoints[1], otherTLS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Status(); err == nil {
+		t.Errorf("client with foreign certificate accepted")
+	}
+	if _, err := Dial(endpoints[1], nil); err != ErrNoClientAuth {
+		t.Errorf("dialing without certificate: have %v, want %v", err, ErrNoClientAuth)
+	}
+}
+
+// auditRecorder collects the audit records of a keystore.
+type auditRecorder struct {
+	records []keystore.AuditRecord
+	mu      sync.Mutex
+}
+
+func (r *auditRecorder) Record(rec *keystore.AuditRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records = append(r.records, *rec)
+	return nil
+}
+
+func checkSigner(t *testing.T, hash, sig []byte, want keystore.Address) {
+	t.Helper()
+	pub, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have := keystore.PubkeyToAddress(*pub); have != want {
+		t.Errorf("signed by %s, want %s", have, want)
+	}
+}
+
+func TestServerPolicy(t *testing.T) {
+	ks := keystore.NewKeyStoreWithStorage(keystore.NewMemoryStorage(), keystore.LightScryptN, keystore.LightScryptP)
+	acc, err := ks.NewAccount("pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := policy.Parse([]byte("contracts: [TransferContract]\nlimits: [{asset: TRX, daily: 100}]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine, err := policy.NewEngine(p, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := &signerAPI{ks, engine}
+	ctx := context.Background()
+
+	rawData := func(amount int64) string {
+		tx, err := transaction.New(core.Transaction_Contract_TransferContract, &contract.TransferContract{
+			OwnerAddress: acc.Address.Bytes(),
+			ToAddress:    acc.Address.Bytes(),
+			Amount:       amount,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := proto.Marshal(tx.GetRawData())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hex.EncodeToString(data)
+	}
+	if _, err := api.SignTxWithPassphrase(ctx, acc.Address, "pw", "", hex.EncodeToString(make([]byte, 32))); err != errHashOnly {
+		t.Errorf("hash only signing: have %v, want %v", err, errHashOnly)
+	}
+	// A failed signature doesn't use up the limit
+	if _, err := api.SignTxWithPassphrase(ctx, acc.Address, "wrong", rawData(100), ""); err == nil {
+		t.Fatal("signed with wrong passphrase")
+	}
+	if _, err := api.SignTxWithPassphrase(ctx, acc.Address, "pw", rawData(100), ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := api.SignTxWithPassphrase(ctx, acc.Address, "pw", rawData(1), ""); !errors.Is(err, policy.ErrLimitExceeded) {
+		t.Errorf("over limit: have %v, want %v", err, policy.ErrLimitExceeded)
+	}
+
+	// Data and messages bypassing the policy are refused
+	msg := hexutil.Bytes("hello")
+	if _, err := api.SignData(ctx, acc.Address, "text/plain", msg); err != errPolicyData {
+		t.Errorf("SignData: have %v, want %v", err, errPolicyData)
+	}
+	if _, err := api.SignText(ctx, acc.Address, msg); err != errPolicyData {
+		t.Errorf("SignText: have %v, want %v", err, errPolicyData)
+	}
+	if _, err := api.SignMessageV1(ctx, acc.Address, msg); err != errPolicyData {
+		t.Errorf("SignMessageV1: have %v, want %v", err, errPolicyData)
+	}
+	if _, err := api.SignMessageV2(ctx, acc.Address, msg); err != errPolicyData {
+		t.Errorf("SignMessageV2: have %v, want %v", err, errPolicyData)
+	}
+	permit := keystore.TypedData{
+		Types:       keystore.TypedDataTypes{"Permit": {{Name: "spender", Type: "address"}, {Name: "value", Type: "uint256"}}},
+		PrimaryType: "Permit",
+		Domain:      keystore.TypedDataDomain{Name: "token", ChainID: big.NewInt(keystore.ChainIDNile)},
+		Message:     map[string]interface{}{"spender": acc.Address.String(), "value": "1000"},
+	}
+	if _, err := api.SignTypedData(ctx, acc.Address, permit); err != errPolicyData {
+		t.Errorf("SignTypedData: have %v, want %v", err, errPolicyData)
+	}
+}
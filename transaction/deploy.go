@@ -0,0 +1,191 @@
+package transaction
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bytejedi/tron-sdk-go/abi"
+	"github.com/bytejedi/tron-sdk-go/keystore"
+	"github.com/bytejedi/tron-sdk-go/proto/core"
+	"github.com/bytejedi/tron-sdk-go/proto/core/contract"
+	ethabi "github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	// DefaultConsumeUserResourcePercent makes callers pay for all the energy
+	// their calls consume.
+	DefaultConsumeUserResourcePercent = 100
+
+	// DefaultOriginEnergyLimit is the energy the deployer provides per call at
+	// most, the TronWeb default.
+	DefaultOriginEnergyLimit = 10000000
+)
+
+// DeployBuilder builds a CreateSmartContract transaction.
+type DeployBuilder struct {
+	owner                      keystore.Address
+	bytecode                   []byte
+	abi                        ethabi.ABI
+	name                       string
+	args                       string
+	consumeUserResourcePercent int64
+	originEnergyLimit          int64
+	callValue                  int64
+	tokenID                    int64
+	tokenValue                 int64
+	feeLimit                   int64
+	expiration                 time.Duration
+}
+
+// NewDeployBuilder creates a builder deploying bytecode with the given ABI
+// from owner.
+func NewDeployBuilder(owner keystore.Address, bytecode []byte, contractABI ethabi.ABI) *DeployBuilder {
+	return &DeployBuilder{
+		owner:                      owner,
+		bytecode:                   bytecode,
+		abi:                        contractABI,
+		consumeUserResourcePercent: DefaultConsumeUserResourcePercent,
+		originEnergyLimit:          DefaultOriginEnergyLimit,
+		expiration:                 DefaultExpiration,
+	}
+}
+
+// Name sets the contract name.
+func (b *DeployBuilder) Name(name string) *DeployBuilder {
+	b.name = name
+	return b
+}
+
+// ConstructorArgs sets the constructor arguments as params JSON in any of
+// the forms abi.Pack accepts.
+func (b *DeployBuilder) ConstructorArgs(paramsJSON string) *DeployBuilder {
+	b.args = paramsJSON
+	return b
+}
+
+// ConsumeUserResourcePercent sets the share of the energy, 0 to 100, that
+// callers pay for themselves.
+func (b *DeployBuilder) ConsumeUserResourcePercent(percent int64) *DeployBuilder {
+	b.consumeUserResourcePercent = percent
+	return b
+}
+
+// OriginEnergyLimit sets the energy the deployer provides per call at most.
+func (b *DeployBuilder) OriginEnergyLimit(limit int64) *DeployBuilder {
+	b.originEnergyLimit = limit
+	return b
+}
+
+// CallValue sets the amount of sun sent to the constructor.
+func (b *DeployBuilder) CallValue(sun int64) *DeployBuilder {
+	b.callValue = sun
+	return b
+}
+
+// CallTokenValue sets the TRC10 token and amount sent to the constructor.
+func (b *DeployBuilder) CallTokenValue(tokenID, value int64) *DeployBuilder {
+	b.tokenID, b.tokenValue = tokenID, value
+	return b
+}
+
+// FeeLimit sets the maximum amount of sun the deployment may burn.
+func (b *DeployBuilder) FeeLimit(sun int64) *DeployBuilder {
+	b.feeLimit = sun
+	return b
+}
+
+// Expiration sets how long the built transaction stays valid.
+func (b *DeployBuilder) Expiration(d time.Duration) *DeployBuilder {
+	b.expiration = d
+	return b
+}
+
+// Contract validates the settings and returns the CreateSmartContract
+// message, e.g. to have a node build the transaction with DeployContract.
+func (b *DeployBuilder) Contract() (*contract.CreateSmartContract, error) {
+	if !b.owner.IsValid() {
+		return nil, fmt.Errorf("invalid owner address %x", b.owner.Bytes())
+	}
+	if len(b.bytecode) == 0 {
+		return nil, fmt.Errorf("empty bytecode")
+	}
+	if b.consumeUserResourcePercent < 0 || b.consumeUserResourcePercent > 100 {
+		return nil, fmt.Errorf("consume_user_resource_percent must be between 0 and 100, got %d", b.consumeUserResourcePercent)
+	}
+	if b.originEnergyLimit <= 0 {
+		return nil, fmt.Errorf("origin_energy_limit must be positive, got %d", b.originEnergyLimit)
+	}
+	if b.callValue < 0 || b.tokenValue < 0 || b.feeLimit < 0 {
+		return nil, fmt.Errorf("call_value, call_token_value and fee_limit must not be negative")
+	}
+
+	code := append([]byte{}, b.bytecode...)
+	if b.args != "" {
+		// The constructor has no selector, Pack only encodes the arguments
+		args, err := abi.Pack(&b.abi.Constructor, b.args)
+		if err != nil {
+			return nil, fmt.Errorf("constructor args: %v", err)
+		}
+		code = append(code, args...)
+	} else if len(b.abi.Constructor.Inputs) > 0 {
+		return nil, fmt.Errorf("constructor expects %d args", len(b.abi.Constructor.Inputs))
+	}
+
+	return &contract.CreateSmartContract{
+		OwnerAddress: b.owner.Bytes(),
+		NewContract: &contract.SmartContract{
+			OriginAddress:              b.owner.Bytes(),
+			Abi:                        abi.ABIToProto(b.abi),
+			Bytecode:                   code,
+			CallValue:                  b.callValue,
+			ConsumeUserResourcePercent: b.consumeUserResourcePercent,
+			Name:                       b.name,
+			OriginEnergyLimit:          b.originEnergyLimit,
+		},
+		CallTokenValue: b.tokenValue,
+		TokenId:        b.tokenID,
+	}, nil
+}
+
+// Build returns the unsigned deployment transaction referencing the block
+// with the given id.
+func (b *DeployBuilder) Build(refBlockID []byte) (*core.Transaction, error) {
+	c, err := b.Contract()
+	if err != nil {
+		return nil, err
+	}
+	tx, err := New(core.Transaction_Contract_CreateSmartContract, c)
+	if err != nil {
+		return nil, err
+	}
+	tx.RawData.FeeLimit = b.feeLimit
+	if err := SetReference(tx, refBlockID, b.expiration); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// ContractAddress computes the address of the contract a CreateSmartContract
+// transaction deploys, the way java-tron derives it:
+//
+//	0x41 || keccak256(txID || owner_address)[12:]
+//
+// The address depends on the transaction id, so it is final once the raw
+// data is; signing does not change it.
+func ContractAddress(tx *core.Transaction) (keystore.Address, error) {
+	contracts := tx.GetRawData().GetContract()
+	if len(contracts) != 1 || contracts[0].GetType() != core.Transaction_Contract_CreateSmartContract {
+		return keystore.Address{}, fmt.Errorf("not a CreateSmartContract transaction")
+	}
+	c := new(contract.CreateSmartContract)
+	if err := contracts[0].GetParameter().UnmarshalTo(c); err != nil {
+		return keystore.Address{}, err
+	}
+	id, err := ID(tx)
+	if err != nil {
+		return keystore.Address{}, err
+	}
+	hash := crypto.Keccak256(id, c.GetOwnerAddress())
+	return keystore.BytesToAddress(hash[12:])
+}
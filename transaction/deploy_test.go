@@ -0,0 +1,75 @@
+package transaction
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/bytejedi/tron-sdk-go/keystore"
+	"github.com/bytejedi/tron-sdk-go/proto/core"
+	"github.com/bytejedi/tron-sdk-go/proto/core/contract"
+	ethabi "github.com/ethereum/go-ethereum/accounts/abi"
+	"google.golang.org/protobuf/proto"
+)
+
+const ctorABI = `[{"inputs":[{"name":"_mgr","type":"address"},{"name":"_cap","type":"uint256"}],"stateMutability":"nonpayable","type":"constructor"}]`
+
+func TestDeployBuilder(t *testing.T) {
+	a, err := ethabi.JSON(strings.NewReader(ctorABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	owner, _ := keystore.Base58ToAddress("TCQRkmYMbb8bzrZfrtcokox8hwVmY3DCVP")
+	bytecode := []byte{0x60, 0x80, 0x60, 0x40}
+	blockID := make([]byte, 32)
+	for i := range blockID {
+		blockID[i] = byte(i)
+	}
+
+	b := NewDeployBuilder(owner, bytecode, a).
+		Name("Token").
+		FeeLimit(1000000000)
+	if _, err := b.Contract(); err == nil {
+		t.Error("expected error for missing constructor args")
+	}
+	tx, err := b.ConstructorArgs(`{"mgr":"TCQRkmYMbb8bzrZfrtcokox8hwVmY3DCVP","cap":"1000"}`).Build(blockID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw := tx.GetRawData()
+	if !bytes.Equal(raw.GetRefBlockBytes(), blockID[6:8]) || !bytes.Equal(raw.GetRefBlockHash(), blockID[8:16]) ||
+		raw.GetExpiration()-raw.GetTimestamp() != DefaultExpiration.Milliseconds() || raw.GetFeeLimit() != 1000000000 {
+		t.Errorf("unexpected raw data %v", raw)
+	}
+	c := new(contract.CreateSmartContract)
+	if err := raw.GetContract()[0].GetParameter().UnmarshalTo(c); err != nil {
+		t.Fatal(err)
+	}
+	if code := c.GetNewContract().GetBytecode(); len(code) != len(bytecode)+64 || !bytes.Equal(code[:4], bytecode) {
+		t.Errorf("constructor args not appended: %x", code)
+	}
+
+}
+
+func TestContractAddress(t *testing.T) {
+	// CreateSmartContract of TCQRkmYMbb8bzrZfrtcokox8hwVmY3DCVP, the id and
+	// address worked out by hand following java-tron's
+	// sha3omit12(txID || owner)
+	raw, _ := hex.DecodeString("0a0212342208010203040506070840e0d4bdbbc82e5a78081e12740a30747970652e676f6f676c65617069732e636f6d2f70726f746f636f6c2e437265617465536d617274436f6e747261637412400a15411ab54bfac5a64d4e34468ae87b1bf46b5994911112270a15411ab54bfac5a64d4e34468ae87b1bf46b599491112205608060405230643a05546f6b656e708080babbc82e90018094ebdc03")
+	tx := &core.Transaction{RawData: new(core.TransactionRaw)}
+	if err := proto.Unmarshal(raw, tx.RawData); err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := ID(tx); hex.EncodeToString(id) != "aa4902c637b5befb0ab3e3761345e1af508ca1073afc312e8902b512e6b33d33" {
+		t.Errorf("unexpected txID %x", id)
+	}
+	addr, err := ContractAddress(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have := addr.String(); have != "TVijeE9FvGn9iDVwgR3gMJDfhHb4zwUQEP" {
+		t.Errorf("contract address mismatch: have %s, want TVijeE9FvGn9iDVwgR3gMJDfhHb4zwUQEP", have)
+	}
+}
@@ -0,0 +1,58 @@
+package transaction
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"time"
+
+	"github.com/bytejedi/tron-sdk-go/proto/core"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// DefaultExpiration is how long after its timestamp a transaction built by
+// this package stays valid, the same default java-tron uses.
+const DefaultExpiration = 60 * time.Second
+
+// ID returns the transaction id, sha256 of the serialized raw data.
+func ID(tx *core.Transaction) ([]byte, error) {
+	rawData, err := proto.Marshal(tx.GetRawData())
+	if err != nil {
+		return nil, err
+	}
+	h256h := sha256.New()
+	h256h.Write(rawData)
+	return h256h.Sum(nil), nil
+}
+
+// New wraps a contract message of the given type into an unsigned
+// transaction. The reference block still has to be set with SetReference.
+func New(ty core.Transaction_Contract_ContractType, c proto.Message) (*core.Transaction, error) {
+	param, err := anypb.New(c)
+	if err != nil {
+		return nil, err
+	}
+	return &core.Transaction{
+		RawData: &core.TransactionRaw{
+			Contract: []*core.Transaction_Contract{{Type: ty, Parameter: param}},
+		},
+	}, nil
+}
+
+// SetReference sets the TaPoS reference block of tx from a block id and
+// stamps it with the current time and the given expiration. The block id
+// carries the block number in its first 8 bytes.
+func SetReference(tx *core.Transaction, blockID []byte, expiration time.Duration) error {
+	if len(blockID) != 32 {
+		return fmt.Errorf("invalid block id length %d", len(blockID))
+	}
+	if tx.GetRawData() == nil {
+		return fmt.Errorf("transaction has no raw data")
+	}
+	now := time.Now()
+	tx.RawData.RefBlockBytes = blockID[6:8]
+	tx.RawData.RefBlockHash = blockID[8:16]
+	tx.RawData.Timestamp = now.UnixNano() / int64(time.Millisecond)
+	tx.RawData.Expiration = now.Add(expiration).UnixNano() / int64(time.Millisecond)
+	return nil
+}